@@ -6,6 +6,7 @@ import (
 
 	"github.com/chuji555/homework-system/dao"
 	"github.com/chuji555/homework-system/router"
+	"github.com/chuji555/homework-system/service"
 	"github.com/spf13/viper"
 )
 
@@ -19,6 +20,10 @@ func init() {
 	}
 	// 初始化数据库
 	dao.InitDB()
+	// 启动自动评测worker
+	service.StartGradeWorkers()
+	// 启动查重worker
+	service.StartSimilarityWorker()
 }
 func main() {
 	// 初始化路由
@@ -0,0 +1,137 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveTestCases 整体替换作业的测试用例
+func SaveTestCases(homeworkID int64, cases []models.TestCase) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("homework_id = ?", homeworkID).Delete(&models.TestCase{}).Error; err != nil {
+			return err
+		}
+		if len(cases) == 0 {
+			return nil
+		}
+		return tx.Create(&cases).Error
+	})
+}
+
+// ListTestCases 查询作业的测试用例（按顺序）
+func ListTestCases(homeworkID int64) ([]models.TestCase, error) {
+	var list []models.TestCase
+	err := DB.Where("homework_id = ?", homeworkID).Order("position ASC").Find(&list).Error
+	return list, err
+}
+
+// CountTestCases 统计作业的测试用例数（决定提交后是否需要自动评测）
+func CountTestCases(homeworkID int64) (int64, error) {
+	var count int64
+	err := DB.Model(&models.TestCase{}).Where("homework_id = ?", homeworkID).Count(&count).Error
+	return count, err
+}
+
+// ResetGradeJob 为提交创建（或重置为排队中）评测任务，旧结果清空
+func ResetGradeJob(submissionID int64) (*models.GradeJob, error) {
+	job := &models.GradeJob{SubmissionID: submissionID, Status: models.GradeJobPending}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "submission_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"status":          models.GradeJobPending,
+				"passed_points":   0,
+				"total_points":    0,
+				"suggested_score": nil,
+				"log":             "",
+				"started_at":      nil,
+				"finished_at":     nil,
+				"updated_at":      time.Now(),
+			}),
+		}).Omit("Results").Create(job).Error; err != nil {
+			return err
+		}
+		// 冲突更新时拿不到ID，重新查一次
+		if err := tx.Where("submission_id = ?", submissionID).First(job).Error; err != nil {
+			return err
+		}
+		return tx.Where("job_id = ?", job.ID).Delete(&models.TestResult{}).Error
+	})
+	return job, err
+}
+
+// ClaimGradeJob 把排队中的任务标记为运行中（返回false说明已被别的worker领走）
+func ClaimGradeJob(jobID int64) (bool, error) {
+	now := time.Now()
+	res := DB.Model(&models.GradeJob{}).
+		Where("id = ? AND status = ?", jobID, models.GradeJobPending).
+		Updates(map[string]interface{}{"status": models.GradeJobRunning, "started_at": &now})
+	return res.RowsAffected == 1, res.Error
+}
+
+// FinishGradeJob 保存评测结果（评测期间任务被重新排队的话丢弃本次结果）
+func FinishGradeJob(job *models.GradeJob) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.GradeJob{}).
+			Where("id = ? AND status = ?", job.ID, models.GradeJobRunning).
+			Updates(map[string]interface{}{
+				"status":          job.Status,
+				"passed_points":   job.PassedPoints,
+				"total_points":    job.TotalPoints,
+				"suggested_score": job.SuggestedScore,
+				"log":             job.Log,
+				"finished_at":     job.FinishedAt,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		if err := tx.Where("job_id = ?", job.ID).Delete(&models.TestResult{}).Error; err != nil {
+			return err
+		}
+		if len(job.Results) == 0 {
+			return nil
+		}
+		for i := range job.Results {
+			job.Results[i].JobID = job.ID
+		}
+		return tx.Create(&job.Results).Error
+	})
+}
+
+// GetGradeJobBySubmission 查询提交的评测任务（关联用例结果）
+func GetGradeJobBySubmission(submissionID int64) (*models.GradeJob, error) {
+	var job models.GradeJob
+	err := DB.Preload("Results").Where("submission_id = ?", submissionID).First(&job).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &job, err
+}
+
+// GetGradeJobByID 根据ID查询评测任务
+func GetGradeJobByID(jobID int64) (*models.GradeJob, error) {
+	var job models.GradeJob
+	err := DB.Where("id = ?", jobID).First(&job).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &job, err
+}
+
+// ListUnfinishedGradeJobs 查询没跑完的任务（服务重启后重新排队）
+func ListUnfinishedGradeJobs() ([]models.GradeJob, error) {
+	var list []models.GradeJob
+	err := DB.Where("status IN ?", []models.GradeJobStatus{models.GradeJobPending, models.GradeJobRunning}).
+		Order("id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// RequeueGradeJob 把运行中的任务放回排队状态
+func RequeueGradeJob(jobID int64) error {
+	return DB.Model(&models.GradeJob{}).Where("id = ?", jobID).
+		Updates(map[string]interface{}{"status": models.GradeJobPending, "started_at": nil}).Error
+}
@@ -0,0 +1,17 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+)
+
+// 记录一次查看学生真实身份
+func CreateDeanonymizationLog(log *models.DeanonymizationLog) error {
+	return DB.Omit("Admin").Create(log).Error
+}
+
+// 查询作业的身份查看记录（新的在前）
+func ListDeanonymizationLogs(homeworkID int64) ([]models.DeanonymizationLog, error) {
+	var list []models.DeanonymizationLog
+	err := DB.Preload("Admin").Where("homework_id = ?", homeworkID).Order("id DESC").Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 根据令牌查询日历订阅
+func GetCalendarTokenByToken(token string) (*models.CalendarToken, error) {
+	var ct models.CalendarToken
+	err := DB.Where("token = ?", token).First(&ct).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &ct, err
+}
+
+// 保存用户的日历令牌（已存在则覆盖，旧令牌立即失效）
+func SaveCalendarToken(ct *models.CalendarToken) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token", "updated_at"}),
+	}).Create(ct).Error
+}
+
+// 删除用户的日历令牌（撤销订阅）
+func DeleteCalendarToken(userID int64) error {
+	return DB.Where("user_id = ?", userID).Delete(&models.CalendarToken{}).Error
+}
+
+// 保存个人延期（同一学生同一作业只保留一条）
+func SaveDeadlineExtension(ext *models.DeadlineExtension) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "homework_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"deadline", "reason", "granted_by", "updated_at"}),
+	}).Create(ext).Error
+}
+
+// 删除个人延期
+func DeleteDeadlineExtension(homeworkID, studentID int64) error {
+	return DB.Where("homework_id = ? AND student_id = ?", homeworkID, studentID).
+		Delete(&models.DeadlineExtension{}).Error
+}
+
+// 查询学生某个作业的个人延期
+func GetDeadlineExtension(homeworkID, studentID int64) (*models.DeadlineExtension, error) {
+	var ext models.DeadlineExtension
+	err := DB.Where("homework_id = ? AND student_id = ?", homeworkID, studentID).First(&ext).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &ext, err
+}
+
+// 查询作业的所有个人延期
+func ListDeadlineExtensionByHomework(homeworkID int64) ([]models.DeadlineExtension, error) {
+	var list []models.DeadlineExtension
+	err := DB.Where("homework_id = ?", homeworkID).Find(&list).Error
+	return list, err
+}
+
+// 查询学生的所有个人延期
+func ListDeadlineExtensionByStudent(studentID int64) ([]models.DeadlineExtension, error) {
+	var list []models.DeadlineExtension
+	err := DB.Where("student_id = ?", studentID).Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 创建提交下的评论
+func CreateSubmissionComment(comment *models.SubmissionComment) error {
+	return DB.Omit("Author").Create(comment).Error
+}
+
+// 根据ID查询评论
+func GetSubmissionCommentByID(commentID int64) (*models.SubmissionComment, error) {
+	var comment models.SubmissionComment
+	err := DB.First(&comment, commentID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &comment, err
+}
+
+// 查询提交下的全部评论（按时间顺序，学生查看时不含私有备注）
+func ListSubmissionComments(subID int64, includePrivate bool) ([]models.SubmissionComment, error) {
+	var list []models.SubmissionComment
+	query := DB.Preload("Author").Where("submission_id = ?", subID)
+	if !includePrivate {
+		query = query.Where("private = ?", false)
+	}
+	err := query.Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+// 查询用户在提交讨论中读到的位置（没读过返回0）
+func GetCommentReadPosition(subID, userID int64) (int64, error) {
+	var read models.SubmissionCommentRead
+	err := DB.Where("submission_id = ? AND user_id = ?", subID, userID).First(&read).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	return read.LastReadCommentID, err
+}
+
+// 标记读到某条评论（只前进不后退）
+func MarkCommentsRead(subID, userID, lastCommentID int64) error {
+	read := &models.SubmissionCommentRead{SubmissionID: subID, UserID: userID, LastReadCommentID: lastCommentID}
+	return DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "submission_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_read_comment_id": gorm.Expr("GREATEST(last_read_comment_id, ?)", lastCommentID),
+			"updated_at":           time.Now(),
+		}),
+	}).Create(read).Error
+}
+
+// 每份提交的未读评论数
+type UnreadCommentCount struct {
+	SubmissionID int64 `json:"submission_id"`
+	HomeworkID   int64 `json:"homework_id"`
+	Unread       int64 `json:"unread"`
+}
+
+// 查询用户有未读评论的提交：学生看自己（和小组）的提交，管理员看自己批改过或参与讨论的提交
+func ListUnreadCommentCounts(userID int64, isAdmin bool) ([]UnreadCommentCount, error) {
+	var followed *gorm.DB
+	if isAdmin {
+		followed = DB.Model(&models.Submission{}).Select("id").
+			Where("reviewer_id = ? OR id IN (?)", userID,
+				DB.Model(&models.SubmissionComment{}).Select("submission_id").Where("author_id = ?", userID))
+	} else {
+		followed = ownOrTeamSubmission(DB.Model(&models.Submission{}).Select("id"), userID)
+	}
+
+	query := DB.Table("submission_comments AS c").
+		Select("c.submission_id AS submission_id, s.homework_id AS homework_id, COUNT(*) AS unread").
+		Joins("JOIN submissions AS s ON s.id = c.submission_id").
+		Joins("LEFT JOIN submission_comment_reads AS r ON r.submission_id = c.submission_id AND r.user_id = ?", userID).
+		Where("c.author_id <> ? AND c.id > COALESCE(r.last_read_comment_id, 0)", userID).
+		Where("c.submission_id IN (?)", followed)
+	if !isAdmin {
+		query = query.Where("c.private = ?", false)
+	}
+	var list []UnreadCommentCount
+	err := query.Group("c.submission_id, s.homework_id").Order("c.submission_id DESC").Scan(&list).Error
+	return list, err
+}
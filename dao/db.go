@@ -1,7 +1,12 @@
 package dao
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/chuji555/homework-system/models"
 	"github.com/spf13/viper"
@@ -24,6 +29,8 @@ func InitDB() {
 	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
 		// 显示SQL日志（新手调试用）
 		Logger: logger.Default.LogMode(logger.Info),
+		// 把唯一索引冲突等数据库错误转换成gorm的通用错误（IsDuplicateKey用）
+		TranslateError: true,
 	})
 	if err != nil {
 		panic(fmt.Sprintf("数据库连接失败：%v", err))
@@ -34,14 +41,172 @@ func InitDB() {
 	sqlDB.SetMaxOpenConns(maxOpenConns)
 	sqlDB.SetMaxIdleConns(maxIdleConns)
 
+	// 建唯一索引前先清理历史上的重复提交，否则建索引失败
+	if err := dedupeSubmissions(); err != nil {
+		panic(fmt.Sprintf("清理重复提交失败：%v", err))
+	}
+
 	// 自动迁移建表
 	err = DB.AutoMigrate(
 		&models.User{},
 		&models.Homework{},
 		&models.Submission{},
+		&models.SubmissionVersion{},
+		&models.StoredFile{},
+		&models.SubmissionDraft{},
+		&models.IdempotencyRecord{},
+		&models.Rubric{},
+		&models.RubricCriterion{},
+		&models.RubricLevel{},
+		&models.SubmissionCriterionScore{},
+		&models.CalendarToken{},
+		&models.DeadlineExtension{},
+		&models.Track{},
+		&models.TrackItem{},
+		&models.Team{},
+		&models.TeamMember{},
+		&models.Question{},
+		&models.QuizQuestion{},
+		&models.QuizAnswer{},
+		&models.TestCase{},
+		&models.GradeJob{},
+		&models.TestResult{},
+		&models.HomeworkRevision{},
+		&models.HomeworkView{},
+		&models.SimilarityPair{},
+		&models.SubmissionComment{},
+		&models.SubmissionCommentRead{},
+		&models.RegradeRequest{},
+		&models.ScoreChange{},
+		&models.PeerReviewRound{},
+		&models.PeerReviewAssignment{},
+		&models.DeanonymizationLog{},
+		&models.ReviewClaim{},
+		&models.Mentorship{},
+		&models.GradeImport{},
+		&models.ShowcaseConsent{},
+		&models.RepositorySnapshot{},
+		&models.Reminder{},
 	)
 	if err != nil {
 		panic(fmt.Sprintf("建表失败：%v", err))
 	}
 	fmt.Println("数据库初始化成功！")
 }
+
+// 引用提交的表和列
+type submissionRef struct{ Table, Column string }
+
+// 旧的重复提交上有这些数据时不能直接删
+var submissionChildren = []submissionRef{
+	{"submission_versions", "submission_id"},
+	{"stored_files", "submission_id"},
+	{"submission_criterion_scores", "submission_id"},
+	{"quiz_answers", "submission_id"},
+	{"submission_comments", "submission_id"},
+	{"regrade_requests", "submission_id"},
+	{"score_changes", "submission_id"},
+	{"grade_jobs", "submission_id"},
+	{"peer_review_assignments", "submission_id"},
+	{"similarity_pairs", "submission_a_id"},
+	{"similarity_pairs", "submission_b_id"},
+	{"showcase_consents", "submission_id"},
+}
+
+// dedupeSubmissions 同一学生同一作业有多条提交时只保留最新的一条，其余删除并记日志（只在还没建唯一索引时执行）；
+// 要删的提交里有已批改的或者带着文件、评论、分数记录等数据时一条都不删，返回清单由人工处理后再启动
+func dedupeSubmissions() error {
+	if !DB.Migrator().HasTable("submissions") || DB.Migrator().HasIndex(&models.Submission{}, "idx_submission_homework_student") {
+		return nil
+	}
+
+	// 1. 找出重复的学生和作业
+	var dups []struct {
+		HomeworkID int64
+		StudentID  int64
+	}
+	if err := DB.Table("submissions").Select("homework_id, student_id").
+		Group("homework_id, student_id").Having("COUNT(*) > 1").Scan(&dups).Error; err != nil {
+		return err
+	}
+	if len(dups) == 0 {
+		return nil
+	}
+	children := make([]submissionRef, 0, len(submissionChildren))
+	for _, c := range submissionChildren {
+		if DB.Migrator().HasTable(c.Table) && DB.Migrator().HasColumn(c.Table, c.Column) {
+			children = append(children, c)
+		}
+	}
+
+	// 2. 逐组保留最新提交的（同时间取ID大的），旧表结构里只有基础字段，按列名查；其余的先检查能不能删
+	type duplicate struct {
+		ID, HomeworkID, StudentID, KeepID int64
+		SubmittedAt                       time.Time
+	}
+	var deletable []duplicate
+	var conflicts []string
+	for _, d := range dups {
+		var rows []struct {
+			ID          int64
+			Score       *int
+			SubmittedAt time.Time
+		}
+		if err := DB.Table("submissions").Select("id, score, submitted_at").
+			Where("homework_id = ? AND student_id = ?", d.HomeworkID, d.StudentID).
+			Order("submitted_at DESC, id DESC").Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, r := range rows[1:] {
+			dup := duplicate{ID: r.ID, HomeworkID: d.HomeworkID, StudentID: d.StudentID, KeepID: rows[0].ID, SubmittedAt: r.SubmittedAt}
+			var related []string
+			if r.Score != nil {
+				related = append(related, "分数="+strconv.Itoa(*r.Score))
+			}
+			for _, c := range children {
+				var count int64
+				if err := DB.Table(c.Table).Where(c.Column+" = ?", r.ID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					related = append(related, fmt.Sprintf("%s %d条", c.Table, count))
+				}
+			}
+			if len(related) == 0 {
+				deletable = append(deletable, dup)
+				continue
+			}
+			conflicts = append(conflicts, fmt.Sprintf("ID=%d 作业=%d 学生=%d 提交时间=%s（%s；最新的是ID=%d）",
+				r.ID, d.HomeworkID, d.StudentID, r.SubmittedAt.Format(time.DateTime), strings.Join(related, "，"), rows[0].ID))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("以下%d条重复提交已批改或有关联数据，不能自动删除，请人工合并或删除后再启动：\n%s",
+			len(conflicts), strings.Join(conflicts, "\n"))
+	}
+
+	// 3. 都是没有批改、没有关联数据的旧提交，一起删掉
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, dup := range deletable {
+			log.Printf("删除重复提交：ID=%d 作业=%d 学生=%d 提交时间=%s（保留ID=%d）",
+				dup.ID, dup.HomeworkID, dup.StudentID, dup.SubmittedAt.Format(time.DateTime), dup.KeepID)
+			if err := tx.Exec("DELETE FROM submissions WHERE id = ?", dup.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// IsDuplicateKey 是否为唯一索引冲突（并发重复插入时由数据库兜底）
+func IsDuplicateKey(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// ErrVersionConflict 按版本号做条件更新时没有命中：读取之后数据已被别人修改
+var ErrVersionConflict = errors.New("数据已被修改")
+
+// nextVersion 更新时把版本号加1（旧ETag随之失效）
+func nextVersion() interface{} {
+	return gorm.Expr("version + 1")
+}
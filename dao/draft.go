@@ -0,0 +1,30 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveDraft 保存草稿（存在则覆盖）
+func SaveDraft(draft *models.SubmissionDraft) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "homework_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "file_url", "file_ids", "updated_at"}),
+	}).Create(draft).Error
+}
+
+// GetDraft 查询学生某个作业的草稿
+func GetDraft(homeworkID, studentID int64) (*models.SubmissionDraft, error) {
+	var draft models.SubmissionDraft
+	err := DB.Where("homework_id = ? AND student_id = ?", homeworkID, studentID).First(&draft).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &draft, err
+}
+
+// DeleteDraft 删除草稿
+func DeleteDraft(homeworkID, studentID int64) error {
+	return DB.Where("homework_id = ? AND student_id = ?", homeworkID, studentID).Delete(&models.SubmissionDraft{}).Error
+}
@@ -0,0 +1,31 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// CreateStoredFile 保存上传文件的信息
+func CreateStoredFile(file *models.StoredFile) error {
+	return DB.Create(file).Error
+}
+
+// GetStoredFileByID 根据ID查询上传文件
+func GetStoredFileByID(fileID int64) (*models.StoredFile, error) {
+	var file models.StoredFile
+	err := DB.First(&file, fileID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &file, err
+}
+
+// ListStoredFileByIDs 批量查询上传文件
+func ListStoredFileByIDs(ids []int64) ([]models.StoredFile, error) {
+	var list []models.StoredFile
+	if len(ids) == 0 {
+		return list, nil
+	}
+	err := DB.Where("id IN ?", ids).Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// ListSubmissionsForImport 查询作业的全部提交（关联学生和小组组员，按提交ID或用户名匹配导入的行用）
+func ListSubmissionsForImport(homeworkID int64) ([]models.Submission, error) {
+	var list []models.Submission
+	err := DB.Preload("Student").
+		Preload("Team.Members.Student").
+		Where("homework_id = ?", homeworkID).
+		Order("id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ImportGrades 批量导入成绩：先写导入记录，再逐条保存批改结果、分数变动和优秀标记（record.Rows和submissions一一对应）；
+// 同一事务，任何一条失败（包括读取之后被别人改过）全部回滚
+func ImportGrades(record *models.GradeImport, submissions []*models.Submission, changes []*models.ScoreChange) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Importer").Create(record).Error; err != nil {
+			return err
+		}
+		for i, sub := range submissions {
+			if changes[i] != nil {
+				changes[i].GradeImportID = &record.ID
+			}
+			if err := saveReview(tx, sub, changes[i]); err != nil {
+				return err
+			}
+			excellent := record.Rows[i].Excellent
+			if excellent == nil || *excellent == sub.IsExcellent {
+				continue
+			}
+			// 取消优秀时推荐理由一起清空
+			updates := map[string]interface{}{"is_excellent": *excellent}
+			if !*excellent {
+				updates["excellent_reason"] = ""
+			}
+			if err := tx.Model(&models.Submission{}).Where("id = ?", sub.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, sub := range submissions {
+		sub.Version++
+		if excellent := record.Rows[i].Excellent; excellent != nil {
+			sub.IsExcellent = *excellent
+			if !*excellent {
+				sub.ExcellentReason = ""
+			}
+		}
+	}
+	return nil
+}
+
+// ListGradeImports 查询作业的导入记录（新的在前，关联导入人）
+func ListGradeImports(homeworkID int64) ([]models.GradeImport, error) {
+	var list []models.GradeImport
+	err := DB.Preload("Importer").Where("homework_id = ?", homeworkID).Order("id DESC").Find(&list).Error
+	return list, err
+}
@@ -1,18 +1,89 @@
 package dao
 
 import (
+	"time"
+
 	"github.com/chuji555/homework-system/models"
 	"gorm.io/gorm"
 )
 
-// CreateHomework 创建作业
+// CreateHomework 创建作业并记录版本1
 func CreateHomework(homework *models.Homework) error {
-	return DB.Create(homework).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(homework).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.HomeworkRevision{
+			HomeworkID: homework.ID,
+			Version:    1,
+			EditorID:   homework.CreatorID,
+			Snapshot:   homework.Snapshot(),
+		}).Error
+	})
 }
 
-// UpdateHomework 修改作业
-func UpdateHomework(homework *models.Homework) error {
-	return DB.Save(homework).Error
+// UpdateHomework 修改作业（只写改动的字段）并追加一个版本；
+// 按读取时的版本号做条件更新，期间被别人改过时返回ErrVersionConflict；
+// 旧数据没有任何版本时先用修改前的内容补一个版本1
+func UpdateHomework(homework *models.Homework, before models.HomeworkSnapshot, revision *models.HomeworkRevision) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Homework{}).
+			Where("id = ? AND version = ?", homework.ID, homework.Version).
+			Updates(homeworkColumns(homework, revision.Changes))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		var latest int
+		if err := tx.Model(&models.HomeworkRevision{}).
+			Where("homework_id = ?", homework.ID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		if latest == 0 {
+			if err := tx.Create(&models.HomeworkRevision{
+				HomeworkID: homework.ID,
+				Version:    1,
+				EditorID:   homework.CreatorID,
+				Snapshot:   before,
+				CreatedAt:  homework.CreatedAt,
+			}).Error; err != nil {
+				return err
+			}
+			latest = 1
+		}
+		revision.HomeworkID = homework.ID
+		revision.Version = latest + 1
+		return tx.Create(revision).Error
+	})
+	if err == nil {
+		homework.Version++
+	}
+	return err
+}
+
+// homeworkColumns 改动的字段对应的列和新值（FieldChange的字段名和列名一致），版本号加1
+func homeworkColumns(homework *models.Homework, changes models.FieldChanges) map[string]interface{} {
+	values := map[string]interface{}{
+		"title":         homework.Title,
+		"description":   homework.Description,
+		"department":    homework.Department,
+		"deadline":      homework.Deadline,
+		"allow_late":    homework.AllowLate,
+		"max_attempts":  homework.MaxAttempts,
+		"grading_scale": homework.GradingScale,
+	}
+	updates := map[string]interface{}{"version": nextVersion()}
+	for _, change := range changes {
+		if value, ok := values[change.Field]; ok {
+			updates[change.Field] = value
+		}
+	}
+	return updates
 }
 
 // DeleteHomework 软删除作业
@@ -58,3 +129,91 @@ func GetHomeworkByID(homeworkID int64) (*models.Homework, error) {
 	}
 	return &homework, err
 }
+
+// ListHomeworkByDepartment 查询部门的全部作业（不分页，日历订阅用）
+func ListHomeworkByDepartment(department string) ([]models.Homework, error) {
+	var list []models.Homework
+	err := DB.Where("department = ?", department).
+		Order("deadline ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ListHomeworkBefore 查询部门里截止时间早于before的作业（算学生以往的提交率用）
+func ListHomeworkBefore(department string, before time.Time) ([]models.Homework, error) {
+	var list []models.Homework
+	err := DB.Where("department = ? AND deadline < ?", department, before).
+		Order("deadline ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ListHomeworkByIDs 根据ID批量查询作业
+func ListHomeworkByIDs(ids []int64) ([]models.Homework, error) {
+	var list []models.Homework
+	if len(ids) == 0 {
+		return list, nil
+	}
+	err := DB.Where("id IN ?", ids).Find(&list).Error
+	return list, err
+}
+
+// UpdateHomeworkPrerequisite 设置作业的前置作业（prerequisiteID为nil表示取消），按读取时的版本号做条件更新
+func UpdateHomeworkPrerequisite(homeworkID int64, version int, prerequisiteID *int64, minPercent float64) error {
+	return updateHomeworkVersioned(DB, homeworkID, version, map[string]interface{}{
+		"prerequisite_id":          prerequisiteID,
+		"prerequisite_min_percent": minPercent,
+	})
+}
+
+// UpdateHomeworkGroupSettings 修改作业的小组设置，按读取时的版本号做条件更新
+func UpdateHomeworkGroupSettings(homeworkID int64, version int, isGroup bool, minTeamSize, maxTeamSize int, allowStudentTeams bool) error {
+	return updateHomeworkVersioned(DB, homeworkID, version, map[string]interface{}{
+		"is_group":            isGroup,
+		"min_team_size":       minTeamSize,
+		"max_team_size":       maxTeamSize,
+		"allow_student_teams": allowStudentTeams,
+	})
+}
+
+// UpdateHomeworkBlindGrading 开启/关闭作业的匿名批改，按读取时的版本号做条件更新
+func UpdateHomeworkBlindGrading(homeworkID int64, version int, enabled bool) error {
+	return updateHomeworkVersioned(DB, homeworkID, version, map[string]interface{}{
+		"blind_grading": enabled,
+	})
+}
+
+// updateHomeworkVersioned 版本号一致时修改作业的设置并把版本号加1，期间被别人改过时返回ErrVersionConflict
+func updateHomeworkVersioned(tx *gorm.DB, homeworkID int64, version int, updates map[string]interface{}) error {
+	updates["version"] = nextVersion()
+	res := tx.Model(&models.Homework{}).Where("id = ? AND version = ?", homeworkID, version).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// ReleaseHomeworkGrades 发布作业成绩或预定发布时间（已经发布的不能再改，预定的还没到时间可以改）
+func ReleaseHomeworkGrades(homeworkID int64, releaseAt, now time.Time) (bool, error) {
+	res := DB.Model(&models.Homework{}).
+		Where("id = ? AND (grades_released_at IS NULL OR grades_released_at > ?)", homeworkID, now).
+		Updates(map[string]interface{}{"grades_released_at": &releaseAt, "version": nextVersion()})
+	return res.RowsAffected == 1, res.Error
+}
+
+// CancelHomeworkGradeRelease 取消还没到时间的预定发布
+func CancelHomeworkGradeRelease(homeworkID int64, now time.Time) (bool, error) {
+	res := DB.Model(&models.Homework{}).
+		Where("id = ? AND grades_released_at > ?", homeworkID, now).
+		Updates(map[string]interface{}{"grades_released_at": nil, "version": nextVersion()})
+	return res.RowsAffected == 1, res.Error
+}
+
+// gradesVisible 只保留学生能看到成绩的作业下的提交（和Homework.GradesVisible一致，查询要先关联homeworks表）
+func gradesVisible(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("homeworks.type = ? OR homeworks.require_grade_release = ? OR homeworks.grades_released_at <= ?",
+		models.HomeworkQuiz, false, now)
+}
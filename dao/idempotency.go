@@ -0,0 +1,36 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// CreateIdempotencyRecord 占用幂等键（唯一索引冲突说明已经有同一个key的请求）
+func CreateIdempotencyRecord(record *models.IdempotencyRecord) error {
+	return DB.Create(record).Error
+}
+
+// GetIdempotencyRecord 查询用户的幂等键记录
+func GetIdempotencyRecord(userID int64, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	err := DB.Where("user_id = ? AND `key` = ?", userID, key).First(&record).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &record, err
+}
+
+// FinishIdempotencyRecord 保存第一次请求的响应
+func FinishIdempotencyRecord(recordID int64, status int, contentType, body string) error {
+	return DB.Model(&models.IdempotencyRecord{}).Where("id = ?", recordID).Updates(map[string]interface{}{
+		"done":          true,
+		"status":        status,
+		"content_type":  contentType,
+		"response_body": body,
+	}).Error
+}
+
+// DeleteIdempotencyRecord 删除记录（请求失败或记录过期，让同一个key可以重试）
+func DeleteIdempotencyRecord(recordID int64) error {
+	return DB.Delete(&models.IdempotencyRecord{}, recordID).Error
+}
@@ -0,0 +1,91 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// CreatePeerReviewRound 创建互评轮次和全部分配（同一事务）
+func CreatePeerReviewRound(round *models.PeerReviewRound, assignments []models.PeerReviewAssignment) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(round).Error; err != nil {
+			return err
+		}
+		for i := range assignments {
+			assignments[i].RoundID = round.ID
+		}
+		return tx.Omit("Round", "Submission", "Reviewer").Create(&assignments).Error
+	})
+}
+
+// GetPeerReviewRoundByHomework 查询作业的互评轮次
+func GetPeerReviewRoundByHomework(homeworkID int64) (*models.PeerReviewRound, error) {
+	var round models.PeerReviewRound
+	err := DB.Where("homework_id = ?", homeworkID).First(&round).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &round, err
+}
+
+// ClosePeerReviewRound 提前结束互评
+func ClosePeerReviewRound(roundID int64, closedAt time.Time) error {
+	return DB.Model(&models.PeerReviewRound{}).
+		Where("id = ? AND closed_at IS NULL", roundID).
+		Update("closed_at", &closedAt).Error
+}
+
+// GetPeerAssignmentByID 根据ID查询互评分配（关联轮次和提交）
+func GetPeerAssignmentByID(assignmentID int64) (*models.PeerReviewAssignment, error) {
+	var assignment models.PeerReviewAssignment
+	err := DB.Preload("Round").Preload("Submission").First(&assignment, assignmentID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &assignment, err
+}
+
+// ListPeerAssignmentsByReviewer 查询学生要评的所有提交（新的轮次在前）
+func ListPeerAssignmentsByReviewer(reviewerID int64) ([]models.PeerReviewAssignment, error) {
+	var list []models.PeerReviewAssignment
+	err := DB.Preload("Round").Preload("Submission.Homework").Preload("Submission.Files").
+		Where("reviewer_id = ?", reviewerID).
+		Order("round_id DESC, id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ListPeerAssignmentsByRound 查询轮次的全部分配（关联评阅人）
+func ListPeerAssignmentsByRound(roundID int64) ([]models.PeerReviewAssignment, error) {
+	var list []models.PeerReviewAssignment
+	err := DB.Preload("Reviewer").Where("round_id = ?", roundID).Order("submission_id ASC, id ASC").Find(&list).Error
+	return list, err
+}
+
+// ListPeerAssignmentsBySubmission 查询一份提交收到的互评（关联评阅人）
+func ListPeerAssignmentsBySubmission(subID int64) ([]models.PeerReviewAssignment, error) {
+	var list []models.PeerReviewAssignment
+	err := DB.Preload("Reviewer").Where("submission_id = ?", subID).Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+// SavePeerReview 保存互评结果（截止前可以修改）
+func SavePeerReview(assignmentID int64, score int, criterionScores models.PeerCriterionScores, comment string, submittedAt time.Time) error {
+	return DB.Model(&models.PeerReviewAssignment{}).Where("id = ?", assignmentID).Updates(map[string]interface{}{
+		"score":            score,
+		"criterion_scores": criterionScores,
+		"comment":          comment,
+		"submitted_at":     &submittedAt,
+	}).Error
+}
+
+// IsPeerReviewer 学生是否被分配评这份提交（互评时可以查看提交的附件）
+func IsPeerReviewer(subID, reviewerID int64) (bool, error) {
+	var count int64
+	err := DB.Model(&models.PeerReviewAssignment{}).
+		Where("submission_id = ? AND reviewer_id = ?", subID, reviewerID).
+		Count(&count).Error
+	return count > 0, err
+}
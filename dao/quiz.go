@@ -0,0 +1,148 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// 单道题的作答统计
+type QuestionStat struct {
+	QuestionID int64 `json:"question_id"`
+	Attempts   int64 `json:"attempts"`
+	Correct    int64 `json:"correct"`
+}
+
+// CreateQuestion 新增题目
+func CreateQuestion(question *models.Question) error {
+	return DB.Create(question).Error
+}
+
+// UpdateQuestion 修改题目
+func UpdateQuestion(question *models.Question) error {
+	return DB.Save(question).Error
+}
+
+// DeleteQuestion 删除题目
+func DeleteQuestion(questionID int64) error {
+	return DB.Delete(&models.Question{}, questionID).Error
+}
+
+// GetQuestionByID 根据ID查询题目
+func GetQuestionByID(questionID int64) (*models.Question, error) {
+	var question models.Question
+	err := DB.Where("id = ?", questionID).First(&question).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &question, err
+}
+
+// ListQuestionByIDs 根据ID批量查询题目
+func ListQuestionByIDs(ids []int64) ([]models.Question, error) {
+	var list []models.Question
+	if len(ids) == 0 {
+		return list, nil
+	}
+	err := DB.Where("id IN ?", ids).Find(&list).Error
+	return list, err
+}
+
+// ListQuestion 分页查询题库（支持部门、题型筛选）
+func ListQuestion(department, questionType string, page, pageSize int) ([]models.Question, int64, error) {
+	var list []models.Question
+	var total int64
+
+	query := DB.Model(&models.Question{})
+	if department != "" {
+		query = query.Where("department = ?", department)
+	}
+	if questionType != "" {
+		query = query.Where("type = ?", questionType)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&list).Error
+
+	return list, total, err
+}
+
+// CountQuizUsage 统计题目被多少个测验引用（被引用的题目不能删除）
+func CountQuizUsage(questionID int64) (int64, error) {
+	var count int64
+	err := DB.Model(&models.QuizQuestion{}).Where("question_id = ?", questionID).Count(&count).Error
+	return count, err
+}
+
+// SaveQuiz 设置测验题目（整体替换），同时把作业改为测验类型；按读取时的作业版本号做条件更新
+func SaveQuiz(homeworkID int64, version int, items []models.QuizQuestion, shuffle bool) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("homework_id = ?", homeworkID).Delete(&models.QuizQuestion{}).Error; err != nil {
+			return err
+		}
+		if len(items) > 0 {
+			if err := tx.Omit("Question").Create(&items).Error; err != nil {
+				return err
+			}
+		}
+		return updateHomeworkVersioned(tx, homeworkID, version, map[string]interface{}{
+			"type":              models.HomeworkQuiz,
+			"shuffle_questions": shuffle,
+		})
+	})
+}
+
+// ListQuizQuestions 查询测验的题目（关联题目内容，按顺序）
+func ListQuizQuestions(homeworkID int64) ([]models.QuizQuestion, error) {
+	var list []models.QuizQuestion
+	err := DB.Preload("Question").
+		Where("homework_id = ?", homeworkID).
+		Order("position ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// CreateQuizSubmission 保存测验提交、逐题判分结果和自动判分记录（同一事务）
+func CreateQuizSubmission(submission *models.Submission, answers []models.QuizAnswer) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("QuizAnswers").Create(submission).Error; err != nil {
+			return err
+		}
+		if submission.Score != nil {
+			change := &models.ScoreChange{
+				SubmissionID: submission.ID,
+				NewScore:     *submission.Score,
+				Source:       models.ScoreSourceQuiz,
+				Reason:       submission.Comment,
+			}
+			if err := tx.Omit("Changer").Create(change).Error; err != nil {
+				return err
+			}
+		}
+		if len(answers) == 0 {
+			return nil
+		}
+		for i := range answers {
+			answers[i].SubmissionID = submission.ID
+		}
+		return tx.Create(&answers).Error
+	})
+}
+
+// ListQuestionStats 按题目统计测验的作答人数和答对人数
+func ListQuestionStats(homeworkID int64) ([]QuestionStat, error) {
+	var list []QuestionStat
+	err := DB.Model(&models.QuizAnswer{}).
+		Select("quiz_answers.question_id AS question_id, COUNT(*) AS attempts, SUM(CASE WHEN quiz_answers.is_correct THEN 1 ELSE 0 END) AS correct").
+		Joins("JOIN submissions ON submissions.id = quiz_answers.submission_id").
+		Where("submissions.homework_id = ?", homeworkID).
+		Group("quiz_answers.question_id").
+		Scan(&list).Error
+	return list, err
+}
@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// 创建复核申请
+func CreateRegradeRequest(request *models.RegradeRequest) error {
+	return DB.Omit("Student", "Resolver", "Submission").Create(request).Error
+}
+
+// 根据ID查询复核申请（关联申请人、处理人和提交）
+func GetRegradeRequestByID(requestID int64) (*models.RegradeRequest, error) {
+	var request models.RegradeRequest
+	err := DB.Preload("Student").Preload("Resolver").Preload("Submission").First(&request, requestID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &request, err
+}
+
+// 查询提交的所有复核申请（按时间顺序）
+func ListRegradeRequestsBySubmission(subID int64) ([]models.RegradeRequest, error) {
+	var list []models.RegradeRequest
+	err := DB.Preload("Student").Preload("Resolver").
+		Where("submission_id = ?", subID).
+		Order("id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// HasPendingRegrade 提交是否有待处理的复核申请（成绩发布后只有这时才能重新批改）
+func HasPendingRegrade(subID int64) (bool, error) {
+	var count int64
+	err := DB.Model(&models.RegradeRequest{}).
+		Where("submission_id = ? AND status = ?", subID, models.RegradePending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// 分页查询部门的复核申请队列（先申请的排前面）
+func ListRegradeQueue(department, status string, page, pageSize int) ([]models.RegradeRequest, int64, error) {
+	var list []models.RegradeRequest
+	var total int64
+
+	query := DB.Model(&models.RegradeRequest{}).Where("department = ?", department)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Student").
+		Preload("Resolver").
+		Preload("Submission.Homework").
+		Order("id ASC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&list).Error
+	return list, total, err
+}
+
+// ResolveRegradeRequest 处理复核申请：改分时同一事务内更新提交、分项得分并记录分数变动；
+// 按待处理状态做条件更新，返回false表示已被别人处理
+func ResolveRegradeRequest(request *models.RegradeRequest, submission *models.Submission, scores []models.SubmissionCriterionScore, change *models.ScoreChange) (bool, error) {
+	resolved := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.RegradeRequest{}).
+			Where("id = ? AND status = ?", request.ID, models.RegradePending).
+			Updates(map[string]interface{}{
+				"status":      request.Status,
+				"new_score":   request.NewScore,
+				"resolver_id": request.ResolverID,
+				"explanation": request.Explanation,
+				"resolved_at": request.ResolvedAt,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		if submission != nil {
+			if scores != nil {
+				if err := saveCriterionScores(tx, submission.ID, scores); err != nil {
+					return err
+				}
+			}
+			if err := saveReview(tx, submission, change); err != nil {
+				return err
+			}
+		}
+		resolved = true
+		return nil
+	})
+	return resolved, err
+}
+
+// 查询提交的分数变动记录（按时间顺序）
+func ListScoreChanges(subID int64) ([]models.ScoreChange, error) {
+	var list []models.ScoreChange
+	err := DB.Preload("Changer").Where("submission_id = ?", subID).Order("id ASC").Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+)
+
+// CreateReminders 批量保存提醒
+func CreateReminders(reminders []models.Reminder) error {
+	if len(reminders) == 0 {
+		return nil
+	}
+	return DB.Omit("Homework").Create(&reminders).Error
+}
+
+// LastReminderTimes 每个学生最近一次收到这个作业提醒的时间
+func LastReminderTimes(homeworkID int64) (map[int64]time.Time, error) {
+	var rows []struct {
+		StudentID int64
+		LastAt    time.Time
+	}
+	err := DB.Model(&models.Reminder{}).
+		Select("student_id, MAX(created_at) AS last_at").
+		Where("homework_id = ?", homeworkID).
+		Group("student_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		result[r.StudentID] = r.LastAt
+	}
+	return result, nil
+}
+
+// ListRemindersByStudent 分页查询学生收到的提醒（新的在前，关联作业）
+func ListRemindersByStudent(studentID int64, page, pageSize int) ([]models.Reminder, int64, error) {
+	var list []models.Reminder
+	var total int64
+	query := DB.Model(&models.Reminder{}).Where("student_id = ?", studentID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Preload("Homework").
+		Order("id DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&list).Error
+	return list, total, err
+}
+
+// MarkRemindersRead 把学生的提醒全部标为已读
+func MarkRemindersRead(studentID int64, at time.Time) error {
+	return DB.Model(&models.Reminder{}).
+		Where("student_id = ? AND read_at IS NULL", studentID).
+		Update("read_at", at).Error
+}
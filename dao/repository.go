@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// CreateRepositorySnapshot 保存仓库快照：先保存打包的文件，再保存快照信息（同一事务）
+func CreateRepositorySnapshot(file *models.StoredFile, snapshot *models.RepositorySnapshot) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+		snapshot.FileID = file.ID
+		return tx.Create(snapshot).Error
+	})
+}
+
+// ListRepositorySnapshotsBySubmission 查询提交的所有仓库快照（包括历史版本的，新的在前）
+func ListRepositorySnapshotsBySubmission(submissionID int64) ([]models.RepositorySnapshot, error) {
+	var list []models.RepositorySnapshot
+	err := DB.Joins("JOIN stored_files ON stored_files.id = repository_snapshots.file_id").
+		Where("stored_files.submission_id = ?", submissionID).
+		Order("repository_snapshots.id DESC").
+		Find(&list).Error
+	return list, err
+}
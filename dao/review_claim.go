@@ -0,0 +1,182 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// activeClaims 有效认领的提交ID（分配的不过期，领取的没到期）
+func activeClaims(now time.Time) *gorm.DB {
+	return DB.Model(&models.ReviewClaim{}).Select("submission_id").
+		Where("expires_at IS NULL OR expires_at > ?", now)
+}
+
+// ClaimSubmission 认领提交：没人认领、认领已过期、本来就是自己的或强制接手时成功（返回false表示别人持有）。
+// 自己名下不过期的分配不会因为再次领取变成有期限的
+func ClaimSubmission(submission *models.Submission, reviewerID int64, expiresAt *time.Time, force bool) (bool, error) {
+	claimed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.ReviewClaim
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("submission_id = ?", submission.ID).
+			First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			claim := &models.ReviewClaim{
+				SubmissionID: submission.ID,
+				HomeworkID:   submission.HomeworkID,
+				ReviewerID:   reviewerID,
+				ExpiresAt:    expiresAt,
+			}
+			if err := tx.Omit("Reviewer").Create(claim).Error; err != nil {
+				if IsDuplicateKey(err) {
+					// 并发认领时别人先插入了
+					return nil
+				}
+				return err
+			}
+			claimed = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		mine := existing.ReviewerID == reviewerID
+		if !mine && existing.Active(time.Now()) && !force {
+			return nil
+		}
+		if mine && existing.ExpiresAt == nil {
+			expiresAt = nil
+		}
+		if err := tx.Model(&existing).Updates(map[string]interface{}{
+			"reviewer_id": reviewerID,
+			"expires_at":  expiresAt,
+		}).Error; err != nil {
+			return err
+		}
+		claimed = true
+		return nil
+	})
+	return claimed, err
+}
+
+// GetReviewClaim 查询提交的认领（关联批改人）
+func GetReviewClaim(subID int64) (*models.ReviewClaim, error) {
+	var claim models.ReviewClaim
+	err := DB.Preload("Reviewer").Where("submission_id = ?", subID).First(&claim).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &claim, err
+}
+
+// ReleaseReviewClaim 释放自己的认领（返回false表示不是自己持有的）
+func ReleaseReviewClaim(subID, reviewerID int64) (bool, error) {
+	res := DB.Where("submission_id = ? AND reviewer_id = ?", subID, reviewerID).Delete(&models.ReviewClaim{})
+	return res.RowsAffected == 1, res.Error
+}
+
+// AssignReviewClaims 批量分配批改人（不过期，覆盖原来的认领）
+func AssignReviewClaims(claims []models.ReviewClaim) error {
+	if len(claims) == 0 {
+		return nil
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "submission_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"reviewer_id": gorm.Expr("VALUES(reviewer_id)"),
+			"expires_at":  nil,
+			"updated_at":  time.Now(),
+		}),
+	}).Omit("Reviewer").Create(&claims).Error
+}
+
+// ListUnclaimedSubmissions 查询作业里没批改、没撤回、也没人有效认领的提交（先交的在前）
+func ListUnclaimedSubmissions(homeworkID int64, now time.Time) ([]models.Submission, error) {
+	var list []models.Submission
+	err := DB.Where("homework_id = ? AND withdrawn = ? AND score IS NULL", homeworkID, false).
+		Where("id NOT IN (?)", activeClaims(now)).
+		Order("submitted_at ASC, id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ListClaimedSubmissions 查询批改人名下还没批改的提交（homeworkID为0时查所有作业，先交的在前）
+func ListClaimedSubmissions(reviewerID, homeworkID int64, now time.Time) ([]models.Submission, error) {
+	var list []models.Submission
+	mine := DB.Model(&models.ReviewClaim{}).Select("submission_id").
+		Where("reviewer_id = ? AND (expires_at IS NULL OR expires_at > ?)", reviewerID, now)
+	query := DB.Preload("Homework").
+		Where("id IN (?) AND withdrawn = ? AND score IS NULL", mine, false)
+	if homeworkID > 0 {
+		query = query.Where("homework_id = ?", homeworkID)
+	}
+	err := query.Order("submitted_at ASC, id ASC").Find(&list).Error
+	return list, err
+}
+
+// CountOpenClaims 统计每个批改人手上还没批改的提交数（按负载分配用）
+func CountOpenClaims(reviewerIDs []int64, now time.Time) (map[int64]int64, error) {
+	result := make(map[int64]int64, len(reviewerIDs))
+	if len(reviewerIDs) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		ReviewerID int64
+		Count      int64
+	}
+	err := DB.Table("review_claims AS c").
+		Select("c.reviewer_id AS reviewer_id, COUNT(*) AS count").
+		Joins("JOIN submissions AS s ON s.id = c.submission_id").
+		Where("c.reviewer_id IN ? AND (c.expires_at IS NULL OR c.expires_at > ?)", reviewerIDs, now).
+		Where("s.score IS NULL AND s.withdrawn = ?", false).
+		Group("c.reviewer_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		result[row.ReviewerID] = row.Count
+	}
+	return result, err
+}
+
+// SetMentor 设置学生的导师（已有导师的覆盖）
+func SetMentor(mentorID int64, studentIDs []int64) error {
+	if len(studentIDs) == 0 {
+		return nil
+	}
+	list := make([]models.Mentorship, 0, len(studentIDs))
+	for _, id := range studentIDs {
+		list = append(list, models.Mentorship{StudentID: id, MentorID: mentorID})
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mentor_id"}),
+	}).Omit("Student", "Mentor").Create(&list).Error
+}
+
+// ListMentorships 查询导师关系（mentorID为0时查全部）
+func ListMentorships(mentorID int64) ([]models.Mentorship, error) {
+	var list []models.Mentorship
+	query := DB.Preload("Student").Preload("Mentor")
+	if mentorID > 0 {
+		query = query.Where("mentor_id = ?", mentorID)
+	}
+	err := query.Order("mentor_id ASC, student_id ASC").Find(&list).Error
+	return list, err
+}
+
+// MapMentors 批量查询学生的导师
+func MapMentors(studentIDs []int64) (map[int64]int64, error) {
+	result := make(map[int64]int64, len(studentIDs))
+	if len(studentIDs) == 0 {
+		return result, nil
+	}
+	var list []models.Mentorship
+	err := DB.Where("student_id IN ?", studentIDs).Find(&list).Error
+	for _, m := range list {
+		result[m.StudentID] = m.MentorID
+	}
+	return result, err
+}
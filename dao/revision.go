@@ -0,0 +1,88 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListHomeworkRevisions 查询作业的所有版本（新的在前，关联修改人）
+func ListHomeworkRevisions(homeworkID int64) ([]models.HomeworkRevision, error) {
+	var list []models.HomeworkRevision
+	err := DB.Preload("Editor").Where("homework_id = ?", homeworkID).Order("version DESC").Find(&list).Error
+	return list, err
+}
+
+// GetHomeworkRevision 查询作业的某个版本
+func GetHomeworkRevision(homeworkID int64, version int) (*models.HomeworkRevision, error) {
+	var revision models.HomeworkRevision
+	err := DB.Where("homework_id = ? AND version = ?", homeworkID, version).First(&revision).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &revision, err
+}
+
+// ListNotableRevisionsSince 查询某时间之后需要提醒学生的修改（按版本顺序）
+func ListNotableRevisionsSince(homeworkID int64, since time.Time) ([]models.HomeworkRevision, error) {
+	var list []models.HomeworkRevision
+	err := DB.Where("homework_id = ? AND notable = ? AND created_at > ?", homeworkID, true, since).
+		Order("version ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// MapLatestNotableRevisionTime 批量查询作业最近一次需要提醒的修改时间
+func MapLatestNotableRevisionTime(homeworkIDs []int64) (map[int64]time.Time, error) {
+	result := make(map[int64]time.Time)
+	if len(homeworkIDs) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		HomeworkID int64
+		Latest     time.Time
+	}
+	err := DB.Model(&models.HomeworkRevision{}).
+		Select("homework_id, MAX(created_at) AS latest").
+		Where("homework_id IN ? AND notable = ?", homeworkIDs, true).
+		Group("homework_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		result[row.HomeworkID] = row.Latest
+	}
+	return result, err
+}
+
+// GetHomeworkView 查询学生上次查看作业的记录
+func GetHomeworkView(homeworkID, studentID int64) (*models.HomeworkView, error) {
+	var view models.HomeworkView
+	err := DB.Where("homework_id = ? AND student_id = ?", homeworkID, studentID).First(&view).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &view, err
+}
+
+// MapHomeworkViews 批量查询学生查看作业的时间
+func MapHomeworkViews(studentID int64, homeworkIDs []int64) (map[int64]time.Time, error) {
+	result := make(map[int64]time.Time)
+	if len(homeworkIDs) == 0 {
+		return result, nil
+	}
+	var list []models.HomeworkView
+	err := DB.Where("student_id = ? AND homework_id IN ?", studentID, homeworkIDs).Find(&list).Error
+	for _, view := range list {
+		result[view.HomeworkID] = view.ViewedAt
+	}
+	return result, err
+}
+
+// SaveHomeworkView 记录学生查看作业的时间
+func SaveHomeworkView(homeworkID, studentID int64, viewedAt time.Time) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "homework_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"viewed_at"}),
+	}).Create(&models.HomeworkView{HomeworkID: homeworkID, StudentID: studentID, ViewedAt: viewedAt}).Error
+}
@@ -0,0 +1,115 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// GetRubricByHomeworkID 查询作业的评分标准（关联评分项和等级描述）
+func GetRubricByHomeworkID(homeworkID int64) (*models.Rubric, error) {
+	var rubric models.Rubric
+	err := DB.Preload("Criteria", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC, id ASC")
+	}).
+		Preload("Criteria.Levels", func(db *gorm.DB) *gorm.DB {
+			return db.Order("points DESC")
+		}).
+		Where("homework_id = ?", homeworkID).
+		First(&rubric).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &rubric, err
+}
+
+// SaveRubric 整体替换作业的评分标准（旧评分项和等级描述全部删除后重建）；
+// 评分标准算作业设置的一部分，按读取时的作业版本号做条件更新并把版本号加1
+func SaveRubric(rubric *models.Rubric, version int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := updateHomeworkVersioned(tx, rubric.HomeworkID, version, map[string]interface{}{}); err != nil {
+			return err
+		}
+		var old models.Rubric
+		err := tx.Where("homework_id = ?", rubric.HomeworkID).First(&old).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == nil {
+			// 删除旧的等级描述和评分项
+			if err := tx.Where("criterion_id IN (?)",
+				tx.Model(&models.RubricCriterion{}).Select("id").Where("rubric_id = ?", old.ID),
+			).Delete(&models.RubricLevel{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("rubric_id = ?", old.ID).Delete(&models.RubricCriterion{}).Error; err != nil {
+				return err
+			}
+			rubric.ID = old.ID
+			rubric.CreatedAt = old.CreatedAt
+		}
+		// Save会连同Criteria、Levels一起写入
+		return tx.Save(rubric).Error
+	})
+}
+
+// DeleteRubric 删除作业的评分标准
+func DeleteRubric(homeworkID int64) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var rubric models.Rubric
+		err := tx.Where("homework_id = ?", homeworkID).First(&rubric).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("criterion_id IN (?)",
+			tx.Model(&models.RubricCriterion{}).Select("id").Where("rubric_id = ?", rubric.ID),
+		).Delete(&models.RubricLevel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("rubric_id = ?", rubric.ID).Delete(&models.RubricCriterion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&rubric).Error
+	})
+}
+
+// CountCriterionScoresByHomework 统计作业下已按评分标准打出的分项数（有数据时不允许再改评分标准）
+func CountCriterionScoresByHomework(homeworkID int64) (int64, error) {
+	var count int64
+	err := DB.Model(&models.SubmissionCriterionScore{}).
+		Joins("JOIN submissions ON submissions.id = submission_criterion_scores.submission_id").
+		Where("submissions.homework_id = ?", homeworkID).
+		Count(&count).Error
+	return count, err
+}
+
+// ReviewSubmissionWithCriteria 保存分项得分并更新提交的总分，分数有变动时记录（同一事务）
+func ReviewSubmissionWithCriteria(submission *models.Submission, scores []models.SubmissionCriterionScore, change *models.ScoreChange) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveCriterionScores(tx, submission.ID, scores); err != nil {
+			return err
+		}
+		return saveReview(tx, submission, change)
+	})
+	if err == nil {
+		submission.Version++
+	}
+	return err
+}
+
+// saveCriterionScores 重新批改时覆盖之前的分项得分
+func saveCriterionScores(tx *gorm.DB, subID int64, scores []models.SubmissionCriterionScore) error {
+	if err := tx.Where("submission_id = ?", subID).Delete(&models.SubmissionCriterionScore{}).Error; err != nil {
+		return err
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+	for i := range scores {
+		scores[i].SubmissionID = subID
+	}
+	return tx.Omit("Criterion").Create(&scores).Error
+}
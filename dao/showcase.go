@@ -0,0 +1,91 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveShowcaseConsent 保存学生的展示同意（已经同意过的只更新是否匿名）
+func SaveShowcaseConsent(consent *models.ShowcaseConsent) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "submission_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"anonymous", "updated_at"}),
+	}).Create(consent).Error
+}
+
+// DeleteShowcaseConsent 撤回展示同意
+func DeleteShowcaseConsent(submissionID, studentID int64) error {
+	return DB.Where("submission_id = ? AND student_id = ?", submissionID, studentID).
+		Delete(&models.ShowcaseConsent{}).Error
+}
+
+// GetShowcaseConsent 查询学生对提交的展示同意（没有同意过返回nil）
+func GetShowcaseConsent(submissionID, studentID int64) (*models.ShowcaseConsent, error) {
+	var consent models.ShowcaseConsent
+	err := DB.Where("submission_id = ? AND student_id = ?", submissionID, studentID).First(&consent).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &consent, err
+}
+
+// ListShowcaseConsentsBySubmissionIDs 批量查询提交的展示同意
+func ListShowcaseConsentsBySubmissionIDs(submissionIDs []int64) ([]models.ShowcaseConsent, error) {
+	var list []models.ShowcaseConsent
+	if len(submissionIDs) == 0 {
+		return list, nil
+	}
+	err := DB.Where("submission_id IN ?", submissionIDs).Find(&list).Error
+	return list, err
+}
+
+// showcased 能放进作品展的提交：标了优秀、没撤回、成绩已发布，提交者（小组作业时每个组员）都同意了展示；
+// 查询要先关联homeworks表
+func showcased(db *gorm.DB, now time.Time) *gorm.DB {
+	db = db.Where("submissions.is_excellent = ? AND submissions.withdrawn = ?", true, false).
+		Where("EXISTS (SELECT 1 FROM showcase_consents sc WHERE sc.submission_id = submissions.id AND sc.student_id = submissions.student_id)").
+		Where("(submissions.team_id IS NULL OR NOT EXISTS (SELECT 1 FROM team_members tm WHERE tm.team_id = submissions.team_id AND NOT EXISTS " +
+			"(SELECT 1 FROM showcase_consents sc WHERE sc.submission_id = submissions.id AND sc.student_id = tm.student_id)))")
+	return gradesVisible(db, now)
+}
+
+// ListShowcase 分页查询作品展（可按作业所属部门和作业筛选，关联作业、学生和小组组员）
+func ListShowcase(department string, homeworkID int64, page, pageSize int) ([]models.Submission, int64, error) {
+	var list []models.Submission
+	var total int64
+
+	query := showcased(DB.Model(&models.Submission{}).
+		Joins("JOIN homeworks ON homeworks.id = submissions.homework_id"), time.Now())
+	if department != "" {
+		query = query.Where("homeworks.department = ?", department)
+	}
+	if homeworkID > 0 {
+		query = query.Where("submissions.homework_id = ?", homeworkID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Homework").
+		Preload("Student").
+		Preload("Team.Members.Student").
+		Order("submissions.submitted_at DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&list).Error
+	return list, total, err
+}
+
+// IsShowcased 提交现在是否在作品展里
+func IsShowcased(submissionID int64) (bool, error) {
+	var count int64
+	err := showcased(DB.Model(&models.Submission{}).
+		Joins("JOIN homeworks ON homeworks.id = submissions.homework_id").
+		Where("submissions.id = ?", submissionID), time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,102 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// SaveSimilarityPairs 保存一份提交的查重结果：更新或新增可疑对，删掉已经不再可疑的；
+// 相似度上升时已核查的状态重置为待处理
+func SaveSimilarityPairs(submissionID int64, pairs []models.SimilarityPair) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// 1. 删掉这份提交不再出现的可疑对
+		query := tx.Where("submission_a_id = ? OR submission_b_id = ?", submissionID, submissionID)
+		for _, p := range pairs {
+			query = query.Where("NOT (submission_a_id = ? AND submission_b_id = ?)", p.SubmissionAID, p.SubmissionBID)
+		}
+		if err := query.Delete(&models.SimilarityPair{}).Error; err != nil {
+			return err
+		}
+
+		// 2. 逐对新增或更新
+		for i := range pairs {
+			pair := &pairs[i]
+			var existing models.SimilarityPair
+			err := tx.Where("submission_a_id = ? AND submission_b_id = ?", pair.SubmissionAID, pair.SubmissionBID).
+				First(&existing).Error
+			if err == gorm.ErrRecordNotFound {
+				pair.Status = models.SimilarityUnreviewed
+				if err := tx.Omit("SubmissionA", "SubmissionB").Create(pair).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			updates := map[string]interface{}{
+				"score":     pair.Score,
+				"jaccard":   pair.Jaccard,
+				"matches_a": pair.MatchesA,
+				"matches_b": pair.MatchesB,
+			}
+			if pair.Score > existing.Score && existing.Status != models.SimilarityUnreviewed {
+				updates["status"] = models.SimilarityUnreviewed
+				updates["reviewer_id"] = nil
+				updates["reviewed_at"] = nil
+			}
+			if err := tx.Model(&existing).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListSimilarityPairs 分页查询作业的可疑提交对（按相似度从高到低，不含已撤回的提交）
+func ListSimilarityPairs(homeworkID int64, status string, minScore float64, page, pageSize int) ([]models.SimilarityPair, int64, error) {
+	var list []models.SimilarityPair
+	var total int64
+
+	withdrawn := DB.Model(&models.Submission{}).Select("id").Where("homework_id = ? AND withdrawn = ?", homeworkID, true)
+	query := DB.Model(&models.SimilarityPair{}).
+		Where("homework_id = ? AND score >= ?", homeworkID, minScore).
+		Where("submission_a_id NOT IN (?) AND submission_b_id NOT IN (?)", withdrawn, withdrawn)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("SubmissionA.Student").
+		Preload("SubmissionB.Student").
+		Order("score DESC, id ASC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&list).Error
+	return list, total, err
+}
+
+// GetSimilarityPairByID 查询可疑提交对（含两份提交和学生）
+func GetSimilarityPairByID(pairID int64) (*models.SimilarityPair, error) {
+	var pair models.SimilarityPair
+	err := DB.Preload("SubmissionA.Student").Preload("SubmissionB.Student").First(&pair, pairID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &pair, err
+}
+
+// ReviewSimilarityPair 记录核查结果
+func ReviewSimilarityPair(pairID, reviewerID int64, status models.SimilarityStatus, note string, reviewedAt time.Time) error {
+	return DB.Model(&models.SimilarityPair{}).Where("id = ?", pairID).Updates(map[string]interface{}{
+		"status":      status,
+		"reviewer_id": reviewerID,
+		"review_note": note,
+		"reviewed_at": &reviewedAt,
+	}).Error
+}
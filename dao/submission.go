@@ -1,13 +1,106 @@
 package dao
 
 import (
+	"strings"
+	"time"
+
 	"github.com/chuji555/homework-system/models"
 	"gorm.io/gorm"
 )
 
-// 创建作业提交记录
+// 创建作业提交记录，同时保存为版本1
 func CreateSubmission(submission *models.Submission) error {
-	return DB.Create(submission).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(submission).Error; err != nil {
+			return err
+		}
+		if err := attachFiles(tx, submission); err != nil {
+			return err
+		}
+		return tx.Create(versionOf(submission, 1, submission.StudentID)).Error
+	})
+}
+
+// 重新提交：追加一个版本并把提交记录更新为最新内容；
+// 按提交次数做条件更新，并发重复提交时只有一个能成功（返回false表示被别的请求抢先）
+func ResubmitSubmission(previous, submission *models.Submission, submitterID int64) (bool, error) {
+	updated := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Submission{}).
+			Where("id = ? AND attempts = ? AND score IS NULL", submission.ID, previous.Attempts).
+			Updates(map[string]interface{}{
+				"content":      submission.Content,
+				"file_url":     submission.FileURL,
+				"file_ids":     submission.FileIDs,
+				"is_late":      submission.IsLate,
+				"submitted_at": submission.SubmittedAt,
+				"attempts":     submission.Attempts,
+				"withdrawn":    false,
+				"withdrawn_at": nil,
+				"version":      nextVersion(),
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		// 旧数据没有版本记录时，先把修改前的内容补成版本1
+		var count int64
+		if err := tx.Model(&models.SubmissionVersion{}).Where("submission_id = ?", submission.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := tx.Create(versionOf(previous, 1, previous.StudentID)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(versionOf(submission, submission.Attempts, submitterID)).Error; err != nil {
+			return err
+		}
+		if err := attachFiles(tx, submission); err != nil {
+			return err
+		}
+		updated = true
+		return nil
+	})
+	return updated, err
+}
+
+// attachFiles 把本次提交用到的上传文件关联到提交记录
+func attachFiles(tx *gorm.DB, submission *models.Submission) error {
+	if len(submission.FileIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&models.StoredFile{}).
+		Where("id IN ? AND submission_id IS NULL", []int64(submission.FileIDs)).
+		Update("submission_id", submission.ID).Error
+}
+
+// versionOf 用提交记录的当前内容生成一个版本
+func versionOf(submission *models.Submission, version int, submitterID int64) *models.SubmissionVersion {
+	return &models.SubmissionVersion{
+		SubmissionID: submission.ID,
+		Version:      version,
+		SubmitterID:  submitterID,
+		Content:      submission.Content,
+		FileURL:      submission.FileURL,
+		FileIDs:      submission.FileIDs,
+		IsLate:       submission.IsLate,
+		SubmittedAt:  submission.SubmittedAt,
+	}
+}
+
+// 撤回提交（已批改的不能撤回）
+func WithdrawSubmission(subID int64, withdrawnAt time.Time) (bool, error) {
+	res := DB.Model(&models.Submission{}).
+		Where("id = ? AND withdrawn = ? AND score IS NULL", subID, false).
+		Updates(map[string]interface{}{"withdrawn": true, "withdrawn_at": &withdrawnAt, "version": nextVersion()})
+	return res.RowsAffected == 1, res.Error
+}
+
+// 查询提交的所有版本（按版本号升序）
+func ListSubmissionVersions(subID int64) ([]models.SubmissionVersion, error) {
+	var list []models.SubmissionVersion
+	err := DB.Where("submission_id = ?", subID).Order("version ASC").Find(&list).Error
+	return list, err
 }
 
 // 根据学生ID+作业ID查询提交记录（防止重复提交）
@@ -20,44 +113,139 @@ func GetSubmissionByStudentAndHomework(studentID, homeworkID int64) (*models.Sub
 	return &sub, err
 }
 
-// 根据学生ID分页查询提交记录
+// 根据小组ID查询提交记录（小组作业一组只能交一份）
+func GetSubmissionByTeam(teamID int64) (*models.Submission, error) {
+	var sub models.Submission
+	err := DB.Where("team_id = ?", teamID).First(&sub).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &sub, err
+}
+
+// 学生本人的提交，或者学生所在小组的提交
+func ownOrTeamSubmission(db *gorm.DB, studentID int64) *gorm.DB {
+	return db.Where("(student_id = ? OR team_id IN (?))", studentID,
+		DB.Model(&models.TeamMember{}).Select("team_id").Where("student_id = ?", studentID))
+}
+
+// 根据学生ID分页查询提交记录（包含所在小组的提交）
 func ListSubmissionByStudentID(studentID int64, page, pageSize int) ([]models.Submission, int64, error) {
 	var list []models.Submission
 	var total int64
 
 	// 先查总数
-	if err := DB.Model(&models.Submission{}).Where("student_id = ?", studentID).Count(&total).Error; err != nil {
+	if err := ownOrTeamSubmission(DB.Model(&models.Submission{}), studentID).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err := DB.Preload("Homework"). // 关联查询作业信息
-					Where("student_id = ?", studentID).
-					Order("submitted_at DESC").
-					Limit(pageSize).
-					Offset(offset).
-					Find(&list).Error
+	query := DB.Preload("Homework"). // 关联查询作业信息
+						Preload("CriterionScores.Criterion"). // 分项得分明细
+						Preload("Team.Members.Student").      // 小组作业的组员
+						Preload("QuizAnswers").               // 测验逐题结果
+						Preload("Files")                      // 上传的文件
+	err := ownOrTeamSubmission(query, studentID).
+		Order("submitted_at DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&list).Error
 
 	return list, total, err
 }
 
-// 根据作业ID分页查询提交记录（管理员用）
-func ListSubmissionByHomeworkID(homeworkID int64, page, pageSize int) ([]models.Submission, int64, error) {
+// 管理员提交列表的筛选和排序条件（零值表示不筛选）
+type SubmissionFilter struct {
+	Reviewed   *bool  // true只看已批改，false只看未批改
+	Late       *bool  // 是否迟交
+	Excellent  *bool  // 是否优秀
+	MinScore   *int   // 分数下限（含，按作业评分制的原始分数，只匹配已批改的）
+	MaxScore   *int   // 分数上限（含）
+	ReviewerID int64  // 批改人
+	Keyword    string // 学生（小组作业时任一组员）的用户名或昵称包含的关键字
+	Sort       string // 排序字段，见SubmissionSort*
+	Desc       bool   // 是否倒序
+}
+
+// 提交列表的排序字段
+const (
+	SubmissionSortSubmittedAt     = "submitted_at"     // 提交时间（默认）
+	SubmissionSortScore           = "score"            // 分数（未批改的始终排在最后）
+	SubmissionSortReviewedAt      = "reviewed_at"      // 批改时间（未批改的始终排在最后）
+	SubmissionSortUnreviewedFirst = "unreviewed_first" // 未批改的在前，同类按提交时间
+)
+
+// likeEscaper 转义LIKE里的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// 根据作业ID分页查询提交记录（管理员用，不含已撤回的），按filter筛选和排序
+func ListSubmissionByHomeworkID(homeworkID int64, filter SubmissionFilter, page, pageSize int) ([]models.Submission, int64, error) {
 	var list []models.Submission
 	var total int64
 
-	if err := DB.Model(&models.Submission{}).Where("homework_id = ?", homeworkID).Count(&total).Error; err != nil {
+	// 1. 筛选条件
+	query := DB.Model(&models.Submission{}).
+		Where("submissions.homework_id = ? AND submissions.withdrawn = ?", homeworkID, false)
+	if filter.Reviewed != nil {
+		if *filter.Reviewed {
+			query = query.Where("submissions.score IS NOT NULL")
+		} else {
+			query = query.Where("submissions.score IS NULL")
+		}
+	}
+	if filter.Late != nil {
+		query = query.Where("submissions.is_late = ?", *filter.Late)
+	}
+	if filter.Excellent != nil {
+		query = query.Where("submissions.is_excellent = ?", *filter.Excellent)
+	}
+	if filter.MinScore != nil {
+		query = query.Where("submissions.score >= ?", *filter.MinScore)
+	}
+	if filter.MaxScore != nil {
+		query = query.Where("submissions.score <= ?", *filter.MaxScore)
+	}
+	if filter.ReviewerID > 0 {
+		query = query.Where("submissions.reviewer_id = ?", filter.ReviewerID)
+	}
+	if filter.Keyword != "" {
+		like := "%" + likeEscaper.Replace(filter.Keyword) + "%"
+		query = query.Where("(EXISTS (SELECT 1 FROM users u WHERE u.id = submissions.student_id AND (u.username LIKE ? OR u.nickname LIKE ?))"+
+			" OR EXISTS (SELECT 1 FROM team_members tm JOIN users u ON u.id = tm.student_id"+
+			" WHERE tm.team_id = submissions.team_id AND (u.username LIKE ? OR u.nickname LIKE ?)))",
+			like, like, like, like)
+	}
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
+	// 2. 排序（同值时按ID保证分页稳定）
+	dir := "ASC"
+	if filter.Desc {
+		dir = "DESC"
+	}
+	switch filter.Sort {
+	case SubmissionSortScore:
+		query = query.Order("submissions.score IS NULL").Order("submissions.score " + dir)
+	case SubmissionSortReviewedAt:
+		query = query.Order("submissions.reviewed_at IS NULL").Order("submissions.reviewed_at " + dir)
+	case SubmissionSortUnreviewedFirst:
+		query = query.Order("submissions.score IS NOT NULL").Order("submissions.submitted_at " + dir)
+	default:
+		query = query.Order("submissions.submitted_at " + dir)
+	}
+	query = query.Order("submissions.id " + dir)
+
+	// 3. 分页查询
 	offset := (page - 1) * pageSize
-	err := DB.Preload("Student"). // 关联查询学生信息
-					Where("homework_id = ?", homeworkID).
-					Order("submitted_at DESC").
-					Limit(pageSize).
-					Offset(offset).
-					Find(&list).Error
+	err := query.Preload("Student"). // 关联查询学生信息
+						Preload("CriterionScores").
+						Preload("Files").
+						Preload("Team.Members.Student").
+						Limit(pageSize).
+						Offset(offset).
+						Find(&list).Error
 
 	return list, total, err
 }
@@ -72,28 +260,122 @@ func GetSubmissionByID(subID int64) (*models.Submission, error) {
 	return &sub, err
 }
 
-// 更新提交记录（批改作业/标记优秀）
-func UpdateSubmission(submission *models.Submission) error {
-	return DB.Save(submission).Error
+// 标记/取消优秀作业（只改优秀标记和推荐理由，按读取时的版本号做条件更新）
+func MarkSubmissionExcellent(submission *models.Submission, isExcellent bool, reason string) error {
+	res := DB.Model(&models.Submission{}).
+		Where("id = ? AND version = ?", submission.ID, submission.Version).
+		Updates(map[string]interface{}{"is_excellent": isExcellent, "excellent_reason": reason, "version": nextVersion()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	submission.IsExcellent = isExcellent
+	submission.ExcellentReason = reason
+	submission.Version++
+	return nil
 }
 
-// 查询优秀作业（所有学生可见）
-func ListExcellentSubmission(page, pageSize int) ([]models.Submission, int64, error) {
-	var list []models.Submission
-	var total int64
+// 保存批改结果，分数有变动时记录（同一事务）
+func ReviewSubmission(submission *models.Submission, change *models.ScoreChange) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		return saveReview(tx, submission, change)
+	})
+	if err == nil {
+		submission.Version++
+	}
+	return err
+}
 
-	if err := DB.Model(&models.Submission{}).Where("is_excellent = ?", true).Count(&total).Error; err != nil {
-		return nil, 0, err
+// saveReview 保存提交的批改信息并追加分数变动记录（change为空表示分数没变），批改后认领失效；
+// 只写批改相关的列，按读取时的版本号做条件更新，期间被别人改过时返回ErrVersionConflict
+func saveReview(tx *gorm.DB, submission *models.Submission, change *models.ScoreChange) error {
+	res := tx.Model(&models.Submission{}).
+		Where("id = ? AND version = ?", submission.ID, submission.Version).
+		Updates(map[string]interface{}{
+			"score":       submission.Score,
+			"comment":     submission.Comment,
+			"reviewer_id": submission.ReviewerID,
+			"reviewed_at": submission.ReviewedAt,
+			"version":     nextVersion(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	if err := tx.Where("submission_id = ?", submission.ID).Delete(&models.ReviewClaim{}).Error; err != nil {
+		return err
 	}
+	if change == nil {
+		return nil
+	}
+	change.SubmissionID = submission.ID
+	return tx.Omit("Changer").Create(change).Error
+}
 
-	offset := (page - 1) * pageSize
-	err := DB.Preload("Homework").
-		Preload("Student").
-		Where("is_excellent = ?", true).
-		Order("submitted_at DESC").
-		Limit(pageSize).
-		Offset(offset).
+// 统计作业的提交数（不含已撤回的）
+func CountSubmissionByHomework(homeworkID int64) (int64, error) {
+	var count int64
+	err := DB.Model(&models.Submission{}).Where("homework_id = ? AND withdrawn = ?", homeworkID, false).Count(&count).Error
+	return count, err
+}
+
+// 统计作业已批改的提交数
+func CountReviewedSubmissionByHomework(homeworkID int64) (int64, error) {
+	var count int64
+	err := DB.Model(&models.Submission{}).
+		Where("homework_id = ? AND score IS NOT NULL", homeworkID).
+		Count(&count).Error
+	return count, err
+}
+
+// 小组作业已批改提交上每个组员的小组得分和个人调整
+type TeamMemberScore struct {
+	Score           int
+	ScoreAdjustment int
+}
+
+// 查询小组作业每个组员的得分（统计用，一条小组提交对应每个组员一行）
+func ListReviewedTeamMemberScores(homeworkID int64) ([]TeamMemberScore, error) {
+	var list []TeamMemberScore
+	err := DB.Model(&models.Submission{}).
+		Select("submissions.score AS score, team_members.score_adjustment AS score_adjustment").
+		Joins("JOIN team_members ON team_members.team_id = submissions.team_id").
+		Where("submissions.homework_id = ? AND submissions.score IS NOT NULL", homeworkID).
+		Scan(&list).Error
+	return list, err
+}
+
+// 查询作业所有已批改提交的分数（统计用）
+func ListReviewedScoresByHomework(homeworkID int64) ([]int, error) {
+	var scores []int
+	err := DB.Model(&models.Submission{}).
+		Where("homework_id = ? AND score IS NOT NULL", homeworkID).
+		Pluck("score", &scores).Error
+	return scores, err
+}
+
+// 查询学生在一批作业上的提交记录（关联作业和组员，用于判断前置作业是否完成）
+func ListSubmissionByStudentAndHomeworkIDs(studentID int64, homeworkIDs []int64) ([]models.Submission, error) {
+	var list []models.Submission
+	if len(homeworkIDs) == 0 {
+		return list, nil
+	}
+	err := ownOrTeamSubmission(DB.Preload("Homework").Preload("Team.Members"), studentID).
+		Where("homework_id IN ?", homeworkIDs).
 		Find(&list).Error
+	return list, err
+}
 
-	return list, total, err
+// 查询一批作业的全部提交记录（学习进度统计用，不含已撤回的）
+func ListSubmissionByHomeworkIDs(homeworkIDs []int64) ([]models.Submission, error) {
+	var list []models.Submission
+	if len(homeworkIDs) == 0 {
+		return list, nil
+	}
+	err := DB.Where("homework_id IN ? AND withdrawn = ?", homeworkIDs, false).Find(&list).Error
+	return list, err
 }
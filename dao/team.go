@@ -0,0 +1,145 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// CreateTeam 创建小组（连同组员一起写入）
+func CreateTeam(team *models.Team) error {
+	return DB.Omit("Members.Student").Create(team).Error
+}
+
+// DeleteTeam 解散小组
+func DeleteTeam(teamID int64) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", teamID).Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Team{}, teamID).Error
+	})
+}
+
+// GetTeamByID 查询小组（关联组员信息）
+func GetTeamByID(teamID int64) (*models.Team, error) {
+	var team models.Team
+	err := DB.Preload("Members.Student").Where("id = ?", teamID).First(&team).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &team, err
+}
+
+// GetTeamByStudentAndHomework 查询学生在某个作业下所在的小组
+func GetTeamByStudentAndHomework(studentID, homeworkID int64) (*models.Team, error) {
+	var member models.TeamMember
+	err := DB.Where("student_id = ? AND homework_id = ?", studentID, homeworkID).First(&member).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return GetTeamByID(member.TeamID)
+}
+
+// ListTeamByHomework 查询作业下的全部小组
+func ListTeamByHomework(homeworkID int64) ([]models.Team, error) {
+	var list []models.Team
+	err := DB.Preload("Members.Student").
+		Where("homework_id = ?", homeworkID).
+		Order("id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ReplaceTeamMembers 整体替换小组成员（保留原有成员的分数调整）
+func ReplaceTeamMembers(team *models.Team, studentIDs []int64) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		keep := make(map[int64]bool, len(studentIDs))
+		for _, id := range studentIDs {
+			keep[id] = true
+		}
+		existing := make(map[int64]bool, len(team.Members))
+		for _, m := range team.Members {
+			existing[m.StudentID] = true
+			if !keep[m.StudentID] {
+				if err := tx.Delete(&models.TeamMember{}, m.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		for _, id := range studentIDs {
+			if existing[id] {
+				continue
+			}
+			member := &models.TeamMember{TeamID: team.ID, HomeworkID: team.HomeworkID, StudentID: id}
+			if err := tx.Omit("Student").Create(member).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddTeamMember 加入小组
+func AddTeamMember(member *models.TeamMember) error {
+	return DB.Omit("Student").Create(member).Error
+}
+
+// DeleteTeamMember 退出小组
+func DeleteTeamMember(teamID, studentID int64) error {
+	return DB.Where("team_id = ? AND student_id = ?", teamID, studentID).Delete(&models.TeamMember{}).Error
+}
+
+// UpdateTeamMemberAdjustment 设置组员的个人分数调整并追加分数变动记录（change为空表示个人得分没变，同一事务）；
+// 按读取时的版本号条件更新小组的提交，期间被别人改过时返回ErrVersionConflict
+func UpdateTeamMemberAdjustment(submission *models.Submission, studentID int64, adjustment int, change *models.ScoreChange) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Submission{}).
+			Where("id = ? AND version = ?", submission.ID, submission.Version).
+			Update("version", nextVersion())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		if err := tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND student_id = ?", submission.TeamID, studentID).
+			Update("score_adjustment", adjustment).Error; err != nil {
+			return err
+		}
+		if change == nil {
+			return nil
+		}
+		change.SubmissionID = submission.ID
+		return tx.Omit("Changer").Create(change).Error
+	})
+	if err == nil {
+		submission.Version++
+	}
+	return err
+}
+
+// ListTeamMembersByTeamIDs 批量查询小组成员
+func ListTeamMembersByTeamIDs(teamIDs []int64) ([]models.TeamMember, error) {
+	var list []models.TeamMember
+	if len(teamIDs) == 0 {
+		return list, nil
+	}
+	err := DB.Where("team_id IN ?", teamIDs).Find(&list).Error
+	return list, err
+}
+
+// CountTeamMembersByHomework 统计作业下已组队的学生数
+func CountTeamMembersByHomework(homeworkID int64) (int64, error) {
+	var count int64
+	err := DB.Model(&models.TeamMember{}).Where("homework_id = ?", homeworkID).Count(&count).Error
+	return count, err
+}
+
+// UpdateTeamLeader 更换组长
+func UpdateTeamLeader(teamID, leaderID int64) error {
+	return DB.Model(&models.Team{}).Where("id = ?", teamID).Update("leader_id", leaderID).Error
+}
@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"github.com/chuji555/homework-system/models"
+	"gorm.io/gorm"
+)
+
+// CreateTrack 创建学习路线（连同路线中的作业一起写入）
+func CreateTrack(track *models.Track) error {
+	return DB.Omit("Items.Homework").Create(track).Error
+}
+
+// UpdateTrack 修改学习路线（路线中的作业整体替换）
+func UpdateTrack(track *models.Track) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("track_id = ?", track.ID).Delete(&models.TrackItem{}).Error; err != nil {
+			return err
+		}
+		for i := range track.Items {
+			track.Items[i].ID = 0
+			track.Items[i].TrackID = track.ID
+		}
+		return tx.Omit("Items.Homework").Save(track).Error
+	})
+}
+
+// DeleteTrack 删除学习路线
+func DeleteTrack(trackID int64) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("track_id = ?", trackID).Delete(&models.TrackItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Track{}, trackID).Error
+	})
+}
+
+// GetTrackByID 查询学习路线（关联路线中的作业，按顺序）
+func GetTrackByID(trackID int64) (*models.Track, error) {
+	var track models.Track
+	err := DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).
+		Preload("Items.Homework").
+		Where("id = ?", trackID).
+		First(&track).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &track, err
+}
+
+// ListTrack 查询学习路线列表（支持部门筛选）
+func ListTrack(department string) ([]models.Track, error) {
+	var list []models.Track
+	query := DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).
+		Preload("Items.Homework")
+	if department != "" {
+		query = query.Where("department = ?", department)
+	}
+	err := query.Order("created_at DESC").Find(&list).Error
+	return list, err
+}
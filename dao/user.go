@@ -1,6 +1,8 @@
 package dao
 
 import (
+	"time"
+
 	"github.com/chuji555/homework-system/models"
 	"gorm.io/gorm"
 )
@@ -34,3 +36,27 @@ func GetUserByID(userID int64) (*models.User, error) {
 func DeleteUser(userID int64) error {
 	return DB.Delete(&models.User{}, userID).Error
 }
+
+// 查询部门的全部学生
+func ListStudentsByDepartment(department string) ([]models.User, error) {
+	var list []models.User
+	err := DB.Where("role = ? AND department = ?", models.Student, department).
+		Order("id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// UpdateUserLastLogin 记录登录时间（不改updated_at）
+func UpdateUserLastLogin(userID int64, at time.Time) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("last_login_at", at).Error
+}
+
+// 根据ID批量查询用户
+func ListUsersByIDs(userIDs []int64) ([]models.User, error) {
+	var list []models.User
+	if len(userIDs) == 0 {
+		return list, nil
+	}
+	err := DB.Where("id IN ?", userIDs).Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 设置测试用例的请求参数
+type SaveTestCasesRequest struct {
+	TestCases []TestCaseRequest `json:"test_cases" binding:"dive"` // 按顺序排列，传空数组表示关闭自动评测
+}
+
+// 单个测试用例
+type TestCaseRequest struct {
+	Name           string `json:"name" binding:"required"`
+	Kind           string `json:"kind" binding:"required,oneof=io go_test"` // io：输入输出比对，go_test：隐藏测试文件
+	Input          string `json:"input"`                                    // io：标准输入
+	ExpectedOutput string `json:"expected_output"`                          // io：期望输出
+	TestSource     string `json:"test_source"`                              // go_test：测试文件源码（package main）
+	Points         int    `json:"points" binding:"required,min=1"`          // 分值
+}
+
+// 管理员设置作业的测试用例
+func SaveTestCases(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req SaveTestCasesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	inputs := make([]service.TestCaseInput, 0, len(req.TestCases))
+	for _, tc := range req.TestCases {
+		inputs = append(inputs, service.TestCaseInput{
+			Name:           tc.Name,
+			Kind:           tc.Kind,
+			Input:          tc.Input,
+			ExpectedOutput: tc.ExpectedOutput,
+			TestSource:     tc.TestSource,
+			Points:         tc.Points,
+		})
+	}
+	errCode := service.SaveTestCases(homeworkID, inputs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// 管理员查询作业的测试用例
+func ListTestCases(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	list, errCode := service.ListTestCases(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
+
+// 管理员查看提交的自动评测结果
+func GetGradeJob(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	job, errCode := service.GetGradeJob(subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, job)
+}
+
+// 管理员重新评测提交
+func RerunGradeJob(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	job, errCode := service.RerunGradeJob(subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, job)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 设置匿名批改的请求参数
+type SetBlindGradingRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Reason  string `json:"reason"` // 成绩发布前关闭匿名批改时必填（会记录）
+}
+
+// 查看学生身份的请求参数
+type DeanonymizeRequest struct {
+	Reason string `json:"reason" binding:"required"` // 查看原因（会记录）
+}
+
+// 管理员开启/关闭作业的匿名批改
+func SetBlindGrading(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req SetBlindGradingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	errCode = service.SetBlindGrading(adminID.(int64), homeworkID, *req.Enabled, req.Reason, ifMatch)
+	if errCode == errcode.VersionConflict {
+		respondHomeworkConflict(c, homeworkID)
+		return
+	}
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// 管理员查询作业的身份查看记录
+func ListDeanonymizationLogs(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	list, errCode := service.ListDeanonymizationLogs(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
+
+// 作业发布者在匿名批改期间查看提交者身份
+func DeanonymizeSubmission(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req DeanonymizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	student, errCode := service.DeanonymizeSubmission(adminID.(int64), subID, req.Reason)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, student)
+}
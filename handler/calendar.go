@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 生成（重置）日历订阅链接
+func ResetCalendarToken(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	token, errCode := service.ResetCalendarToken(userID.(int64))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{
+		"url": requestBaseURL(c) + "/calendar/" + token + ".ics", // 填到手机日历的“订阅日历”里
+	})
+}
+
+// 撤销日历订阅链接
+func RevokeCalendarToken(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	errCode := service.RevokeCalendarToken(userID.(int64))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "日历订阅已撤销"})
+}
+
+// 日历订阅（公开接口，凭URL里的令牌鉴权）
+func CalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	data, errCode := service.BuildCalendar(token, requestBaseURL(c))
+	if errCode != errcode.Success {
+		// 日历App只认HTTP状态码
+		if errCode == errcode.AuthError {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// requestBaseURL 根据当前请求拼出服务的根地址
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 发表评论的请求参数
+type CreateCommentRequest struct {
+	Content   string `json:"content" binding:"required"`
+	ParentID  *int64 `json:"parent_id" binding:"omitempty,min=1"`  // 回复的评论ID
+	LineStart *int   `json:"line_start" binding:"omitempty,min=1"` // 锚定的行范围（从1开始，和line_end一起传）
+	LineEnd   *int   `json:"line_end" binding:"omitempty,min=1"`
+	Private   bool   `json:"private"` // 批改人的私有备注（学生看不到，学生不能设置）
+}
+
+// 标记已读的请求参数
+type MarkCommentsReadRequest struct {
+	LastCommentID int64 `json:"last_comment_id" binding:"omitempty,min=1"` // 读到的评论ID，不传表示全部已读
+}
+
+// 在提交下发表评论或回复
+func CreateSubmissionComment(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	comment, errCode := service.CreateSubmissionComment(userID.(int64), role == "admin", subID, service.CommentInput{
+		Content:   req.Content,
+		ParentID:  req.ParentID,
+		LineStart: req.LineStart,
+		LineEnd:   req.LineEnd,
+		Private:   req.Private,
+	})
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, comment)
+}
+
+// 查询提交的讨论
+func ListSubmissionComments(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	thread, errCode := service.ListSubmissionComments(userID.(int64), role == "admin", subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, thread)
+}
+
+// 标记提交的讨论已读
+func MarkSubmissionCommentsRead(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req MarkCommentsReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, errcode.ParamError)
+			return
+		}
+	}
+
+	errCode := service.MarkSubmissionCommentsRead(userID.(int64), role == "admin", subID, req.LastCommentID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// 查询有未读评论的提交
+func ListUnreadComments(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	list, errCode := service.ListUnreadComments(userID.(int64), role == "admin")
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
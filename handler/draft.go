@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 保存草稿的请求参数（内容可以为空，随时保存）
+type SaveDraftRequest struct {
+	Content string  `json:"content"`
+	FileURL string  `json:"file_url"`
+	FileIDs []int64 `json:"file_ids"`
+}
+
+// 学生自动保存草稿
+func SaveDraft(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req SaveDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	draft, errCode := service.SaveDraft(studentID.(int64), homeworkID, req.Content, req.FileURL, req.FileIDs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"updated_at": draft.UpdatedAt})
+}
+
+// 学生查询自己的草稿
+func GetDraft(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	draft, errCode := service.GetDraft(studentID.(int64), homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, draft)
+}
+
+// 学生丢弃草稿
+func DeleteDraft(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.DeleteDraft(studentID.(int64), homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "草稿已删除"})
+}
+
+// 学生把草稿作为正式提交
+func SubmitDraft(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.SubmitDraft(studentID.(int64), homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "提交成功"})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// setETag 用数据的版本号作为ETag
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchVersion 解析If-Match请求头里的版本号；修改带版本号的数据时必须带（*也不行，否则照样会覆盖别人的修改）
+func ifMatchVersion(c *gin.Context) (int, errcode.ErrCode) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" || value == "*" {
+		return 0, errcode.IfMatchRequired
+	}
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	v, err := strconv.Atoi(value)
+	if err != nil || v <= 0 {
+		return 0, errcode.ParamError
+	}
+	return v, errcode.Success
+}
+
+// respondHomeworkConflict 作业版本冲突时返回作业的最新数据和ETag
+func respondHomeworkConflict(c *gin.Context, homeworkID int64) {
+	current, errCode := service.GetHomeworkByID(homeworkID, 0)
+	if errCode != errcode.Success {
+		response.Error(c, errcode.VersionConflict)
+		return
+	}
+	setETag(c, current.Version)
+	response.ErrorWithData(c, errcode.VersionConflict, homeworkDetail(current))
+}
+
+// respondSubmissionConflict 版本冲突时返回提交的最新数据和ETag，前端据此刷新后重试
+func respondSubmissionConflict(c *gin.Context, userID int64, isAdmin bool, subID int64) {
+	current, errCode := service.GetSubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		response.Error(c, errcode.VersionConflict)
+		return
+	}
+	setETag(c, current.Version)
+	response.ErrorWithData(c, errcode.VersionConflict, current)
+}
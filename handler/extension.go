@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 个人延期的请求参数
+type GrantExtensionRequest struct {
+	StudentID int64     `json:"student_id" binding:"required"` // 学生ID
+	Deadline  time.Time `json:"deadline" binding:"required"`   // 延期后的截止时间（必须晚于作业截止时间）
+	Reason    string    `json:"reason" binding:"max=500"`      // 延期原因
+}
+
+// 管理员给学生单独延期
+func GrantExtension(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req GrantExtensionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.GrantExtension(homeworkID, req.StudentID, req.Deadline, req.Reason, adminID.(int64))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "延期成功"})
+}
+
+// 管理员取消学生的个人延期
+func RevokeExtension(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, err := strconv.ParseInt(c.Param("student_id"), 10, 64)
+	if err != nil || studentID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.RevokeExtension(homeworkID, studentID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "已取消延期"})
+}
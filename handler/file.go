@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 学生上传文件（multipart：homework_id + file），提交作业时通过file_ids关联
+func UploadFile(c *gin.Context) {
+	// 1. 获取当前登录学生ID
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	// 2. 限制请求体大小，解析表单
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxUploadRequestSize())
+	homeworkID, err := strconv.ParseInt(c.PostForm("homework_id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Error(c, errcode.FileTooLarge)
+			return
+		}
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Error(c, errcode.FileTooLarge)
+			return
+		}
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	// 3. 调用业务逻辑
+	file, errCode := service.UploadFile(studentID.(int64), homeworkID, header)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, file)
+}
+
+// 获取文件的临时下载链接（校验当前用户能否查看）
+func GetFileURL(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+	fileID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || fileID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	role, _ := c.Get("role")
+	url, expiresAt, errCode := service.FileDownloadURL(userID.(int64), role == "admin", fileID, requestBaseURL(c))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"url": url, "expires_at": expiresAt})
+}
+
+// 凭签名链接下载文件（浏览器直接打开，不带Token）
+func DownloadFile(c *gin.Context) {
+	fileID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || fileID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, err1 := strconv.ParseInt(c.Query("uid"), 10, 64)
+	expires, err2 := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err1 != nil || err2 != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	file, reader, errCode := service.OpenSignedFile(fileID, userID, expires, c.Query("sig"))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	defer reader.Close()
+
+	// 一律按附件下载，防止上传的文件在本站域名下被当成网页执行
+	c.Header("Content-Type", file.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(file.Size, 10))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("ETag", `"`+file.SHA256+`"`)
+	c.Status(http.StatusOK)
+	_, _ = io.Copy(c.Writer, reader)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 管理员从CSV/XLSX批量导入作业成绩（表单字段：file表格，dry_run=true只预览，override=true接手别人负责的提交）
+func ImportGrades(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	// 限制请求体大小，解析表单
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxImportRequestSize())
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Error(c, errcode.FileTooLarge)
+			return
+		}
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
+	override, _ := strconv.ParseBool(c.DefaultPostForm("override", "false"))
+
+	// 有错误的行时也返回逐行结果
+	result, errCode := service.ImportGrades(adminID.(int64), homeworkID, header, dryRun, override)
+	if errCode == errcode.GradeImportInvalid {
+		response.ErrorWithData(c, errCode, result)
+		return
+	}
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, result)
+}
+
+// 管理员查询作业的成绩导入记录
+func ListGradeImports(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	list, errCode := service.ListGradeImports(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 发布成绩的请求参数（不传请求体时立即发布）
+type ReleaseGradesRequest struct {
+	ReleaseAt *time.Time `json:"release_at"` // 预定发布时间，为空或已经过去时立即发布
+}
+
+// 管理员发布作业成绩（可以预定时间）
+func ReleaseGrades(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req ReleaseGradesRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, errcode.ParamError)
+			return
+		}
+	}
+
+	homework, errCode := service.ReleaseGrades(homeworkID, req.ReleaseAt)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{
+		"homework_id":        homework.ID,
+		"grades_released_at": homework.GradesReleasedAt,
+		"version":            homework.Version,
+	})
+}
+
+// 管理员取消预定的成绩发布
+func CancelGradeRelease(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.CancelGradeRelease(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "已取消预定发布"})
+}
@@ -17,6 +17,11 @@ type CreateHomeworkRequest struct {
 	Department  string    `json:"department" binding:"required,oneof=backend frontend sre product design android ios"` // 所属部门（必填，限定枚举值）
 	Deadline    time.Time `json:"deadline" binding:"required"`                                                         // 截止时间（必填）
 	AllowLate   bool      `json:"allow_late" binding:"omitempty"`                                                      // 是否允许迟交（可选，默认false）
+	MaxAttempts *int      `json:"max_attempts" binding:"omitempty,min=0"`                                              // 最多提交次数（可选，默认1，0表示不限）
+	// 评分制（可选，默认percentage百分制）
+	GradingScale string `json:"grading_scale" binding:"omitempty,oneof=percentage ten_point letter pass_fail"`
+	// 作业类型（可选，默认normal；git为提交仓库的作业，测验走题库接口创建）
+	Type string `json:"type" binding:"omitempty,oneof=normal git"`
 }
 
 // UpdateHomeworkRequest 管理员修改作业的请求参数（和创建类似，字段可选）
@@ -26,6 +31,9 @@ type UpdateHomeworkRequest struct {
 	Department  string     `json:"department" binding:"omitempty,oneof=backend frontend sre product design android ios"`
 	Deadline    *time.Time `json:"deadline" binding:"omitempty"` // 用指针，区分“不传”和“传空”
 	AllowLate   *bool      `json:"allow_late" binding:"omitempty"`
+	MaxAttempts *int       `json:"max_attempts" binding:"omitempty,min=0"`
+	// 评分制（已有批改记录时不允许修改）
+	GradingScale string `json:"grading_scale" binding:"omitempty,oneof=percentage ten_point letter pass_fail"`
 }
 
 // -------------------------- 核心接口实现 --------------------------
@@ -47,7 +55,11 @@ func CreateHomework(c *gin.Context) {
 		return
 	}
 
-	// 3. 调用service层的创建作业逻辑
+	// 3. 调用service层的创建作业逻辑（不传提交次数时只能交一次）
+	maxAttempts := 1
+	if req.MaxAttempts != nil {
+		maxAttempts = *req.MaxAttempts
+	}
 	errCode := service.CreateHomework(
 		req.Title,
 		req.Description,
@@ -55,6 +67,9 @@ func CreateHomework(c *gin.Context) {
 		creatorID.(int64), // 类型断言：上下文存的是interface{}，转成int64
 		req.Deadline,
 		req.AllowLate,
+		maxAttempts,
+		req.GradingScale,
+		req.Type,
 	)
 
 	// 4. 根据业务逻辑结果返回响应
@@ -85,17 +100,33 @@ func UpdateHomework(c *gin.Context) {
 		return
 	}
 
-	// 3. 调用service层修改逻辑
-	errCode := service.UpdateHomework(
+	// 只在If-Match的版本和当前一致时修改
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	// 3. 调用service层修改逻辑（记录修改人）
+	editorID, _ := c.Get("userID")
+	errCode = service.UpdateHomework(
 		homeworkID,
+		editorID.(int64),
 		req.Title,
 		req.Description,
 		req.Department,
 		req.Deadline,
 		req.AllowLate,
+		req.MaxAttempts,
+		req.GradingScale,
+		ifMatch,
 	)
 
-	// 4. 返回响应
+	// 4. 返回响应（版本冲突时带上最新的作业）
+	if errCode == errcode.VersionConflict {
+		respondHomeworkConflict(c, homeworkID)
+		return
+	}
 	if errCode != errcode.Success {
 		response.Error(c, errCode)
 		return
@@ -158,7 +189,7 @@ func ListHomework(c *gin.Context) {
 	}
 
 	// 3. 调用service层查询列表逻辑
-	list, total, errCode := service.ListHomework(department, page, pageSize)
+	list, total, errCode := service.ListHomework(department, page, pageSize, currentStudentID(c))
 	if errCode != errcode.Success {
 		response.Error(c, errCode)
 		return
@@ -185,14 +216,20 @@ func GetHomework(c *gin.Context) {
 	}
 
 	// 2. 调用service层查询详情逻辑
-	homework, errCode := service.GetHomeworkByID(homeworkID)
+	homework, errCode := service.GetHomeworkByID(homeworkID, currentStudentID(c))
 	if errCode != errcode.Success {
 		response.Error(c, errCode)
 		return
 	}
 
-	// 3. 格式化响应（补充部门中文标签）
-	resp := gin.H{
+	// 3. 格式化响应，版本号放在ETag里（修改时通过If-Match带回来）
+	setETag(c, homework.Version)
+	response.Success(c, homeworkDetail(homework))
+}
+
+// homeworkDetail 作业详情的响应格式（补充部门中文标签）
+func homeworkDetail(homework *models.Homework) gin.H {
+	return gin.H{
 		"id":               homework.ID,
 		"title":            homework.Title,
 		"description":      homework.Description,
@@ -202,11 +239,90 @@ func GetHomework(c *gin.Context) {
 		"creator_nickname": homework.Creator.Nickname, // 发布者昵称（关联查询）
 		"deadline":         homework.Deadline,
 		"allow_late":       homework.AllowLate,
-		"created_at":       homework.CreatedAt,
-		"updated_at":       homework.UpdatedAt,
+		"type":             homework.Type,
+		"grading_scale":    homework.GradingScale,
+		"grading_label":    homework.GradingScale.Label(), // 评分制中文名
+		"max_score":        homework.GradingScale.MaxScore(),
+		// 前置作业（学生未完成前置作业时locked为true）
+		"prerequisite_id":          homework.PrerequisiteID,
+		"prerequisite_min_percent": homework.PrerequisiteMinPercent,
+		"locked":                   homework.Locked,
+		// 小组作业设置
+		"is_group":            homework.IsGroup,
+		"min_team_size":       homework.MinTeamSize,
+		"max_team_size":       homework.MaxTeamSize,
+		"allow_student_teams": homework.AllowStudentTeams,
+		"created_at":          homework.CreatedAt,
+		"updated_at":          homework.UpdatedAt,
+		"version":             homework.Version,
+		// 成绩发布（预定发布时是将来的时间）
+		"require_grade_release": homework.RequireGradeRelease,
+		"grades_released_at":    homework.GradesReleasedAt,
 	}
+}
 
-	response.Success(c, resp)
+// GetHomeworkStats 管理员查询作业成绩统计（分数统一换算成百分比）
+func GetHomeworkStats(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	stats, errCode := service.GetHomeworkStats(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, stats)
+}
+
+// 设置前置作业的请求参数
+type SetPrerequisiteRequest struct {
+	PrerequisiteID *int64  `json:"prerequisite_id"`                     // 前置作业ID（传null取消前置）
+	MinPercent     float64 `json:"min_percent" binding:"min=0,max=100"` // 前置作业最低得分（换算成百分比）
+}
+
+// SetPrerequisite 管理员设置作业的前置作业
+func SetPrerequisite(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req SetPrerequisiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	errCode = service.SetPrerequisite(homeworkID, req.PrerequisiteID, req.MinPercent, ifMatch)
+	if errCode == errcode.VersionConflict {
+		respondHomeworkConflict(c, homeworkID)
+		return
+	}
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "前置作业设置成功"})
+}
+
+// currentStudentID 当前用户是学生时返回其ID，否则返回0（管理员不受作业锁定限制）
+func currentStudentID(c *gin.Context) int64 {
+	role, _ := c.Get("role")
+	userID, _ := c.Get("userID")
+	if role != "student" || userID == nil {
+		return 0
+	}
+	return userID.(int64)
 }
 
 // formatHomeworkList 格式化作业列表，补充部门中文标签
@@ -222,8 +338,52 @@ func formatHomeworkList(homeworks []models.Homework) []gin.H {
 			"creator_id":       h.CreatorID,
 			"deadline":         h.Deadline,
 			"allow_late":       h.AllowLate,
+			"type":             h.Type,
+			"grading_scale":    h.GradingScale,
+			"prerequisite_id":  h.PrerequisiteID,
+			"locked":           h.Locked,
+			"is_group":         h.IsGroup,
 			"created_at":       h.CreatedAt,
+			"version":          h.Version,
 		})
 	}
 	return list
 }
+
+// ListHomeworkRevisions 管理员查询作业的修改记录
+func ListHomeworkRevisions(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	list, errCode := service.ListHomeworkRevisions(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
+
+// DiffHomeworkRevisions 管理员对比作业的两个版本（?from=1&to=3）
+func DiffHomeworkRevisions(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil || from <= 0 || to <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	diff, errCode := service.DiffHomeworkRevisions(homeworkID, from, to)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, diff)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/pkg/spreadsheet"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 一键提醒的请求参数（不传请求体时用默认内容）
+type RemindMissingRequest struct {
+	Message string `json:"message" binding:"max=500"`
+}
+
+// 管理员查询作业的未交名单
+func ListMissingSubmissions(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	missing, errCode := service.ListMissingSubmissions(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, missing)
+}
+
+// 管理员导出作业的未交名单（CSV）
+func ExportMissingSubmissions(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	missing, errCode := service.ListMissingSubmissions(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	filename := fmt.Sprintf("missing-%d.csv", homeworkID)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Status(http.StatusOK)
+	_ = spreadsheet.WriteCSV(c.Writer, service.MissingSubmissionsCSV(missing))
+}
+
+// 管理员给未交的学生一键发提醒
+func RemindMissingStudents(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req RemindMissingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, errcode.ParamError)
+			return
+		}
+	}
+
+	result, errCode := service.RemindMissingStudents(adminID.(int64), homeworkID, req.Message)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, result)
+}
+
+// 学生查询收到的提醒
+func ListMyReminders(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	list, total, errCode := service.ListMyReminders(studentID.(int64), page, pageSize)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, response.PageResponse{
+		List:     list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// 学生把提醒全部标为已读
+func MarkRemindersRead(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	if errCode := service.MarkRemindersRead(studentID.(int64)); errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 开启互评的请求参数
+type StartPeerReviewRequest struct {
+	ReviewersPerSubmission int       `json:"reviewers_per_submission" binding:"required,min=1"` // 每份提交分给几个同学评
+	Deadline               time.Time `json:"deadline" binding:"required"`                       // 互评截止时间
+}
+
+// 提交互评的请求参数
+type SubmitPeerReviewRequest struct {
+	Score           *int                    `json:"score" binding:"omitempty,min=0"` // 分数（作业有评分标准时按criterion_scores打分）
+	Grade           string                  `json:"grade"`                           // 等级（等级制/通过制可代替score）
+	CriterionScores []CriterionScoreRequest `json:"criterion_scores" binding:"omitempty,dive"`
+	Comment         string                  `json:"comment"` // 评语
+}
+
+// 管理员开启作业互评
+func StartPeerReview(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req StartPeerReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	round, errCode := service.StartPeerReview(adminID.(int64), homeworkID, req.ReviewersPerSubmission, req.Deadline)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, round)
+}
+
+// 管理员提前结束互评
+func ClosePeerReview(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.ClosePeerReview(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// 管理员查看作业互评进度和汇总分
+func GetPeerReviewOverview(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	overview, errCode := service.GetPeerReviewOverview(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, overview)
+}
+
+// 学生查询自己的互评任务
+func ListMyPeerReviewTasks(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	tasks, errCode := service.ListMyPeerReviewTasks(studentID.(int64))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, tasks)
+}
+
+// 学生提交互评
+func SubmitPeerReview(c *gin.Context) {
+	assignmentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || assignmentID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req SubmitPeerReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	criterionScores := make([]service.CriterionScoreInput, 0, len(req.CriterionScores))
+	for _, cs := range req.CriterionScores {
+		criterionScores = append(criterionScores, service.CriterionScoreInput{
+			CriterionID: cs.CriterionID,
+			Score:       cs.Score,
+			Comment:     cs.Comment,
+		})
+	}
+	errCode := service.SubmitPeerReview(studentID.(int64), assignmentID, service.PeerReviewInput{
+		Score:           req.Score,
+		Grade:           req.Grade,
+		CriterionScores: criterionScores,
+		Comment:         req.Comment,
+	})
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// 查询提交收到的互评
+func GetSubmissionPeerReviews(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	summary, errCode := service.GetSubmissionPeerReviews(userID.(int64), role == "admin", subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, summary)
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 题目的请求参数
+type QuestionRequest struct {
+	Department  string   `json:"department" binding:"required,oneof=backend frontend sre product design android ios"` // 所属部门题库
+	Type        string   `json:"type" binding:"required,oneof=single multiple true_false fill"`                       // 题型
+	Stem        string   `json:"stem" binding:"required"`                                                             // 题干
+	Options     []string `json:"options"`                                                                             // 选项（选择题必填）
+	Answer      string   `json:"answer" binding:"required"`                                                           // 标准答案
+	Explanation string   `json:"explanation"`                                                                         // 解析
+	Points      int      `json:"points" binding:"required,min=1"`                                                     // 默认分值
+}
+
+// 组卷的请求参数
+type SaveQuizRequest struct {
+	Questions []QuizItemRequest `json:"questions" binding:"required,min=1,dive"` // 按顺序排列的题目
+	Shuffle   bool              `json:"shuffle"`                                 // 是否对每个学生打乱题目顺序
+}
+
+// 组卷中的一道题
+type QuizItemRequest struct {
+	QuestionID int64 `json:"question_id" binding:"required"`
+	Points     int   `json:"points" binding:"min=0"` // 本次测验中的分值（不传用题目默认分值）
+}
+
+// 提交测验的请求参数
+type SubmitQuizRequest struct {
+	HomeworkID int64               `json:"homework_id" binding:"required"`
+	Answers    []QuizAnswerRequest `json:"answers" binding:"dive"`
+}
+
+// 一道题的作答
+type QuizAnswerRequest struct {
+	QuestionID int64  `json:"question_id" binding:"required"`
+	Answer     string `json:"answer"` // 单选"B"，多选"A,C"，判断"true"/"false"，填空直接填写
+}
+
+// 管理员新增题目
+func CreateQuestion(c *gin.Context) {
+	creatorID, _ := c.Get("userID")
+	if creatorID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req QuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	question, errCode := service.CreateQuestion(toQuestionInput(req), creatorID.(int64))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, question)
+}
+
+// 管理员修改题目
+func UpdateQuestion(c *gin.Context) {
+	questionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || questionID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req QuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.UpdateQuestion(questionID, toQuestionInput(req))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "题目修改成功"})
+}
+
+// 管理员删除题目
+func DeleteQuestion(c *gin.Context) {
+	questionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || questionID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.DeleteQuestion(questionID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "题目删除成功"})
+}
+
+// 管理员查询题库（支持部门、题型筛选+分页）
+func ListQuestion(c *gin.Context) {
+	pageStr := c.DefaultQuery("page", "1")
+	pageSizeStr := c.DefaultQuery("page_size", "10")
+	page, _ := strconv.Atoi(pageStr)
+	pageSize, _ := strconv.Atoi(pageSizeStr)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	list, total, errCode := service.ListQuestion(c.Query("department"), c.Query("type"), page, pageSize)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	resp := response.PageResponse{
+		List:     list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	response.Success(c, resp)
+}
+
+// 管理员为作业组卷
+func SaveQuiz(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req SaveQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	items := make([]service.QuizItemInput, 0, len(req.Questions))
+	for _, q := range req.Questions {
+		items = append(items, service.QuizItemInput{QuestionID: q.QuestionID, Points: q.Points})
+	}
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	errCode = service.SaveQuiz(homeworkID, items, req.Shuffle, ifMatch)
+	if errCode == errcode.VersionConflict {
+		respondHomeworkConflict(c, homeworkID)
+		return
+	}
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "组卷成功"})
+}
+
+// 学生获取测验题目
+func GetQuiz(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	questions, errCode := service.GetQuiz(homeworkID, studentID.(int64))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, questions)
+}
+
+// 管理员查询测验每道题的正确率
+func GetQuizStats(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	stats, errCode := service.GetQuizStats(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, stats)
+}
+
+// 学生提交测验（自动判分）
+func SubmitQuiz(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req SubmitQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	answers := make([]service.QuizAnswerInput, 0, len(req.Answers))
+	for _, a := range req.Answers {
+		answers = append(answers, service.QuizAnswerInput{QuestionID: a.QuestionID, Answer: a.Answer})
+	}
+	result, errCode := service.SubmitQuiz(studentID.(int64), req.HomeworkID, answers)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, result)
+}
+
+// toQuestionInput 请求参数转service层入参
+func toQuestionInput(req QuestionRequest) service.QuestionInput {
+	return service.QuestionInput{
+		Department:  req.Department,
+		Type:        req.Type,
+		Stem:        req.Stem,
+		Options:     req.Options,
+		Answer:      req.Answer,
+		Explanation: req.Explanation,
+		Points:      req.Points,
+	}
+}
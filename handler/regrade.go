@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 申请复核的请求参数
+type CreateRegradeRequest struct {
+	Reason string `json:"reason" binding:"required"` // 申请理由
+}
+
+// 处理复核申请的请求参数
+type ResolveRegradeRequest struct {
+	Decision        string                  `json:"decision" binding:"required,oneof=keep change"` // keep：维持原分，change：改分
+	Score           *int                    `json:"score" binding:"omitempty,min=0"`               // 改分时的新分数（和批改一样，也可以传grade或criterion_scores）
+	Grade           string                  `json:"grade"`
+	CriterionScores []CriterionScoreRequest `json:"criterion_scores" binding:"omitempty,dive"`
+	Explanation     string                  `json:"explanation" binding:"required"` // 处理说明（学生可见）
+}
+
+// 学生对已批改的提交申请复核
+func CreateRegrade(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req CreateRegradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	request, errCode := service.CreateRegradeRequest(studentID.(int64), subID, req.Reason)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, request)
+}
+
+// 查询提交的复核申请和分数变动记录
+func GetRegradeHistory(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	history, errCode := service.GetRegradeHistory(userID.(int64), role == "admin", subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, history)
+}
+
+// 管理员查询部门的复核申请队列
+func ListRegradeQueue(c *gin.Context) {
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	// 筛选和分页参数（不传department时看自己所在部门）
+	department := c.Query("department")
+	status := c.Query("status")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	list, total, errCode := service.ListRegradeQueue(adminID.(int64), department, status, page, pageSize)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, response.PageResponse{
+		List:     list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// 管理员处理复核申请
+func ResolveRegrade(c *gin.Context) {
+	requestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || requestID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req ResolveRegradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	criterionScores := make([]service.CriterionScoreInput, 0, len(req.CriterionScores))
+	for _, cs := range req.CriterionScores {
+		criterionScores = append(criterionScores, service.CriterionScoreInput{
+			CriterionID: cs.CriterionID,
+			Score:       cs.Score,
+			Comment:     cs.Comment,
+		})
+	}
+	errCode := service.ResolveRegradeRequest(adminID.(int64), requestID, service.RegradeResolution{
+		Decision:        req.Decision,
+		Score:           req.Score,
+		Grade:           req.Grade,
+		CriterionScores: criterionScores,
+		Explanation:     req.Explanation,
+	})
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
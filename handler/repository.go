@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 提交仓库作业的请求参数
+type SubmitRepositoryRequest struct {
+	HomeworkID int64  `json:"homework_id" binding:"required"`
+	RepoURL    string `json:"repo_url" binding:"required,max=500"` // 仓库地址（https/ssh）
+	Ref        string `json:"ref" binding:"required,max=255"`      // 分支、标签或提交SHA
+	Note       string `json:"note"`                                // 提交说明（可选）
+}
+
+// 重新提交仓库作业的请求参数
+type ResubmitRepositoryRequest struct {
+	RepoURL string `json:"repo_url" binding:"required,max=500"`
+	Ref     string `json:"ref" binding:"required,max=255"`
+	Note    string `json:"note"`
+}
+
+// 学生提交仓库作业（克隆仓库并固定到ref当前指向的提交）
+func SubmitRepository(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req SubmitRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.SubmitRepository(studentID.(int64), req.HomeworkID, req.RepoURL, req.Ref, req.Note)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "提交成功"})
+}
+
+// 学生重新提交仓库作业（重新克隆，生成新版本）
+func ResubmitRepository(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req ResubmitRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.ResubmitRepository(studentID.(int64), subID, req.RepoURL, req.Ref, req.Note)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "重新提交成功"})
+}
+
+// 查询提交的仓库快照（提交SHA、时间和文件列表）
+func ListRepositorySnapshots(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	list, errCode := service.ListRepositorySnapshots(userID.(int64), role == "admin", subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
+
+// 查看仓库快照里的文件（不传snapshot_id时看当前版本）
+func GetRepositoryFile(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	path := c.Query("path")
+	if path == "" {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	var snapshotID int64
+	if v := c.Query("snapshot_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			response.Error(c, errcode.ParamError)
+			return
+		}
+		snapshotID = id
+	}
+
+	file, errCode := service.GetRepositoryFile(userID.(int64), role == "admin", subID, snapshotID, path)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, file)
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 自动分配批改人的请求参数
+type AssignReviewersRequest struct {
+	Strategy    string  `json:"strategy" binding:"required,oneof=round_robin load mentor"` // round_robin：轮流，load：按负载，mentor：按导师
+	ReviewerIDs []int64 `json:"reviewer_ids"`                                              // 参与分配的批改人（按导师分配时可不传，传了只分给其中的导师）
+}
+
+// 认领提交的请求参数
+type ClaimSubmissionRequest struct {
+	Force bool `json:"force"` // 接手别人的认领
+}
+
+// 设置导师的请求参数
+type SetMentorRequest struct {
+	MentorID   int64   `json:"mentor_id" binding:"required"`
+	StudentIDs []int64 `json:"student_ids" binding:"required,min=1"`
+}
+
+// 管理员把作业里还没人负责的提交分给批改人
+func AssignReviewers(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req AssignReviewersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	assigned, errCode := service.AssignReviewers(homeworkID, req.Strategy, req.ReviewerIDs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"assigned": assigned})
+}
+
+// 管理员获取作业里自己要批改的下一份提交（没有时返回空）
+func NextUnreviewed(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	reviewerID, _ := c.Get("userID")
+	if reviewerID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	next, errCode := service.NextUnreviewed(reviewerID.(int64), homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, next)
+}
+
+// 管理员认领提交
+func ClaimSubmission(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	reviewerID, _ := c.Get("userID")
+	if reviewerID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	// 请求体可以不传
+	var req ClaimSubmissionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, errcode.ParamError)
+			return
+		}
+	}
+
+	claim, errCode := service.ClaimSubmission(reviewerID.(int64), subID, req.Force)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, claim)
+}
+
+// 管理员放弃自己的认领
+func ReleaseClaim(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	reviewerID, _ := c.Get("userID")
+	if reviewerID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	errCode := service.ReleaseClaim(reviewerID.(int64), subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// 管理员查询自己名下还没批改的提交
+func ListMyClaims(c *gin.Context) {
+	reviewerID, _ := c.Get("userID")
+	if reviewerID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	// 可按作业筛选
+	homeworkID, _ := strconv.ParseInt(c.DefaultQuery("homework_id", "0"), 10, 64)
+
+	list, errCode := service.ListMyClaims(reviewerID.(int64), homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
+
+// 管理员设置导师负责的学生
+func SetMentor(c *gin.Context) {
+	var req SetMentorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.SetMentor(req.MentorID, req.StudentIDs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// 管理员查询导师关系
+func ListMentorships(c *gin.Context) {
+	// 可按导师筛选
+	mentorID, _ := strconv.ParseInt(c.DefaultQuery("mentor_id", "0"), 10, 64)
+
+	list, errCode := service.ListMentorships(mentorID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 设置评分标准的请求参数
+type SaveRubricRequest struct {
+	Criteria []CriterionRequest `json:"criteria" binding:"required,min=1,dive"` // 评分项（至少1个）
+}
+
+// 评分项
+type CriterionRequest struct {
+	Name        string         `json:"name" binding:"required,max=100"`     // 评分项名称
+	Description string         `json:"description"`                         // 评分项说明
+	MaxPoints   int            `json:"max_points" binding:"required,min=1"` // 该项满分
+	Levels      []LevelRequest `json:"levels" binding:"omitempty,dive"`     // 等级描述（可选）
+}
+
+// 等级描述
+type LevelRequest struct {
+	Points      int    `json:"points" binding:"min=0"`
+	Description string `json:"description" binding:"required"`
+}
+
+// 管理员设置作业的评分标准（整体替换）
+func SaveRubric(c *gin.Context) {
+	// 1. 获取作业ID
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	// 2. 校验参数
+	var req SaveRubricRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	// 3. 转换成service层入参
+	criteria := make([]service.CriterionInput, 0, len(req.Criteria))
+	for _, cr := range req.Criteria {
+		levels := make([]service.LevelInput, 0, len(cr.Levels))
+		for _, l := range cr.Levels {
+			levels = append(levels, service.LevelInput{Points: l.Points, Description: l.Description})
+		}
+		criteria = append(criteria, service.CriterionInput{
+			Name:        cr.Name,
+			Description: cr.Description,
+			MaxPoints:   cr.MaxPoints,
+			Levels:      levels,
+		})
+	}
+
+	// 4. 调用业务逻辑（只在If-Match的作业版本和当前一致时修改）
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	errCode = service.SaveRubric(homeworkID, criteria, ifMatch)
+	if errCode == errcode.VersionConflict {
+		respondHomeworkConflict(c, homeworkID)
+		return
+	}
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "评分标准设置成功"})
+}
+
+// 查询作业的评分标准（所有人可见，学生可据此了解评分要求）
+func GetRubric(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	rubric, errCode := service.GetRubric(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, rubric)
+}
+
+// 管理员删除作业的评分标准
+func DeleteRubric(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.DeleteRubric(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "评分标准删除成功"})
+}
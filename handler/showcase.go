@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 作品展同意的请求参数
+type SetShowcaseConsentRequest struct {
+	Consent   *bool `json:"consent" binding:"required"` // false为撤回同意
+	Anonymous bool  `json:"anonymous"`                  // 匿名展示（不显示姓名）
+}
+
+// 查询作品展（所有人可见，可按部门和作业筛选）
+func ListShowcase(c *gin.Context) {
+	// 1. 分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	// 2. 筛选参数：部门和作业（都可选）
+	department := c.Query("department")
+	if department != "" {
+		validDept := false
+		for _, d := range []string{"backend", "frontend", "sre", "product", "design", "android", "ios"} {
+			if department == d {
+				validDept = true
+				break
+			}
+		}
+		if !validDept {
+			response.Error(c, errcode.ParamError)
+			return
+		}
+	}
+	var homeworkID int64
+	if v := c.Query("homework_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			response.Error(c, errcode.ParamError)
+			return
+		}
+		homeworkID = id
+	}
+
+	// 3. 调用业务逻辑
+	list, total, errCode := service.ListShowcase(department, homeworkID, page, pageSize)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, response.PageResponse{
+		List:     list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// 学生查询自己提交的作品展同意情况
+func GetShowcaseConsent(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	state, errCode := service.GetShowcaseConsent(studentID.(int64), subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, state)
+}
+
+// 学生同意或撤回把自己的优秀作业放进作品展
+func SetShowcaseConsent(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req SetShowcaseConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	state, errCode := service.SetShowcaseConsent(studentID.(int64), subID, *req.Consent, req.Anonymous)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, state)
+}
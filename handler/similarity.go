@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 核查可疑提交对的请求参数
+type ReviewSimilarityRequest struct {
+	Status string `json:"status" binding:"required,oneof=reviewed acceptable"` // reviewed：已核查，acceptable：正常相似
+	Note   string `json:"note"`                                                // 核查备注
+}
+
+// 管理员查询作业的可疑提交对
+func ListSimilarityPairs(c *gin.Context) {
+	// 1. 获取作业ID
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	// 2. 筛选和分页参数
+	status := c.Query("status")
+	minScore, err := strconv.ParseFloat(c.DefaultQuery("min_score", "0"), 64)
+	if err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	// 3. 调用业务逻辑
+	list, total, errCode := service.ListSimilarityPairs(homeworkID, status, minScore, page, pageSize)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, response.PageResponse{
+		List:     list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// 管理员对整个作业重新查重
+func RescanHomeworkSimilarity(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	queued, errCode := service.RescanHomeworkSimilarity(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"queued": queued})
+}
+
+// 管理员查看可疑提交对详情（两份提交的原文和相同片段）
+func GetSimilarityPair(c *gin.Context) {
+	pairID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || pairID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	detail, errCode := service.GetSimilarityPair(pairID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, detail)
+}
+
+// 管理员标记可疑提交对
+func ReviewSimilarityPair(c *gin.Context) {
+	pairID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || pairID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req ReviewSimilarityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.ReviewSimilarityPair(pairID, adminID.(int64), req.Status, req.Note)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, nil)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"github.com/chuji555/homework-system/dao"
 	"github.com/chuji555/homework-system/pkg/errcode"
 	"github.com/chuji555/homework-system/pkg/response"
 	"github.com/chuji555/homework-system/service"
@@ -10,9 +11,10 @@ import (
 
 // 提交作业的请求参数
 type CreateSubmissionRequest struct {
-	HomeworkID int64  `json:"homework_id" binding:"required"` // 作业ID
-	Content    string `json:"content" binding:"required"`     // 提交内容
-	FileURL    string `json:"file_url"`                       // 附件URL（可选）
+	HomeworkID int64   `json:"homework_id" binding:"required"` // 作业ID
+	Content    string  `json:"content" binding:"required"`     // 提交内容
+	FileURL    string  `json:"file_url"`                       // 附件URL（可选）
+	FileIDs    []int64 `json:"file_ids"`                       // 已上传文件的ID（可选）
 }
 
 // 学生提交作业
@@ -37,6 +39,7 @@ func CreateSubmission(c *gin.Context) {
 		req.HomeworkID,
 		req.Content,
 		req.FileURL,
+		req.FileIDs,
 	)
 	if errCode != errcode.Success {
 		response.Error(c, errCode)
@@ -79,7 +82,23 @@ func ListMySubmission(c *gin.Context) {
 	response.Success(c, resp)
 }
 
-// 管理员根据作业ID查询提交记录
+// 管理员查询提交列表的查询参数（筛选条件都可选）
+type ListSubmissionQuery struct {
+	Page       int    `form:"page,default=1" binding:"min=1"`
+	PageSize   int    `form:"page_size,default=10" binding:"min=1,max=100"`
+	Status     string `form:"status" binding:"omitempty,oneof=reviewed unreviewed"` // 已批改/未批改
+	Late       *bool  `form:"late"`                                                 // 是否迟交
+	Excellent  *bool  `form:"excellent"`                                            // 是否优秀
+	MinScore   *int   `form:"min_score" binding:"omitempty,min=0"`                  // 分数范围（按作业评分制的原始分数）
+	MaxScore   *int   `form:"max_score" binding:"omitempty,min=0"`
+	ReviewerID int64  `form:"reviewer_id" binding:"omitempty,min=1"` // 批改人
+	Keyword    string `form:"keyword" binding:"max=50"`              // 学生用户名或昵称（匿名批改期间不能用）
+	// 排序字段（默认submitted_at）和方向（默认desc，unreviewed_first默认asc）
+	Sort  string `form:"sort" binding:"omitempty,oneof=submitted_at score reviewed_at unreviewed_first"`
+	Order string `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+// 管理员根据作业ID查询提交记录（支持筛选和排序）
 func ListSubmissionByHomework(c *gin.Context) {
 	// 1. 获取作业ID
 	homeworkIDStr := c.Param("homework_id")
@@ -89,14 +108,30 @@ func ListSubmissionByHomework(c *gin.Context) {
 		return
 	}
 
-	// 2. 分页参数
-	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "10")
-	page, _ := strconv.Atoi(pageStr)
-	pageSize, _ := strconv.Atoi(pageSizeStr)
+	// 2. 分页、筛选和排序参数
+	var query ListSubmissionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	filter := dao.SubmissionFilter{
+		Late:       query.Late,
+		Excellent:  query.Excellent,
+		MinScore:   query.MinScore,
+		MaxScore:   query.MaxScore,
+		ReviewerID: query.ReviewerID,
+		Keyword:    query.Keyword,
+		Sort:       query.Sort,
+		// 默认新提交的在前；未批改优先时默认先到先批
+		Desc: query.Order == "desc" || (query.Order == "" && query.Sort != dao.SubmissionSortUnreviewedFirst),
+	}
+	if query.Status != "" {
+		reviewed := query.Status == "reviewed"
+		filter.Reviewed = &reviewed
+	}
 
 	// 3. 调用业务逻辑
-	list, total, errCode := service.ListSubmissionByHomework(homeworkID, page, pageSize)
+	list, total, errCode := service.ListSubmissionByHomework(homeworkID, filter, query.Page, query.PageSize)
 	if errCode != errcode.Success {
 		response.Error(c, errCode)
 		return
@@ -105,16 +140,26 @@ func ListSubmissionByHomework(c *gin.Context) {
 	resp := response.PageResponse{
 		List:     list,
 		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
+		Page:     query.Page,
+		PageSize: query.PageSize,
 	}
 	response.Success(c, resp)
 }
 
 // 管理员批改作业
 type ReviewSubmissionRequest struct {
-	Score   int    `json:"score" binding:"required,min=0,max=100"` // 分数0-100
-	Comment string `json:"comment"`                                // 批改评语
+	Score           *int                    `json:"score" binding:"omitempty,min=0"`           // 分数（范围由作业评分制决定，百分制0-100、十分制0-10）
+	Grade           string                  `json:"grade"`                                     // 等级（等级制填A-F，通过制填pass/fail，可代替score）
+	CriterionScores []CriterionScoreRequest `json:"criterion_scores" binding:"omitempty,dive"` // 分项得分（作业有评分标准时必填）
+	Comment         string                  `json:"comment"`                                   // 批改评语
+	Override        bool                    `json:"override"`                                  // 接手别人认领或批改过的提交
+}
+
+// 单个评分项的得分
+type CriterionScoreRequest struct {
+	CriterionID int64  `json:"criterion_id" binding:"required"`
+	Score       int    `json:"score" binding:"min=0"`
+	Comment     string `json:"comment"`
 }
 
 func ReviewSubmission(c *gin.Context) {
@@ -133,15 +178,32 @@ func ReviewSubmission(c *gin.Context) {
 		return
 	}
 
-	// 3. 校验参数
+	// 3. 校验参数（只在If-Match的版本和当前一致时批改）
 	var req ReviewSubmissionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, errcode.ParamError)
 		return
 	}
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
 
 	// 4. 调用业务逻辑
-	errCode := service.ReviewSubmission(subID, req.Score, req.Comment, reviewerID.(int64))
+	criterionScores := make([]service.CriterionScoreInput, 0, len(req.CriterionScores))
+	for _, cs := range req.CriterionScores {
+		criterionScores = append(criterionScores, service.CriterionScoreInput{
+			CriterionID: cs.CriterionID,
+			Score:       cs.Score,
+			Comment:     cs.Comment,
+		})
+	}
+	errCode = service.ReviewSubmission(subID, req.Score, req.Grade, criterionScores, req.Comment, reviewerID.(int64), req.Override, ifMatch)
+	if errCode == errcode.VersionConflict {
+		respondSubmissionConflict(c, reviewerID.(int64), true, subID)
+		return
+	}
 	if errCode != errcode.Success {
 		response.Error(c, errCode)
 		return
@@ -152,7 +214,8 @@ func ReviewSubmission(c *gin.Context) {
 
 // 管理员标记优秀作业
 type MarkExcellentRequest struct {
-	IsExcellent bool `json:"is_excellent" binding:"required"`
+	IsExcellent *bool  `json:"is_excellent" binding:"required"`
+	Reason      string `json:"reason" binding:"max=500"` // 推荐理由（展示在作品展里）
 }
 
 func MarkExcellent(c *gin.Context) {
@@ -164,15 +227,27 @@ func MarkExcellent(c *gin.Context) {
 		return
 	}
 
-	// 2. 校验参数
+	// 2. 校验参数（只在If-Match的版本和当前一致时修改）
 	var req MarkExcellentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, errcode.ParamError)
 		return
 	}
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
 
 	// 3. 调用业务逻辑
-	errCode := service.MarkExcellent(subID, req.IsExcellent)
+	errCode = service.MarkExcellent(subID, *req.IsExcellent, req.Reason, ifMatch)
+	if errCode == errcode.VersionConflict {
+		adminID, _ := c.Get("userID")
+		if adminID != nil {
+			respondSubmissionConflict(c, adminID.(int64), true, subID)
+			return
+		}
+	}
 	if errCode != errcode.Success {
 		response.Error(c, errCode)
 		return
@@ -181,26 +256,118 @@ func MarkExcellent(c *gin.Context) {
 	response.Success(c, gin.H{"msg": "标记成功"})
 }
 
-// 查询优秀作业（所有人可见）
-func ListExcellentSubmission(c *gin.Context) {
-	// 1. 分页参数
-	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "10")
-	page, _ := strconv.Atoi(pageStr)
-	pageSize, _ := strconv.Atoi(pageSizeStr)
+// 查询单个提交（管理员或提交者本人），版本号放在ETag里
+func GetSubmission(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
 
-	// 2. 调用业务逻辑
-	list, total, errCode := service.ListExcellentSubmission(page, pageSize)
+	sub, errCode := service.GetSubmission(userID.(int64), role == "admin", subID)
 	if errCode != errcode.Success {
 		response.Error(c, errCode)
 		return
 	}
+	setETag(c, sub.Version)
+	response.Success(c, sub)
+}
 
-	resp := response.PageResponse{
-		List:     list,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
+// 重新提交的请求参数
+type ResubmitRequest struct {
+	Content string  `json:"content" binding:"required"` // 新版本的提交内容
+	FileURL string  `json:"file_url"`                   // 附件URL（可选）
+	FileIDs []int64 `json:"file_ids"`                   // 已上传文件的ID（可沿用之前版本的文件）
+}
+
+// 学生重新提交作业（生成新版本）
+func ResubmitSubmission(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
 	}
-	response.Success(c, resp)
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req ResubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.ResubmitSubmission(studentID.(int64), subID, req.Content, req.FileURL, req.FileIDs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "重新提交成功"})
+}
+
+// 学生撤回提交（截止前）
+func WithdrawSubmission(c *gin.Context) {
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.WithdrawSubmission(studentID.(int64), subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "撤回成功"})
+}
+
+// 管理员查看提交的历史版本
+func ListSubmissionVersions(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	list, errCode := service.ListSubmissionVersions(subID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
+
+// 管理员对比提交的两个版本（?from=1&to=2）
+func DiffSubmissionVersions(c *gin.Context) {
+	subID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil || from <= 0 || to <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	diff, errCode := service.DiffSubmissionVersions(subID, from, to)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, diff)
 }
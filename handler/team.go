@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 小组作业设置的请求参数
+type GroupSettingsRequest struct {
+	IsGroup           bool `json:"is_group"`                      // 是否为小组作业
+	MinTeamSize       int  `json:"min_team_size" binding:"min=0"` // 每组最少人数（提交时校验）
+	MaxTeamSize       int  `json:"max_team_size" binding:"min=0"` // 每组最多人数
+	AllowStudentTeams bool `json:"allow_student_teams"`           // 是否允许学生自行组队
+}
+
+// 创建小组的请求参数
+type CreateTeamRequest struct {
+	Name      string  `json:"name" binding:"required,max=100"` // 小组名称
+	MemberIDs []int64 `json:"member_ids"`                      // 组员ID（管理员必填，第一个为组长；学生自行组队时忽略）
+}
+
+// 调整小组成员的请求参数
+type ReplaceTeamMembersRequest struct {
+	MemberIDs []int64 `json:"member_ids" binding:"required,min=1"`
+}
+
+// 组员个人分数调整的请求参数
+type MemberAdjustmentRequest struct {
+	StudentID  int64 `json:"student_id" binding:"required"`
+	Adjustment int   `json:"adjustment"` // 在小组得分基础上加减的分数
+}
+
+// 管理员设置作业的小组模式
+func UpdateGroupSettings(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req GroupSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	errCode = service.UpdateGroupSettings(homeworkID, req.IsGroup, req.MinTeamSize, req.MaxTeamSize, req.AllowStudentTeams, ifMatch)
+	if errCode == errcode.VersionConflict {
+		respondHomeworkConflict(c, homeworkID)
+		return
+	}
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "小组设置成功"})
+}
+
+// 创建小组（管理员指定组员，学生自行组队）
+func CreateTeam(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	team, errCode := service.CreateTeam(homeworkID, req.Name, userID.(int64), role == "admin", req.MemberIDs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, team)
+}
+
+// 管理员查询作业下的全部小组
+func ListTeam(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	list, errCode := service.ListTeam(homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
+
+// 学生查询自己在某个作业下的小组
+func GetMyTeam(c *gin.Context) {
+	homeworkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || homeworkID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	team, errCode := service.GetMyTeam(studentID.(int64), homeworkID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, team)
+}
+
+// 管理员调整小组成员
+func ReplaceTeamMembers(c *gin.Context) {
+	teamID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || teamID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req ReplaceTeamMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.ReplaceTeamMembers(teamID, req.MemberIDs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "组员调整成功"})
+}
+
+// 管理员解散小组
+func DeleteTeam(c *gin.Context) {
+	teamID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || teamID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.DeleteTeam(teamID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "小组已解散"})
+}
+
+// 管理员设置组员的个人分数调整（If-Match为小组提交的版本号）
+func SetMemberAdjustment(c *gin.Context) {
+	teamID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || teamID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	adminID, _ := c.Get("userID")
+	if adminID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req MemberAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	ifMatch, errCode := ifMatchVersion(c)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+
+	subID, errCode := service.SetMemberAdjustment(teamID, req.StudentID, req.Adjustment, adminID.(int64), ifMatch)
+	if errCode == errcode.VersionConflict {
+		respondSubmissionConflict(c, adminID.(int64), true, subID)
+		return
+	}
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "分数调整成功"})
+}
+
+// 学生加入小组
+func JoinTeam(c *gin.Context) {
+	teamID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || teamID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	errCode := service.JoinTeam(teamID, studentID.(int64))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "加入成功"})
+}
+
+// 学生退出小组
+func LeaveTeam(c *gin.Context) {
+	teamID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || teamID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	studentID, _ := c.Get("userID")
+	if studentID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	errCode := service.LeaveTeam(teamID, studentID.(int64))
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "已退出小组"})
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 创建学习路线的请求参数
+type CreateTrackRequest struct {
+	Name        string  `json:"name" binding:"required,max=100"`                                                     // 路线名称
+	Description string  `json:"description"`                                                                         // 路线说明
+	Department  string  `json:"department" binding:"required,oneof=backend frontend sre product design android ios"` // 所属部门
+	HomeworkIDs []int64 `json:"homework_ids" binding:"required,min=1"`                                               // 按顺序排列的作业ID
+}
+
+// 修改学习路线的请求参数
+type UpdateTrackRequest struct {
+	Name        string  `json:"name" binding:"omitempty,max=100"`
+	Description string  `json:"description"`
+	HomeworkIDs []int64 `json:"homework_ids" binding:"required,min=1"`
+}
+
+// 管理员创建学习路线
+func CreateTrack(c *gin.Context) {
+	creatorID, _ := c.Get("userID")
+	if creatorID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	var req CreateTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	track, errCode := service.CreateTrack(req.Name, req.Description, req.Department, creatorID.(int64), req.HomeworkIDs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"id": track.ID})
+}
+
+// 管理员修改学习路线
+func UpdateTrack(c *gin.Context) {
+	trackID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	var req UpdateTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.UpdateTrack(trackID, req.Name, req.Description, req.HomeworkIDs)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "路线修改成功"})
+}
+
+// 管理员删除学习路线
+func DeleteTrack(c *gin.Context) {
+	trackID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	errCode := service.DeleteTrack(trackID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, gin.H{"msg": "路线删除成功"})
+}
+
+// 查询学习路线列表（学生只能看到本部门的路线）
+func ListTrack(c *gin.Context) {
+	department := c.Query("department")
+	if role, _ := c.Get("role"); role == "student" {
+		dept, _ := c.Get("department")
+		department, _ = dept.(string)
+	}
+
+	list, errCode := service.ListTrack(department)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, list)
+}
+
+// 查询学习路线详情
+func GetTrack(c *gin.Context) {
+	trackID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+
+	track, errCode := service.GetTrack(trackID)
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, track)
+}
+
+// 查询学习进度（管理员看全部门学生，学生只看自己）
+func GetTrackProgress(c *gin.Context) {
+	trackID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		response.Error(c, errcode.ParamError)
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if userID == nil {
+		response.Error(c, errcode.AuthError)
+		return
+	}
+
+	progress, errCode := service.GetTrackProgress(trackID, userID.(int64), role == "admin")
+	if errCode != errcode.Success {
+		response.Error(c, errCode)
+		return
+	}
+	response.Success(c, progress)
+}
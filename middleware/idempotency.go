@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/response"
+	"github.com/chuji555/homework-system/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求体上限（带幂等键的都是JSON接口）
+const idempotencyMaxBody = 8 << 20
+
+// IdempotencyMiddleware：POST请求带了Idempotency-Key时，同一个key重试直接返回第一次成功的响应
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(key) > 128 {
+			response.Error(c, errcode.ParamError)
+			c.Abort()
+			return
+		}
+
+		// 1. 读出请求体算哈希，再放回去给后面的handler用
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, idempotencyMaxBody+1))
+		if err != nil || len(body) > idempotencyMaxBody {
+			response.Error(c, errcode.ParamError)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+
+		// 2. 登录后的接口按用户区分key（同一个key换了请求内容视为冲突）；
+		//    未登录的接口（注册）用户ID都是0，key再加上请求指纹（方法+路径+请求体哈希），
+		//    不同客户端碰巧用了同一个key也拿不到别人的响应
+		var userID int64
+		if id, exists := c.Get("userID"); exists {
+			userID = id.(int64)
+		} else {
+			fingerprint := sha256.Sum256([]byte(key + "\n" + c.Request.Method + "\n" + c.Request.URL.Path + "\n" + bodyHash))
+			key = "anon:" + hex.EncodeToString(fingerprint[:])
+		}
+		record, replay, errCode := service.BeginIdempotentRequest(userID, key, c.Request.URL.Path, bodyHash)
+		if errCode != errcode.Success {
+			response.Error(c, errCode)
+			c.Abort()
+			return
+		}
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.Status, record.ContentType, []byte(record.ResponseBody))
+			c.Abort()
+			return
+		}
+
+		// 3. 执行请求并记下响应；handler异常（panic）时也要释放key
+		writer := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = writer
+		finished := false
+		defer func() {
+			if !finished {
+				service.FinishIdempotentRequest(record.ID, false, 0, "", "")
+			}
+		}()
+		c.Next()
+		finished = true
+		service.FinishIdempotentRequest(record.ID, succeeded(writer), writer.Status(), writer.Header().Get("Content-Type"), writer.body.String())
+	}
+}
+
+// bodyRecorder 把写给客户端的响应体同时留一份
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// succeeded 业务是否成功（HTTP状态2xx且响应里的code为0）
+func succeeded(w *bodyRecorder) bool {
+	if w.Status() < 200 || w.Status() >= 300 {
+		return false
+	}
+	var resp struct {
+		Code errcode.ErrCode `json:"code"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &resp); err != nil {
+		return false
+	}
+	return resp.Code == errcode.Success
+}
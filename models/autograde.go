@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// 测试用例类型
+type TestCaseKind string
+
+const (
+	TestCaseIO     TestCaseKind = "io"      // 标准输入/期望输出
+	TestCaseGoTest TestCaseKind = "go_test" // 隐藏的Go测试文件（和学生代码放在同一个包里跑go test）
+)
+
+// 自动评测任务状态
+type GradeJobStatus string
+
+const (
+	GradeJobPending GradeJobStatus = "pending"
+	GradeJobRunning GradeJobStatus = "running"
+	GradeJobDone    GradeJobStatus = "done"
+	GradeJobFailed  GradeJobStatus = "failed" // 评测系统自身出错（不是学生代码没通过）
+)
+
+// 编程作业的测试用例
+type TestCase struct {
+	ID             int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID     int64        `gorm:"not null;index" json:"homework_id"`
+	Name           string       `gorm:"size:100;not null" json:"name"`
+	Kind           TestCaseKind `gorm:"type:enum('io','go_test');not null" json:"kind"`
+	Input          string       `gorm:"type:text" json:"input"`           // io：标准输入
+	ExpectedOutput string       `gorm:"type:text" json:"expected_output"` // io：期望输出（比较时忽略首尾空白）
+	TestSource     string       `gorm:"type:text" json:"test_source"`     // go_test：测试文件源码
+	Points         int          `gorm:"not null;default:1" json:"points"`
+	Position       int          `gorm:"not null" json:"position"`
+}
+
+// 自动评测任务（每份提交一个，批改人可以看到结果和建议分）
+type GradeJob struct {
+	ID             int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID   int64          `gorm:"not null;uniqueIndex" json:"submission_id"`
+	Status         GradeJobStatus `gorm:"type:enum('pending','running','done','failed');not null;index" json:"status"`
+	PassedPoints   int            `gorm:"not null;default:0" json:"passed_points"`
+	TotalPoints    int            `gorm:"not null;default:0" json:"total_points"`
+	SuggestedScore *int           `json:"suggested_score,omitempty"` // 按作业评分制换算的建议分
+	Log            string         `gorm:"type:text" json:"log"`      // 编译错误或评测系统错误信息
+	StartedAt      *time.Time     `json:"started_at,omitempty"`
+	FinishedAt     *time.Time     `json:"finished_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	// 关联每个用例的结果
+	Results []TestResult `gorm:"foreignKey:JobID" json:"results"`
+}
+
+// 单个用例的评测结果
+type TestResult struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	JobID      int64  `gorm:"not null;index" json:"job_id"`
+	TestCaseID int64  `gorm:"not null" json:"test_case_id"`
+	Name       string `gorm:"size:100" json:"name"`
+	Passed     bool   `gorm:"not null" json:"passed"`
+	Points     int    `gorm:"not null" json:"points"`
+	Output     string `gorm:"type:text" json:"output"` // 实际输出（已截断）
+	Message    string `gorm:"size:500" json:"message"` // 失败原因，如超时、运行错误、输出不一致
+	DurationMs int64  `json:"duration_ms"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// 匿名批改期间查看学生真实身份的记录
+type DeanonymizationLog struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID   int64     `gorm:"not null;index" json:"homework_id"`
+	SubmissionID *int64    `json:"submission_id,omitempty"` // 为空表示关闭了整个作业的匿名批改
+	AdminID      int64     `gorm:"not null" json:"admin_id"`
+	Reason       string    `gorm:"type:text;not null" json:"reason"`
+	CreatedAt    time.Time `json:"created_at"`
+	// 关联操作人
+	Admin User `gorm:"foreignKey:AdminID" json:"admin,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// 日历订阅令牌（日历App无法带Bearer头，凭URL里的令牌访问）
+type CalendarToken struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int64     `gorm:"not null;uniqueIndex" json:"user_id"`
+	Token     string    `gorm:"size:64;not null;uniqueIndex" json:"-"` // 令牌本身不随JSON返回
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 个人截止时间延期（管理员给某个学生单独延期）
+type DeadlineExtension struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64     `gorm:"not null;uniqueIndex:idx_extension_homework_student" json:"homework_id"`
+	StudentID  int64     `gorm:"not null;uniqueIndex:idx_extension_homework_student" json:"student_id"`
+	Deadline   time.Time `gorm:"not null" json:"deadline"`
+	Reason     string    `gorm:"size:500" json:"reason"`
+	GrantedBy  int64     `gorm:"not null" json:"granted_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
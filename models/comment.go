@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// 提交下的讨论（批改人和学生互相回复，可以针对提交内容的某几行）
+type SubmissionComment struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID int64     `gorm:"not null;index" json:"submission_id"`
+	ParentID     *int64    `gorm:"index" json:"parent_id,omitempty"` // 回复的哪条评论（顶层评论为空）
+	AuthorID     int64     `gorm:"not null" json:"author_id"`
+	Content      string    `gorm:"type:text;not null" json:"content"`
+	Version      int       `gorm:"not null" json:"version"`            // 评论时提交的版本（行号针对这个版本）
+	LineStart    *int      `json:"line_start,omitempty"`               // 锚定的起始行（从1开始）
+	LineEnd      *int      `json:"line_end,omitempty"`                 // 锚定的结束行（含）
+	Private      bool      `gorm:"default:false;index" json:"private"` // 批改人的私有备注，学生看不到
+	CreatedAt    time.Time `json:"created_at"`
+	// 关联作者
+	Author User `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+	// 回复（查询时组装，不落库）
+	Replies []SubmissionComment `gorm:"-" json:"replies,omitempty"`
+	// 当前用户是否未读（不落库）
+	Unread bool `gorm:"-" json:"unread"`
+}
+
+// 用户在提交讨论中读到的位置（评论ID自增，读到的最大ID之前的都算已读）
+type SubmissionCommentRead struct {
+	ID                int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID      int64     `gorm:"not null;uniqueIndex:idx_comment_read_submission_user" json:"submission_id"`
+	UserID            int64     `gorm:"not null;uniqueIndex:idx_comment_read_submission_user" json:"user_id"`
+	LastReadCommentID int64     `gorm:"not null;default:0" json:"last_read_comment_id"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
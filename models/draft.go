@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// 作业草稿（每个学生每个作业一份，前端定时自动保存；不算提交，批改人看不到）
+type SubmissionDraft struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64     `gorm:"not null;uniqueIndex:idx_draft_homework_student" json:"homework_id"`
+	StudentID  int64     `gorm:"not null;uniqueIndex:idx_draft_homework_student" json:"student_id"`
+	Content    string    `gorm:"type:text" json:"content"`
+	FileURL    string    `gorm:"size:500" json:"file_url"`
+	FileIDs    IDList    `gorm:"type:text" json:"file_ids"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"` // 最后一次自动保存的时间
+}
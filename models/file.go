@@ -0,0 +1,47 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ID列表（以JSON存一列）
+type IDList []int64
+
+func (l IDList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+func (l *IDList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case nil:
+		*l = nil
+		return nil
+	default:
+		return errors.New("IDList: 不支持的数据类型")
+	}
+}
+
+// 上传的文件（先上传，提交作业时再关联到提交记录；关联后不再修改或删除）
+type StoredFile struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UploaderID   int64     `gorm:"not null;index" json:"uploader_id"`
+	HomeworkID   int64     `gorm:"not null;index" json:"homework_id"`
+	SubmissionID *int64    `gorm:"index" json:"submission_id,omitempty"` // 未关联时为空
+	Name         string    `gorm:"size:255;not null" json:"name"`        // 原始文件名
+	Size         int64     `gorm:"not null" json:"size"`
+	ContentType  string    `gorm:"size:100;not null" json:"content_type"` // 服务端根据内容识别的类型
+	SHA256       string    `gorm:"size:64;not null" json:"sha256"`
+	StorageKey   string    `gorm:"size:255;not null;uniqueIndex" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
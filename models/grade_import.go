@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 批量导入时写入的一行成绩
+type GradeImportRow struct {
+	Line         int    `json:"line"` // 表格里的行号
+	SubmissionID int64  `json:"submission_id"`
+	OldScore     *int   `json:"old_score,omitempty"`
+	NewScore     int    `json:"new_score"`
+	Comment      string `json:"comment,omitempty"`
+	Excellent    *bool  `json:"excellent,omitempty"` // 为空表示没改优秀标记
+}
+
+type GradeImportRows []GradeImportRow
+
+func (r GradeImportRows) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(r)
+	return string(b), err
+}
+
+func (r *GradeImportRows) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	case nil:
+		*r = nil
+		return nil
+	default:
+		return errors.New("GradeImportRows: 不支持的数据类型")
+	}
+}
+
+// 批量导入成绩的审计记录（每次正式导入一条，预览不记录）
+type GradeImport struct {
+	ID         int64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64           `gorm:"not null;index" json:"homework_id"`
+	ImporterID int64           `gorm:"not null" json:"importer_id"`
+	FileName   string          `gorm:"size:255" json:"file_name"`
+	Format     string          `gorm:"size:10;not null" json:"format"` // csv或xlsx
+	RowCount   int             `gorm:"not null" json:"row_count"`
+	Rows       GradeImportRows `gorm:"type:mediumtext" json:"rows,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	// 关联导入人
+	Importer User `gorm:"foreignKey:ImporterID" json:"importer,omitempty"`
+}
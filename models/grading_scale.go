@@ -0,0 +1,174 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 评分制（决定Submission.Score的取值范围和展示方式）
+type GradingScale string
+
+const (
+	ScalePercentage GradingScale = "percentage" // 百分制 0-100
+	ScaleTenPoint   GradingScale = "ten_point"  // 十分制 0-10
+	ScaleLetter     GradingScale = "letter"     // 等级制 A/B/C/D/F
+	ScalePassFail   GradingScale = "pass_fail"  // 通过/不通过
+)
+
+// 等级制的存储值：F=0 ... A=4
+var letterGrades = []string{"F", "D", "C", "B", "A"}
+
+// 等级制换算成百分比时使用的代表分（取各档中间值）
+var letterPercentages = []float64{50, 65, 75, 85, 95}
+
+// 数据库里没填时按百分制处理（兼容旧数据）
+func (s GradingScale) normalize() GradingScale {
+	if s == "" {
+		return ScalePercentage
+	}
+	return s
+}
+
+// IsValid 是否为支持的评分制
+func (s GradingScale) IsValid() bool {
+	switch s.normalize() {
+	case ScalePercentage, ScaleTenPoint, ScaleLetter, ScalePassFail:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsNumeric 是否为数值型评分制（只有数值型才能配评分标准）
+func (s GradingScale) IsNumeric() bool {
+	switch s.normalize() {
+	case ScalePercentage, ScaleTenPoint:
+		return true
+	default:
+		return false
+	}
+}
+
+// MaxScore 该评分制下Score的最大存储值
+func (s GradingScale) MaxScore() int {
+	switch s.normalize() {
+	case ScaleTenPoint:
+		return 10
+	case ScaleLetter:
+		return len(letterGrades) - 1
+	case ScalePassFail:
+		return 1
+	default:
+		return 100
+	}
+}
+
+// ValidScore 分数是否在该评分制的取值范围内
+func (s GradingScale) ValidScore(score int) bool {
+	return score >= 0 && score <= s.MaxScore()
+}
+
+// ParseGrade 把等级（如"A"、"pass"）转换成存储值，数值型评分制直接解析数字
+func (s GradingScale) ParseGrade(grade string) (int, bool) {
+	grade = strings.TrimSpace(grade)
+	switch s.normalize() {
+	case ScaleLetter:
+		for i, g := range letterGrades {
+			if strings.EqualFold(g, grade) {
+				return i, true
+			}
+		}
+		return 0, false
+	case ScalePassFail:
+		switch strings.ToLower(grade) {
+		case "pass":
+			return 1, true
+		case "fail":
+			return 0, true
+		}
+		return 0, false
+	default:
+		score, err := strconv.Atoi(grade)
+		if err != nil || !s.ValidScore(score) {
+			return 0, false
+		}
+		return score, true
+	}
+}
+
+// Display 分数的展示文本（如"8/10"、"B"、"通过"）
+func (s GradingScale) Display(score int) string {
+	switch s.normalize() {
+	case ScaleTenPoint:
+		return strconv.Itoa(score) + "/10"
+	case ScaleLetter:
+		if score < 0 || score >= len(letterGrades) {
+			return ""
+		}
+		return letterGrades[score]
+	case ScalePassFail:
+		if score > 0 {
+			return "通过"
+		}
+		return "不通过"
+	default:
+		return strconv.Itoa(score)
+	}
+}
+
+// Percentage 换算成百分比（统计用）
+func (s GradingScale) Percentage(score int) float64 {
+	switch s.normalize() {
+	case ScaleLetter:
+		if score < 0 || score >= len(letterPercentages) {
+			return 0
+		}
+		return letterPercentages[score]
+	default:
+		return float64(score) * 100 / float64(s.MaxScore())
+	}
+}
+
+// FromPercentage 把百分比换算成该评分制下的存储值（自动判分用）
+func (s GradingScale) FromPercentage(percent float64) int {
+	switch s.normalize() {
+	case ScaleLetter:
+		// 90以上A，80以上B，70以上C，60以上D，其余F
+		switch {
+		case percent >= 90:
+			return 4
+		case percent >= 80:
+			return 3
+		case percent >= 70:
+			return 2
+		case percent >= 60:
+			return 1
+		default:
+			return 0
+		}
+	case ScalePassFail:
+		if percent >= 60 {
+			return 1
+		}
+		return 0
+	default:
+		return int(math.Round(percent * float64(s.MaxScore()) / 100))
+	}
+}
+
+// Label 评分制中文名
+func (s GradingScale) Label() string {
+	switch s.normalize() {
+	case ScalePercentage:
+		return "百分制"
+	case ScaleTenPoint:
+		return "十分制"
+	case ScaleLetter:
+		return "等级制"
+	case ScalePassFail:
+		return "通过制"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,111 @@
+package models
+
+import "testing"
+
+func TestFromPercentage(t *testing.T) {
+	tests := []struct {
+		scale   GradingScale
+		percent float64
+		want    int
+	}{
+		{ScalePercentage, 0, 0},
+		{ScalePercentage, 59.4, 59},
+		{ScalePercentage, 59.5, 60},
+		{ScalePercentage, 100, 100},
+		{"", 87.5, 88}, // 没填按百分制
+		{ScaleTenPoint, 0, 0},
+		{ScaleTenPoint, 84, 8},
+		{ScaleTenPoint, 85, 9},
+		{ScaleTenPoint, 100, 10},
+		{ScaleLetter, 100, 4},
+		{ScaleLetter, 90, 4},
+		{ScaleLetter, 89.99, 3},
+		{ScaleLetter, 80, 3},
+		{ScaleLetter, 79.99, 2},
+		{ScaleLetter, 70, 2},
+		{ScaleLetter, 69.99, 1},
+		{ScaleLetter, 60, 1},
+		{ScaleLetter, 59.99, 0},
+		{ScaleLetter, 0, 0},
+		{ScalePassFail, 60, 1},
+		{ScalePassFail, 59.99, 0},
+		{ScalePassFail, 100, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.scale.FromPercentage(tt.percent); got != tt.want {
+			t.Errorf("%s.FromPercentage(%v) = %d, want %d", tt.scale, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestParseGradeAndDisplay(t *testing.T) {
+	tests := []struct {
+		scale   GradingScale
+		grade   string
+		want    int
+		ok      bool
+		display string
+	}{
+		{ScaleLetter, "A", 4, true, "A"},
+		{ScaleLetter, " b ", 3, true, "B"},
+		{ScaleLetter, "f", 0, true, "F"},
+		{ScaleLetter, "E", 0, false, ""},
+		{ScaleLetter, "A+", 0, false, ""},
+		{ScalePassFail, "PASS", 1, true, "通过"},
+		{ScalePassFail, "fail", 0, true, "不通过"},
+		{ScalePassFail, "1", 0, false, ""},
+		{ScaleTenPoint, "8", 8, true, "8/10"},
+		{ScaleTenPoint, "11", 0, false, ""},
+		{ScalePercentage, "100", 100, true, "100"},
+		{ScalePercentage, "-1", 0, false, ""},
+		{ScalePercentage, "90.5", 0, false, ""},
+	}
+	for _, tt := range tests {
+		got, ok := tt.scale.ParseGrade(tt.grade)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s.ParseGrade(%q) = %d, %v; want %d, %v", tt.scale, tt.grade, got, ok, tt.want, tt.ok)
+		}
+		if ok && tt.scale.Display(got) != tt.display {
+			t.Errorf("%s.Display(%d) = %q, want %q", tt.scale, got, tt.scale.Display(got), tt.display)
+		}
+	}
+}
+
+func TestLetterScaleRoundTrip(t *testing.T) {
+	// 每档的代表分换算回来还是同一档
+	for score := 0; score <= ScaleLetter.MaxScore(); score++ {
+		if got := ScaleLetter.FromPercentage(ScaleLetter.Percentage(score)); got != score {
+			t.Errorf("等级%s：Percentage=%v，换算回来是%d", ScaleLetter.Display(score), ScaleLetter.Percentage(score), got)
+		}
+	}
+	for _, score := range []int{-1, 5} {
+		if ScaleLetter.Display(score) != "" || ScaleLetter.Percentage(score) != 0 || ScaleLetter.ValidScore(score) {
+			t.Errorf("越界的等级%d应该无效", score)
+		}
+	}
+}
+
+func TestScaleProperties(t *testing.T) {
+	tests := []struct {
+		scale   GradingScale
+		valid   bool
+		numeric bool
+		max     int
+		pct     float64 // 满分的百分比
+	}{
+		{ScalePercentage, true, true, 100, 100},
+		{"", true, true, 100, 100},
+		{ScaleTenPoint, true, true, 10, 100},
+		{ScaleLetter, true, false, 4, 95},
+		{ScalePassFail, true, false, 1, 100},
+		{"gpa", false, false, 100, 100},
+	}
+	for _, tt := range tests {
+		if tt.scale.IsValid() != tt.valid || tt.scale.IsNumeric() != tt.numeric || tt.scale.MaxScore() != tt.max {
+			t.Errorf("%q: IsValid=%v IsNumeric=%v MaxScore=%d", tt.scale, tt.scale.IsValid(), tt.scale.IsNumeric(), tt.scale.MaxScore())
+		}
+		if got := tt.scale.Percentage(tt.max); got != tt.pct {
+			t.Errorf("%q.Percentage(%d) = %v, want %v", tt.scale, tt.max, got, tt.pct)
+		}
+	}
+}
@@ -12,10 +12,48 @@ type Homework struct {
 	CreatorID   int64      `gorm:"not null" json:"creator_id"`
 	Deadline    time.Time  `gorm:"not null" json:"deadline"`
 	AllowLate   bool       `gorm:"default:false" json:"allow_late"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	// 最多提交次数（含重新提交，0表示不限）；不能加default标签，否则创建时gorm会把0换成默认值，
+	// 不传时默认1次在创建作业的接口里处理
+	MaxAttempts int `gorm:"not null" json:"max_attempts"`
+	// 作业类型（普通/测验/仓库）及测验是否打乱题目顺序
+	Type             HomeworkType `gorm:"type:enum('normal','quiz','git');default:'normal';not null" json:"type"`
+	ShuffleQuestions bool         `gorm:"default:false" json:"shuffle_questions"`
+	// 评分制（默认百分制）
+	GradingScale GradingScale `gorm:"type:enum('percentage','ten_point','letter','pass_fail');default:'percentage';not null" json:"grading_scale"`
+	// 前置作业：该作业被批改且得分（换算成百分比）不低于要求后才解锁
+	PrerequisiteID         *int64  `gorm:"index" json:"prerequisite_id,omitempty"`
+	PrerequisiteMinPercent float64 `gorm:"default:0" json:"prerequisite_min_percent"`
+	// 小组作业设置（一组只交一份，组员共享成绩）
+	IsGroup           bool      `gorm:"default:false" json:"is_group"`
+	MinTeamSize       int       `gorm:"default:0" json:"min_team_size"`
+	MaxTeamSize       int       `gorm:"default:0" json:"max_team_size"`
+	AllowStudentTeams bool      `gorm:"default:false" json:"allow_student_teams"` // 是否允许学生自行组队
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// 匿名批改：成绩发布前，批改人看到的学生身份替换为固定的化名
+	BlindGrading     bool       `gorm:"default:false" json:"blind_grading"`
+	GradesReleasedAt *time.Time `json:"grades_released_at,omitempty"` // 成绩发布时间（可以是预定的将来时间；发布后学生能看到成绩，身份不再隐藏）
+	// 成绩要发布后学生才能看到（新作业都需要；加这个字段之前的旧作业没有发布环节，成绩一直可见）
+	RequireGradeRelease bool `gorm:"not null;default:false" json:"require_grade_release"`
+	// 乐观锁版本号：每次修改加1，用作ETag，修改时和If-Match比对
+	Version int `gorm:"not null;default:1" json:"version"`
 	// 关联发布者（后续查询用）
 	Creator User `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
+	// 对当前学生是否锁定（前置作业未完成，不落库）
+	Locked bool `gorm:"-" json:"locked,omitempty"`
+	// 学生上次查看/提交之后截止时间、作业要求的改动（不落库）
+	Changes FieldChanges `gorm:"-" json:"changes,omitempty"`
+	Updated bool         `gorm:"-" json:"updated,omitempty"` // 列表中标记有未查看的改动
+}
+
+// GradesReleased 成绩是否已经发布（预定的发布时间到了也算）
+func (h *Homework) GradesReleased(now time.Time) bool {
+	return h.GradesReleasedAt != nil && !now.Before(*h.GradesReleasedAt)
+}
+
+// GradesVisible 学生能否看到成绩：测验交卷即判分，旧作业没有发布环节，其余要等成绩发布
+func (h *Homework) GradesVisible(now time.Time) bool {
+	return h.Type == HomeworkQuiz || !h.RequireGradeRelease || h.GradesReleased(now)
 }
 
 func (h *Homework) DepartmentLabel() string {
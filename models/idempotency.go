@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// 带Idempotency-Key的POST请求的处理记录（重试时直接返回保存的响应）
+type IdempotencyRecord struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       int64     `gorm:"not null;uniqueIndex:idx_idempotency_user_key" json:"user_id"` // 未登录接口（注册）为0
+	Key          string    `gorm:"size:128;not null;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	Path         string    `gorm:"size:200;not null" json:"path"`
+	RequestHash  string    `gorm:"size:64;not null" json:"request_hash"` // 请求体的SHA-256，同一个key换了请求内容视为冲突
+	Done         bool      `gorm:"not null;default:false" json:"done"`   // false表示第一次请求还在处理中
+	Status       int       `json:"status"`
+	ContentType  string    `gorm:"size:100" json:"content_type"`
+	ResponseBody string    `gorm:"type:mediumtext" json:"response_body"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 互评的分项得分
+type PeerCriterionScore struct {
+	CriterionID int64  `json:"criterion_id"`
+	Score       int    `json:"score"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+type PeerCriterionScores []PeerCriterionScore
+
+func (s PeerCriterionScores) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+func (s *PeerCriterionScores) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case nil:
+		*s = nil
+		return nil
+	default:
+		return errors.New("PeerCriterionScores: 不支持的数据类型")
+	}
+}
+
+// 作业的互评轮次（截止后由管理员开启，每个作业一轮，到自己的截止时间自动结束）
+type PeerReviewRound struct {
+	ID                     int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID             int64      `gorm:"not null;uniqueIndex" json:"homework_id"`
+	ReviewersPerSubmission int        `gorm:"not null" json:"reviewers_per_submission"` // 每份提交分给几个同学（小组作业是几个小组）评
+	Deadline               time.Time  `gorm:"not null" json:"deadline"`
+	ClosedAt               *time.Time `json:"closed_at,omitempty"` // 管理员提前结束的时间
+	CreatorID              int64      `gorm:"not null" json:"creator_id"`
+	CreatedAt              time.Time  `json:"created_at"`
+}
+
+// Open 互评是否还在进行（到截止时间或被提前结束后关闭）
+func (r *PeerReviewRound) Open(now time.Time) bool {
+	return r.ClosedAt == nil && now.Before(r.Deadline)
+}
+
+// 互评分配：一个学生评一份提交（对学生匿名）
+type PeerReviewAssignment struct {
+	ID              int64               `gorm:"primaryKey;autoIncrement" json:"id"`
+	RoundID         int64               `gorm:"not null;uniqueIndex:idx_peer_assignment" json:"round_id"`
+	SubmissionID    int64               `gorm:"not null;uniqueIndex:idx_peer_assignment;index" json:"submission_id"`
+	ReviewerID      int64               `gorm:"not null;uniqueIndex:idx_peer_assignment;index" json:"reviewer_id"`
+	Score           *int                `json:"score,omitempty"` // 评完才有
+	CriterionScores PeerCriterionScores `gorm:"type:text" json:"criterion_scores,omitempty"`
+	Comment         string              `gorm:"type:text" json:"comment,omitempty"`
+	SubmittedAt     *time.Time          `json:"submitted_at,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+	// 关联轮次、被评的提交和评阅人
+	Round      PeerReviewRound `gorm:"foreignKey:RoundID" json:"round,omitempty"`
+	Submission Submission      `gorm:"foreignKey:SubmissionID" json:"submission,omitempty"`
+	Reviewer   User            `gorm:"foreignKey:ReviewerID" json:"reviewer,omitempty"`
+}
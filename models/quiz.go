@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 作业类型
+type HomeworkType string
+
+const (
+	HomeworkNormal HomeworkType = "normal" // 普通作业（人工批改）
+	HomeworkQuiz   HomeworkType = "quiz"   // 测验（题库出题，自动判分）
+	HomeworkGit    HomeworkType = "git"    // 仓库作业（提交git仓库地址，服务端克隆并固定到具体提交）
+)
+
+// 题型
+type QuestionType string
+
+const (
+	SingleChoice   QuestionType = "single"     // 单选，答案如"B"
+	MultipleChoice QuestionType = "multiple"   // 多选，答案如"A,C"
+	TrueFalse      QuestionType = "true_false" // 判断，答案为"true"/"false"
+	FillBlank      QuestionType = "fill"       // 填空，多个可接受答案用"|"分隔
+)
+
+// 字符串列表（以JSON格式存进一个text字段）
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*l = nil
+		return nil
+	default:
+		return errors.New("StringList: 不支持的数据类型")
+	}
+	return json.Unmarshal(b, l)
+}
+
+// 题库中的题目（按部门维护）
+type Question struct {
+	ID          int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Department  Department   `gorm:"type:enum('backend','frontend','sre','product','design','android','ios');not null;index" json:"department"`
+	Type        QuestionType `gorm:"type:enum('single','multiple','true_false','fill');not null" json:"type"`
+	Stem        string       `gorm:"type:text;not null" json:"stem"`   // 题干
+	Options     StringList   `gorm:"type:text" json:"options"`         // 选项（选择题用，依次对应A、B、C...）
+	Answer      string       `gorm:"size:500;not null" json:"answer"`  // 标准答案（不能返回给学生）
+	Explanation string       `gorm:"type:text" json:"explanation"`     // 解析
+	Points      int          `gorm:"not null;default:1" json:"points"` // 默认分值
+	CreatorID   int64        `gorm:"not null" json:"creator_id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// 测验作业包含的题目
+type QuizQuestion struct {
+	ID         int64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64 `gorm:"not null;index" json:"homework_id"`
+	QuestionID int64 `gorm:"not null;index" json:"question_id"`
+	Position   int   `gorm:"not null" json:"position"`
+	Points     int   `gorm:"not null" json:"points"` // 本次测验中的分值
+	// 关联题目
+	Question Question `gorm:"foreignKey:QuestionID" json:"question,omitempty"`
+}
+
+// 学生在某道题上的作答及判分结果
+type QuizAnswer struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID int64  `gorm:"not null;index" json:"submission_id"`
+	QuestionID   int64  `gorm:"not null;index" json:"question_id"`
+	Answer       string `gorm:"size:500" json:"answer"`
+	IsCorrect    bool   `gorm:"not null" json:"is_correct"`
+	Points       int    `gorm:"not null" json:"points"` // 本题得分
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// 复核申请状态
+type RegradeStatus string
+
+const (
+	RegradePending RegradeStatus = "pending" // 待处理
+	RegradeKept    RegradeStatus = "kept"    // 维持原分
+	RegradeChanged RegradeStatus = "changed" // 已改分
+)
+
+// 分数变动来源
+type ScoreChangeSource string
+
+const (
+	ScoreSourceReview  ScoreChangeSource = "review"  // 批改（含重新批改）
+	ScoreSourceQuiz    ScoreChangeSource = "quiz"    // 测验自动判分
+	ScoreSourceRegrade ScoreChangeSource = "regrade" // 处理复核申请
+	ScoreSourceImport  ScoreChangeSource = "import"  // 批量导入成绩
+	// 小组作业组员的个人分数调整（记录的是该组员调整前后的个人得分）
+	ScoreSourceAdjustment ScoreChangeSource = "adjustment"
+)
+
+// 学生对已批改提交的复核申请（按作业所属部门排队）
+type RegradeRequest struct {
+	ID            int64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID  int64         `gorm:"not null;index" json:"submission_id"`
+	HomeworkID    int64         `gorm:"not null;index" json:"homework_id"`
+	Department    Department    `gorm:"type:enum('backend','frontend','sre','product','design','android','ios');not null;index" json:"department"`
+	StudentID     int64         `gorm:"not null" json:"student_id"` // 申请人
+	Reason        string        `gorm:"type:text;not null" json:"reason"`
+	Status        RegradeStatus `gorm:"type:enum('pending','kept','changed');not null;default:'pending';index" json:"status"`
+	OriginalScore int           `gorm:"not null" json:"original_score"` // 申请时的分数
+	NewScore      *int          `json:"new_score,omitempty"`            // 改分后的分数
+	ResolverID    *int64        `json:"resolver_id,omitempty"`
+	Explanation   string        `gorm:"type:text" json:"explanation,omitempty"` // 处理说明
+	ResolvedAt    *time.Time    `json:"resolved_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	// 关联申请人、处理人和提交
+	Student    User        `gorm:"foreignKey:StudentID" json:"student,omitempty"`
+	Resolver   *User       `gorm:"foreignKey:ResolverID" json:"resolver,omitempty"`
+	Submission *Submission `gorm:"foreignKey:SubmissionID" json:"submission,omitempty"`
+}
+
+// 提交的分数变动记录（只追加不修改，学生和管理员都能查看）
+type ScoreChange struct {
+	ID               int64             `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID     int64             `gorm:"not null;index" json:"submission_id"`
+	OldScore         *int              `json:"old_score,omitempty"` // 第一次批改时为空
+	NewScore         int               `gorm:"not null" json:"new_score"`
+	Source           ScoreChangeSource `gorm:"type:enum('review','quiz','regrade','import','adjustment');not null" json:"source"`
+	ChangedBy        *int64            `json:"changed_by,omitempty"`         // 自动判分时为空
+	RegradeRequestID *int64            `json:"regrade_request_id,omitempty"` // 因复核申请改分时关联的申请
+	GradeImportID    *int64            `json:"grade_import_id,omitempty"`    // 批量导入时关联的导入记录
+	StudentID        *int64            `json:"student_id,omitempty"`         // 个人分数调整时对应的组员（其他组员看不到）
+	Reason           string            `gorm:"type:text" json:"reason,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	// 关联操作人
+	Changer *User `gorm:"foreignKey:ChangedBy" json:"changer,omitempty"`
+}
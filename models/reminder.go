@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// 管理员发给学生的交作业提醒（站内消息）
+type Reminder struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64      `gorm:"not null;index:idx_reminder_homework_student" json:"homework_id"`
+	StudentID  int64      `gorm:"not null;index:idx_reminder_homework_student;index" json:"student_id"`
+	SenderID   int64      `gorm:"not null" json:"sender_id"`
+	Message    string     `gorm:"size:500;not null" json:"message"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// 关联作业
+	Homework Homework `gorm:"foreignKey:HomeworkID" json:"homework,omitempty"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 仓库快照里的一个文件
+type RepoTreeEntry struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"` // 100644普通文件、100755可执行文件、120000符号链接、160000子模块
+	Size int64  `json:"size"`
+}
+
+// 仓库快照的文件列表（以JSON存一列）
+type RepoTree []RepoTreeEntry
+
+func (t RepoTree) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *RepoTree) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	case nil:
+		*t = nil
+		return nil
+	default:
+		return errors.New("RepoTree: 不支持的数据类型")
+	}
+}
+
+// 仓库作业的一次快照：提交时克隆仓库、固定到具体提交，打包的tar作为上传文件关联到提交（创建后不再修改）
+type RepositorySnapshot struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID  int64     `gorm:"not null;index" json:"homework_id"`
+	StudentID   int64     `gorm:"not null;index" json:"student_id"`      // 提交这一版的学生
+	FileID      int64     `gorm:"not null;uniqueIndex" json:"file_id"`   // 打包的tar（StoredFile）
+	RepoURL     string    `gorm:"size:500;not null" json:"repo_url"`     // 学生填的仓库地址
+	Ref         string    `gorm:"size:255;not null" json:"ref"`          // 学生填的分支、标签或提交
+	CommitSHA   string    `gorm:"size:64;not null" json:"commit_sha"`    // 克隆时ref指向的提交
+	CommittedAt time.Time `gorm:"not null" json:"committed_at"`          // 该提交的提交时间
+	FileCount   int       `gorm:"not null" json:"file_count"`            // 快照里的文件数
+	Tree        RepoTree  `gorm:"type:mediumtext" json:"tree,omitempty"` // 快照里的文件列表
+	CreatedAt   time.Time `json:"created_at"`                            // 克隆的时间
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// 提交的批改认领（同一时间只有一个批改人持有）：自动分配的不过期，从队列领取的到期自动释放
+type ReviewClaim struct {
+	ID           int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID int64      `gorm:"not null;uniqueIndex" json:"submission_id"`
+	HomeworkID   int64      `gorm:"not null;index" json:"homework_id"`
+	ReviewerID   int64      `gorm:"not null;index" json:"reviewer_id"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // 为空表示分配的，不会过期
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// 关联批改人
+	Reviewer User `gorm:"foreignKey:ReviewerID" json:"reviewer,omitempty"`
+}
+
+// Active 认领是否还有效
+func (c *ReviewClaim) Active(now time.Time) bool {
+	return c.ExpiresAt == nil || c.ExpiresAt.After(now)
+}
+
+// 导师关系（按导师分配批改时，学生的提交交给自己的导师）
+type Mentorship struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	StudentID int64     `gorm:"not null;uniqueIndex" json:"student_id"`
+	MentorID  int64     `gorm:"not null;index" json:"mentor_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// 关联学生和导师
+	Student User `gorm:"foreignKey:StudentID" json:"student,omitempty"`
+	Mentor  User `gorm:"foreignKey:MentorID" json:"mentor,omitempty"`
+}
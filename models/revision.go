@@ -0,0 +1,147 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// 修改后需要提醒学生的字段
+var notableFields = map[string]bool{"deadline": true, "description": true}
+
+// 作业在某个版本时的内容（只记录修改接口能改的字段）
+type HomeworkSnapshot struct {
+	Title        string       `json:"title"`
+	Description  string       `json:"description"`
+	Department   Department   `json:"department"`
+	Deadline     time.Time    `json:"deadline"`
+	AllowLate    bool         `json:"allow_late"`
+	MaxAttempts  int          `json:"max_attempts"`
+	GradingScale GradingScale `json:"grading_scale"`
+}
+
+func (s HomeworkSnapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+func (s *HomeworkSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case nil:
+		return nil
+	default:
+		return errors.New("HomeworkSnapshot: 不支持的数据类型")
+	}
+}
+
+// 一个字段的修改
+type FieldChange struct {
+	Field string `json:"field"` // 字段名（和作业的json字段名一致）
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// 修改的字段列表（以JSON存一列）
+type FieldChanges []FieldChange
+
+func (c FieldChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func (c *FieldChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return errors.New("FieldChanges: 不支持的数据类型")
+	}
+}
+
+// 作业的修改记录（每次修改一个版本，版本1为创建时的内容）
+type HomeworkRevision struct {
+	ID         int64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64            `gorm:"not null;uniqueIndex:idx_homework_version" json:"homework_id"`
+	Version    int              `gorm:"not null;uniqueIndex:idx_homework_version" json:"version"`
+	EditorID   int64            `gorm:"not null" json:"editor_id"`
+	Snapshot   HomeworkSnapshot `gorm:"type:text" json:"snapshot"` // 修改后的完整内容
+	Changes    FieldChanges     `gorm:"type:text" json:"changes"`  // 相对上一版本改了哪些字段
+	Notable    bool             `gorm:"not null;default:false" json:"notable"`
+	CreatedAt  time.Time        `json:"created_at"`
+	// 关联修改人
+	Editor User `gorm:"foreignKey:EditorID" json:"editor,omitempty"`
+}
+
+// 学生最后一次查看作业详情的时间（用来判断哪些修改需要提醒）
+type HomeworkView struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64     `gorm:"not null;uniqueIndex:idx_view_homework_student" json:"homework_id"`
+	StudentID  int64     `gorm:"not null;uniqueIndex:idx_view_homework_student" json:"student_id"`
+	ViewedAt   time.Time `gorm:"not null" json:"viewed_at"`
+}
+
+// Snapshot 作业当前内容的快照
+func (h *Homework) Snapshot() HomeworkSnapshot {
+	return HomeworkSnapshot{
+		Title:        h.Title,
+		Description:  h.Description,
+		Department:   h.Department,
+		Deadline:     h.Deadline,
+		AllowLate:    h.AllowLate,
+		MaxAttempts:  h.MaxAttempts,
+		GradingScale: h.GradingScale,
+	}
+}
+
+// DiffSnapshots 比较两个版本，返回改动的字段
+func DiffSnapshots(from, to HomeworkSnapshot) FieldChanges {
+	var changes FieldChanges
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	add("title", from.Title, to.Title)
+	add("description", from.Description, to.Description)
+	add("department", string(from.Department), string(to.Department))
+	add("deadline", from.Deadline.Local().Format("2006-01-02 15:04:05"), to.Deadline.Local().Format("2006-01-02 15:04:05"))
+	add("allow_late", strconv.FormatBool(from.AllowLate), strconv.FormatBool(to.AllowLate))
+	add("max_attempts", strconv.Itoa(from.MaxAttempts), strconv.Itoa(to.MaxAttempts))
+	add("grading_scale", string(from.GradingScale), string(to.GradingScale))
+	return changes
+}
+
+// IsNotable 是否包含需要提醒学生的字段（截止时间、作业要求）
+func (c FieldChanges) IsNotable() bool {
+	for _, change := range c {
+		if notableFields[change.Field] {
+			return true
+		}
+	}
+	return false
+}
+
+// Notable 只保留需要提醒学生的字段
+func (c FieldChanges) Notable() FieldChanges {
+	var list FieldChanges
+	for _, change := range c {
+		if notableFields[change.Field] {
+			list = append(list, change)
+		}
+	}
+	return list
+}
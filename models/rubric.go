@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// 评分标准（一个作业最多一套）
+type Rubric struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64     `gorm:"not null;uniqueIndex" json:"homework_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// 关联评分项
+	Criteria []RubricCriterion `gorm:"foreignKey:RubricID" json:"criteria"`
+}
+
+// 评分项（如“代码规范”“功能完整度”）
+type RubricCriterion struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	RubricID    int64  `gorm:"not null;index" json:"rubric_id"`
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+	MaxPoints   int    `gorm:"not null" json:"max_points"`
+	SortOrder   int    `gorm:"not null;default:0" json:"sort_order"`
+	// 关联等级描述
+	Levels []RubricLevel `gorm:"foreignKey:CriterionID" json:"levels"`
+}
+
+// 评分等级描述（如“8分：接口齐全且有单元测试”）
+type RubricLevel struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	CriterionID int64  `gorm:"not null;index" json:"criterion_id"`
+	Points      int    `gorm:"not null" json:"points"`
+	Description string `gorm:"type:text;not null" json:"description"`
+}
+
+// 提交在某个评分项上的得分
+type SubmissionCriterionScore struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID int64  `gorm:"not null;index" json:"submission_id"`
+	CriterionID  int64  `gorm:"not null;index" json:"criterion_id"`
+	Score        int    `gorm:"not null" json:"score"`
+	Comment      string `gorm:"type:text" json:"comment,omitempty"`
+	// 关联评分项（展示评分项名称用）
+	Criterion RubricCriterion `gorm:"foreignKey:CriterionID" json:"criterion,omitempty"`
+}
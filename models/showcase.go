@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// 学生同意把自己的优秀作业放进作品展（小组作业要每个组员都同意才展示，任一组员选匿名就匿名展示）
+type ShowcaseConsent struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID int64     `gorm:"not null;uniqueIndex:idx_showcase_consent_submission_student" json:"submission_id"`
+	StudentID    int64     `gorm:"not null;uniqueIndex:idx_showcase_consent_submission_student" json:"student_id"`
+	Anonymous    bool      `gorm:"not null;default:false" json:"anonymous"` // 展示时不显示姓名
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 相似提交对的处理状态
+type SimilarityStatus string
+
+const (
+	SimilarityUnreviewed SimilarityStatus = "unreviewed" // 待处理
+	SimilarityReviewed   SimilarityStatus = "reviewed"   // 已核查（确认有问题，另行处理）
+	SimilarityAcceptable SimilarityStatus = "acceptable" // 已核查，属于正常相似（比如都用了模板代码）
+)
+
+// 原文中和另一份提交相同的片段（字节位置，左闭右开）
+type MatchRegion struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type MatchRegions []MatchRegion
+
+func (r MatchRegions) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(r)
+	return string(b), err
+}
+
+func (r *MatchRegions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	case nil:
+		*r = nil
+		return nil
+	default:
+		return errors.New("MatchRegions: 不支持的数据类型")
+	}
+}
+
+// 同一作业下相似度超过阈值的两份提交（SubmissionAID < SubmissionBID）
+type SimilarityPair struct {
+	ID            int64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID    int64            `gorm:"not null;index" json:"homework_id"`
+	SubmissionAID int64            `gorm:"not null;uniqueIndex:idx_similarity_pair" json:"submission_a_id"`
+	SubmissionBID int64            `gorm:"not null;uniqueIndex:idx_similarity_pair;index" json:"submission_b_id"`
+	Score         float64          `gorm:"not null;index" json:"score"` // 共同指纹占较短一份的比例
+	Jaccard       float64          `gorm:"not null" json:"jaccard"`     // 共同指纹占全部指纹的比例
+	MatchesA      MatchRegions     `gorm:"type:text" json:"matches_a"`  // A中相同的片段
+	MatchesB      MatchRegions     `gorm:"type:text" json:"matches_b"`  // B中相同的片段
+	Status        SimilarityStatus `gorm:"type:enum('unreviewed','reviewed','acceptable');not null;default:'unreviewed'" json:"status"`
+	ReviewerID    *int64           `json:"reviewer_id,omitempty"`
+	ReviewNote    string           `gorm:"type:text" json:"review_note,omitempty"`
+	ReviewedAt    *time.Time       `json:"reviewed_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	// 关联两份提交（含学生）
+	SubmissionA Submission `gorm:"foreignKey:SubmissionAID" json:"submission_a,omitempty"`
+	SubmissionB Submission `gorm:"foreignKey:SubmissionBID" json:"submission_b,omitempty"`
+}
@@ -6,20 +6,66 @@ import (
 
 type Submission struct {
 	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
-	HomeworkID  int64      `gorm:"not null;index" json:"homework_id"`
-	StudentID   int64      `gorm:"not null;index" json:"student_id"`
+	HomeworkID  int64      `gorm:"not null;index;uniqueIndex:idx_submission_homework_student" json:"homework_id"`
+	StudentID   int64      `gorm:"not null;index;uniqueIndex:idx_submission_homework_student" json:"student_id"` // 每个学生每个作业只有一条提交（多次提交见SubmissionVersion）
 	Content     string     `gorm:"type:text;not null" json:"content"`
 	FileURL     string     `gorm:"size:500" json:"file_url"`
+	FileIDs     IDList     `gorm:"type:text" json:"file_ids"` // 当前版本关联的上传文件
 	IsLate      bool       `gorm:"default:false" json:"is_late"`
-	Score       *int       `json:"score,omitempty"` // 分数可选（批改后才有）
+	Attempts    int        `gorm:"not null;default:1" json:"attempts"`   // 第几次提交（内容始终是最新版本，历史版本见SubmissionVersion）
+	Withdrawn   bool       `gorm:"default:false;index" json:"withdrawn"` // 学生已撤回（截止前可重新提交）
+	WithdrawnAt *time.Time `json:"withdrawn_at,omitempty"`
+	TeamID      *int64     `gorm:"uniqueIndex" json:"team_id,omitempty"` // 小组作业时为提交所属小组（一组只有一条提交）
+	Score       *int       `json:"score,omitempty"`                      // 分数可选（批改后才有）
 	Comment     string     `gorm:"type:text" json:"comment,omitempty"`
 	IsExcellent bool       `gorm:"default:false" json:"is_excellent"`
-	ReviewerID  *int64     `json:"reviewer_id,omitempty"`
-	SubmittedAt time.Time  `json:"submitted_at"`
-	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	// 管理员写的推荐理由（取消优秀时清空）
+	ExcellentReason string     `gorm:"size:500" json:"excellent_reason,omitempty"`
+	ReviewerID      *int64     `json:"reviewer_id,omitempty"`
+	SubmittedAt     time.Time  `json:"submitted_at"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Version         int        `gorm:"not null;default:1" json:"version"` // 乐观锁版本号（每次修改加1，用作ETag）
 	// 关联作业和学生
 	Homework Homework `gorm:"foreignKey:HomeworkID" json:"homework,omitempty"`
 	Student  User     `gorm:"foreignKey:StudentID" json:"student,omitempty"`
+	// 小组作业的小组（含组员）
+	Team *Team `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+	// 按评分标准批改时的分项得分
+	CriterionScores []SubmissionCriterionScore `gorm:"foreignKey:SubmissionID" json:"criterion_scores,omitempty"`
+	// 测验作业的逐题作答结果
+	QuizAnswers []QuizAnswer `gorm:"foreignKey:SubmissionID" json:"quiz_answers,omitempty"`
+	// 上传过的文件（包括历史版本的，当前版本的见FileIDs）
+	Files []StoredFile `gorm:"foreignKey:SubmissionID" json:"files,omitempty"`
+	// 历史版本
+	Versions []SubmissionVersion `gorm:"foreignKey:SubmissionID" json:"versions,omitempty"`
+	// 按作业评分制换算出的展示文本和百分比（不落库）
+	ScoreDisplay string   `gorm:"-" json:"score_display,omitempty"`
+	ScorePercent *float64 `gorm:"-" json:"score_percent,omitempty"`
+	// 匿名批改时代替学生身份的化名（不落库）
+	Pseudonym string `gorm:"-" json:"pseudonym,omitempty"`
+}
+
+// 提交的历史版本（创建后不再修改）
+type SubmissionVersion struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubmissionID int64     `gorm:"not null;uniqueIndex:idx_submission_version" json:"submission_id"`
+	Version      int       `gorm:"not null;uniqueIndex:idx_submission_version" json:"version"`
+	SubmitterID  int64     `gorm:"not null" json:"submitter_id"` // 小组作业时是提交这一版的组员
+	Content      string    `gorm:"type:text;not null" json:"content"`
+	FileURL      string    `gorm:"size:500" json:"file_url"`
+	FileIDs      IDList    `gorm:"type:text" json:"file_ids"`
+	IsLate       bool      `gorm:"default:false" json:"is_late"`
+	SubmittedAt  time.Time `json:"submitted_at"`
+}
+
+// FillScoreDisplay 按评分制填充分数的展示文本和百分比
+func (s *Submission) FillScoreDisplay(scale GradingScale) {
+	if s.Score == nil {
+		return
+	}
+	percent := scale.Percentage(*s.Score)
+	s.ScoreDisplay = scale.Display(*s.Score)
+	s.ScorePercent = &percent
 }
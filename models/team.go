@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// 小组（小组作业按组提交，一组只有一份提交）
+type Team struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	HomeworkID int64     `gorm:"not null;index" json:"homework_id"`
+	Name       string    `gorm:"size:100;not null" json:"name"`
+	LeaderID   int64     `gorm:"not null" json:"leader_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// 关联组员
+	Members []TeamMember `gorm:"foreignKey:TeamID" json:"members"`
+}
+
+// 组员（同一作业下一个学生只能在一个组里）
+type TeamMember struct {
+	ID         int64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	TeamID     int64 `gorm:"not null;index" json:"team_id"`
+	HomeworkID int64 `gorm:"not null;uniqueIndex:idx_team_member_homework_student" json:"homework_id"`
+	StudentID  int64 `gorm:"not null;uniqueIndex:idx_team_member_homework_student" json:"student_id"`
+	// 个人分数调整（在小组得分基础上加减，如贡献少的组员-10），只通过Adjustment展示
+	ScoreAdjustment int       `gorm:"not null;default:0" json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	// 关联学生（展示昵称用）
+	Student User `gorm:"foreignKey:StudentID" json:"student,omitempty"`
+	// 展示给管理员和组员本人（成绩发布后）的个人分数调整（不落库）
+	Adjustment *int `gorm:"-" json:"score_adjustment,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// 学习路线（按部门定义的一组有序作业，如“Go基础 → Gin+GORM”）
+type Track struct {
+	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	Description string     `gorm:"type:text" json:"description"`
+	Department  Department `gorm:"type:enum('backend','frontend','sre','product','design','android','ios');not null;index" json:"department"`
+	CreatorID   int64      `gorm:"not null" json:"creator_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// 关联路线中的作业（按Position排序）
+	Items []TrackItem `gorm:"foreignKey:TrackID" json:"items"`
+}
+
+// 路线中的一项作业
+type TrackItem struct {
+	ID         int64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	TrackID    int64 `gorm:"not null;index" json:"track_id"`
+	HomeworkID int64 `gorm:"not null;index" json:"homework_id"`
+	Position   int   `gorm:"not null" json:"position"` // 从0开始的顺序
+	// 关联作业（展示标题用）
+	Homework Homework `gorm:"foreignKey:HomeworkID" json:"homework,omitempty"`
+}
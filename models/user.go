@@ -33,8 +33,10 @@ type User struct {
 	Role       Role       `gorm:"type:enum('student','admin');not null" json:"role"`
 	Department Department `gorm:"type:enum('backend','frontend','sre','product','design','android','ios');not null" json:"department"`
 	Email      string     `gorm:"size:100" json:"email"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	// 最近一次登录时间（从没登录过为空；不随用户信息返回，只在未交名单里给管理员看）
+	LastLoginAt *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 	// 软删除标记
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
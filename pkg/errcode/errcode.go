@@ -4,13 +4,31 @@ type ErrCode int
 
 // 定义常用错误码
 const (
-	Success          ErrCode = 0
-	ParamError       ErrCode = 10001
-	AuthError        ErrCode = 10002
-	PermissionDenied ErrCode = 10003
-	DataNotFound     ErrCode = 10004
-	DBError          ErrCode = 10005
-	TokenExpired     ErrCode = 10006
+	Success             ErrCode = 0
+	ParamError          ErrCode = 10001
+	AuthError           ErrCode = 10002
+	PermissionDenied    ErrCode = 10003
+	DataNotFound        ErrCode = 10004
+	DBError             ErrCode = 10005
+	TokenExpired        ErrCode = 10006
+	HomeworkLocked      ErrCode = 10007
+	AttemptsUsedUp      ErrCode = 10008
+	DeadlinePassed      ErrCode = 10009
+	FileTooLarge        ErrCode = 10010
+	FileTypeNotAllowed  ErrCode = 10011
+	StorageError        ErrCode = 10012
+	AlreadySubmitted    ErrCode = 10013
+	IdempotencyConflict ErrCode = 10014
+	RegradeWindowClosed ErrCode = 10015
+	RegradePending      ErrCode = 10016
+	RegradeLimitReached ErrCode = 10017
+	ReviewClaimed       ErrCode = 10018
+	VersionConflict     ErrCode = 10019
+	GradeImportInvalid  ErrCode = 10020
+	GradesLocked        ErrCode = 10021
+	RepositoryInvalid   ErrCode = 10022
+	RepositoryFailed    ErrCode = 10023
+	IfMatchRequired     ErrCode = 10024
 )
 
 // 获取错误信息
@@ -30,6 +48,42 @@ func (e ErrCode) Msg() string {
 		return "数据库操作失败"
 	case TokenExpired:
 		return "Token已过期"
+	case HomeworkLocked:
+		return "作业尚未解锁，请先完成前置作业"
+	case AttemptsUsedUp:
+		return "提交次数已用完"
+	case DeadlinePassed:
+		return "已过截止时间"
+	case FileTooLarge:
+		return "文件过大"
+	case FileTypeNotAllowed:
+		return "不支持的文件类型"
+	case StorageError:
+		return "文件存储失败"
+	case AlreadySubmitted:
+		return "已提交过该作业"
+	case IdempotencyConflict:
+		return "相同的Idempotency-Key正在处理或已用于其他请求"
+	case RegradeWindowClosed:
+		return "已超过申请复核的期限"
+	case RegradePending:
+		return "已有待处理的复核申请"
+	case RegradeLimitReached:
+		return "复核申请次数已用完"
+	case ReviewClaimed:
+		return "该提交由其他批改人负责批改"
+	case VersionConflict:
+		return "数据已被他人修改，请刷新后重试"
+	case GradeImportInvalid:
+		return "导入的表格有错误，请按每行的提示修改后重试"
+	case GradesLocked:
+		return "成绩已发布，学生申请复核后才能修改"
+	case RepositoryInvalid:
+		return "仓库地址或分支/标签/提交名不正确"
+	case RepositoryFailed:
+		return "无法克隆仓库或找不到指定的分支/标签/提交"
+	case IfMatchRequired:
+		return "修改前请先查询数据，并在If-Match请求头里带上返回的ETag"
 	default:
 		return "未知错误"
 	}
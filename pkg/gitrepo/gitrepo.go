@@ -0,0 +1,400 @@
+package gitrepo
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrInvalidURL   = errors.New("gitrepo: 仓库地址不正确或不允许")
+	ErrInvalidRef   = errors.New("gitrepo: 分支/标签/提交名不正确")
+	ErrCloneFailed  = errors.New("gitrepo: 克隆仓库失败")
+	ErrRefNotFound  = errors.New("gitrepo: 仓库里找不到这个分支/标签/提交")
+	ErrTooLarge     = errors.New("gitrepo: 仓库内容超过大小上限")
+	ErrFileNotFound = errors.New("gitrepo: 快照里没有这个文件")
+)
+
+// 克隆的配置
+type Options struct {
+	GitBinary  string        // git可执行文件
+	WorkDir    string        // 克隆用的临时目录（为空则用系统临时目录）
+	Timeout    time.Duration // 整个快照过程的超时
+	MaxSize    int64         // 快照里所有文件的总大小上限（字节），克隆下来的仓库（含历史）也不能超过它；0表示不限制
+	AllowLocal bool          // 是否允许本地路径、file://地址和解析到内网的主机（只在测试环境打开，如用本地裸仓库测试）
+}
+
+// 快照里的一个文件
+type TreeEntry struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"` // 100644普通文件、100755可执行文件、120000符号链接
+	Size int64  `json:"size"`
+}
+
+// 一次快照的结果：Archive是git archive生成的tar文件，用完要Close删掉临时目录
+type Snapshot struct {
+	CommitSHA   string
+	CommittedAt time.Time // 提交时间（committer时间）
+	Tree        []TreeEntry
+	Archive     string
+	ArchiveSize int64
+	dir         string
+}
+
+// Close 删除快照的临时目录
+func (s *Snapshot) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// scp风格的ssh地址，如 git@github.com:user/repo.git
+var scpURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[A-Za-z0-9._~/-]+$`)
+
+// 分支、标签名或提交SHA
+var refPattern = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
+// ValidateURL 只允许https/http/ssh/git协议（AllowLocal时还允许本地路径和file://），
+// 不允许带密码，也不允许git的ext::等传输助手写法
+func ValidateURL(raw string, allowLocal bool) error {
+	if raw == "" || len(raw) > 500 || strings.HasPrefix(raw, "-") || strings.Contains(raw, "::") ||
+		strings.ContainsAny(raw, " \t\r\n") {
+		return ErrInvalidURL
+	}
+	if scpURL.MatchString(raw) {
+		return nil
+	}
+	if filepath.IsAbs(raw) {
+		if allowLocal {
+			return nil
+		}
+		return ErrInvalidURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ErrInvalidURL
+	}
+	switch u.Scheme {
+	case "https", "http", "ssh", "git":
+		if u.Host == "" {
+			return ErrInvalidURL
+		}
+	case "file":
+		if !allowLocal {
+			return ErrInvalidURL
+		}
+	default:
+		return ErrInvalidURL
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// ValidateRef 校验分支、标签名或提交SHA（不能以-开头，不能有..）
+func ValidateRef(ref string) error {
+	if ref == "" || len(ref) > 255 || strings.HasPrefix(ref, "-") || strings.Contains(ref, "..") ||
+		!refPattern.MatchString(ref) {
+		return ErrInvalidRef
+	}
+	return nil
+}
+
+// Take 克隆仓库，把ref解析成具体的提交，记录文件列表并用git archive打包成tar
+func Take(ctx context.Context, opts Options, repoURL, ref string) (*Snapshot, error) {
+	// 1. 校验参数
+	if err := ValidateURL(repoURL, opts.AllowLocal); err != nil {
+		return nil, err
+	}
+	if err := ValidateRef(ref); err != nil {
+		return nil, err
+	}
+	gitBinary := opts.GitBinary
+	if gitBinary == "" {
+		gitBinary = "git"
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	dir, err := os.MkdirTemp(opts.WorkDir, "repo-*")
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{dir: dir}
+	ok := false
+	defer func() {
+		if !ok {
+			snap.Close()
+		}
+	}()
+	r := &runner{ctx: ctx, git: gitBinary, home: dir, allowLocal: opts.AllowLocal}
+	gitDir := filepath.Join(dir, "repo.git")
+
+	// 2. 主机名只能解析到公网地址
+	var config []string
+	if !opts.AllowLocal {
+		if config, err = checkHost(ctx, repoURL); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. 裸克隆（不检出，不会执行仓库里的钩子），克隆过程中目录超过上限就停下
+	if err := r.clone(config, repoURL, gitDir, opts.MaxSize); err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, ErrCloneFailed
+	}
+
+	// 4. 把ref解析成提交SHA，记录提交时间
+	out, err := r.run("--git-dir", gitDir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return nil, ErrRefNotFound
+	}
+	snap.CommitSHA = strings.TrimSpace(string(out))
+	out, err = r.run("--git-dir", gitDir, "show", "-s", "--format=%cI", snap.CommitSHA)
+	if err != nil {
+		return nil, err
+	}
+	snap.CommittedAt, err = time.Parse(time.RFC3339, strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. 文件列表（子模块只记录不打包），总大小超过上限时不打包
+	out, err = r.run("--git-dir", gitDir, "ls-tree", "-r", "-l", "-z", snap.CommitSHA)
+	if err != nil {
+		return nil, err
+	}
+	snap.Tree, err = parseTree(out)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, e := range snap.Tree {
+		total += e.Size
+	}
+	if opts.MaxSize > 0 && total > opts.MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	// 6. 打包成tar
+	snap.Archive = filepath.Join(dir, "snapshot.tar")
+	if _, err := r.run("--git-dir", gitDir, "archive", "--format=tar", "-o", snap.Archive, snap.CommitSHA); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(snap.Archive)
+	if err != nil {
+		return nil, err
+	}
+	snap.ArchiveSize = info.Size()
+	ok = true
+	return snap, nil
+}
+
+// checkHost 解析仓库地址的主机名，解析到回环、内网、链路本地等地址时拒绝（防止借克隆访问服务器所在的内网）；
+// http(s)地址返回把主机名固定到检查过的地址的git配置，克隆时不会被DNS重绑定绕过
+func checkHost(ctx context.Context, repoURL string) ([]string, error) {
+	// 1. 取出主机名和端口（scp风格的是ssh）
+	var scheme, host, port string
+	if scpURL.MatchString(repoURL) {
+		_, rest, _ := strings.Cut(repoURL, "@")
+		scheme = "ssh"
+		host, _, _ = strings.Cut(rest, ":")
+	} else {
+		u, err := url.Parse(repoURL)
+		if err != nil || u.Hostname() == "" {
+			return nil, ErrInvalidURL
+		}
+		scheme, host, port = u.Scheme, u.Hostname(), u.Port()
+	}
+	if port == "" {
+		port = map[string]string{"https": "443", "http": "80", "ssh": "22", "git": "9418"}[scheme]
+	}
+
+	// 2. 所有解析结果都要是公网地址
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return nil, ErrCloneFailed
+	}
+	ips := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if !publicIP(a.IP) {
+			return nil, ErrInvalidURL
+		}
+		if a.IP.To4() == nil {
+			ips = append(ips, "["+a.IP.String()+"]")
+		} else {
+			ips = append(ips, a.IP.String())
+		}
+	}
+
+	// 3. http(s)固定解析结果并且不跟随重定向（重定向的目标没检查过）
+	if scheme != "http" && scheme != "https" {
+		return nil, nil
+	}
+	return []string{
+		"-c", "http.curloptResolve=" + host + ":" + port + ":" + strings.Join(ips, ","),
+		"-c", "http.followRedirects=false",
+	}, nil
+}
+
+// publicIP 是否为公网地址
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// parseTree 解析git ls-tree -r -l -z的输出：<mode> <type> <object> <size>\t<path>\0
+func parseTree(out []byte) ([]TreeEntry, error) {
+	var entries []TreeEntry
+	for _, line := range bytes.Split(out, []byte{0}) {
+		if len(line) == 0 {
+			continue
+		}
+		meta, path, found := bytes.Cut(line, []byte{'\t'})
+		fields := strings.Fields(string(meta))
+		if !found || len(fields) != 4 {
+			return nil, fmt.Errorf("gitrepo: 无法解析文件列表：%q", line)
+		}
+		entry := TreeEntry{Path: string(path), Mode: fields[0]}
+		if fields[1] == "blob" {
+			size, err := strconv.ParseInt(fields[3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("gitrepo: 无法解析文件大小：%q", line)
+			}
+			entry.Size = size
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ReadFile 从快照的tar里读出一个文件，超过max字节时返回ErrTooLarge
+func ReadFile(archive io.Reader, path string, max int64) ([]byte, error) {
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, ErrFileNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name != path || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if max > 0 && hdr.Size > max {
+			return nil, ErrTooLarge
+		}
+		return io.ReadAll(tr)
+	}
+}
+
+// runner 在隔离的环境里运行git：不读系统和用户的配置，不弹出输入密码的提示，只允许白名单里的协议
+type runner struct {
+	ctx        context.Context
+	git        string
+	home       string
+	allowLocal bool
+}
+
+func (r *runner) run(args ...string) ([]byte, error) {
+	return r.runContext(r.ctx, args...)
+}
+
+// clone 裸克隆到gitDir，克隆过程中定期统计目录大小，超过max（0表示不限制）时杀掉git并返回ErrTooLarge
+func (r *runner) clone(config []string, repoURL, gitDir string, max int64) error {
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+	var exceeded atomic.Bool
+	if max > 0 {
+		go func() {
+			ticker := time.NewTicker(100 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if dirSize(gitDir) > max {
+						exceeded.Store(true)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+	args := append(append([]string{}, config...), "clone", "--bare", "--quiet", "--no-local", "--", repoURL, gitDir)
+	_, err := r.runContext(ctx, args...)
+	if exceeded.Load() {
+		return ErrTooLarge
+	}
+	// 克隆完最后一次统计可能还没到
+	if err == nil && max > 0 && dirSize(gitDir) > max {
+		return ErrTooLarge
+	}
+	return err
+}
+
+// dirSize 目录下所有文件的总大小（文件在统计时被删掉的忽略）
+func dirSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+func (r *runner) runContext(ctx context.Context, args ...string) ([]byte, error) {
+	protocols := "https:http:ssh:git"
+	if r.allowLocal {
+		protocols += ":file"
+	}
+	cmd := exec.CommandContext(ctx, r.git, args...)
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + r.home,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL=" + os.DevNull,
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_ALLOW_PROTOCOL=" + protocols,
+		"GIT_SSH_COMMAND=ssh -o BatchMode=yes",
+	}
+	cmd.WaitDelay = 5 * time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gitrepo: git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
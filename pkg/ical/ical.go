@@ -0,0 +1,111 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 日历事件（只包含作业截止提醒用到的字段）
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Updated     time.Time
+	Alarms      []time.Duration // 提前多久提醒（如24小时、1小时）
+}
+
+// 日历（对应一个VCALENDAR）
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// 时间统一转成UTC格式（如20260301T155959Z）
+const timeFormat = "20060102T150405Z"
+
+// Bytes 生成.ics文件内容（RFC 5545）
+func (c *Calendar) Bytes() []byte {
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:-//homework-system//deadline feed//CN")
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escape(c.Name))
+	}
+	stamp := time.Now().UTC().Format(timeFormat)
+	for _, e := range c.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+escape(e.UID))
+		writeLine(&b, "DTSTAMP:"+stamp)
+		if !e.Updated.IsZero() {
+			writeLine(&b, "LAST-MODIFIED:"+e.Updated.UTC().Format(timeFormat))
+		}
+		writeLine(&b, "DTSTART:"+e.Start.UTC().Format(timeFormat))
+		writeLine(&b, "DTEND:"+e.End.UTC().Format(timeFormat))
+		writeLine(&b, "SUMMARY:"+escape(e.Summary))
+		if e.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escape(e.Description))
+		}
+		if e.URL != "" {
+			writeLine(&b, "URL:"+escape(e.URL))
+		}
+		for _, a := range e.Alarms {
+			writeLine(&b, "BEGIN:VALARM")
+			writeLine(&b, "ACTION:DISPLAY")
+			writeLine(&b, "DESCRIPTION:"+escape(e.Summary))
+			writeLine(&b, "TRIGGER:"+duration(-a))
+			writeLine(&b, "END:VALARM")
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+	writeLine(&b, "END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// escape 转义文本值中的特殊字符
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// duration 把时长转成iCalendar的DURATION格式（如-PT24H、-PT30M）
+func duration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	minutes := int64(d / time.Minute)
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%sPT%dH", sign, minutes/60)
+	}
+	return fmt.Sprintf("%sPT%dM", sign, minutes)
+}
+
+// writeLine 写一行并按75字节折行（续行以空格开头，空格也算在75字节里），不拆开UTF-8字符
+func writeLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// isRuneStart 判断该字节是否为UTF-8字符的首字节
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,157 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"普通文本", "普通文本"},
+		{`a\b`, `a\\b`},
+		{"a;b,c", `a\;b\,c`},
+		{"第一行\n第二行", `第一行\n第二行`},
+		{"windows\r\n换行", `windows\n换行`},
+		{`\;`, `\\\;`},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{-24 * time.Hour, "-PT24H"},
+		{-time.Hour, "-PT1H"},
+		{-30 * time.Minute, "-PT30M"},
+		{-90 * time.Minute, "-PT90M"},
+		{2 * time.Hour, "PT2H"},
+		{0, "PT0H"},
+	}
+	for _, tt := range tests {
+		if got := duration(tt.d); got != tt.want {
+			t.Errorf("duration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		lines int
+	}{
+		{"不超长不折行", "SUMMARY:" + strings.Repeat("a", 67), 1},
+		{"刚好超过一个字节", "SUMMARY:" + strings.Repeat("a", 68), 2},
+		{"长ASCII", "DESCRIPTION:" + strings.Repeat("x", 300), 5},
+		{"中文不拆开字符", "SUMMARY:" + strings.Repeat("作业截止", 30), 0},
+		{"混合", "DESCRIPTION:" + strings.Repeat("ab作业", 40), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeLine(&b, tt.line)
+			out := b.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("没有以CRLF结尾：%q", out)
+			}
+			physical := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if tt.lines > 0 && len(physical) != tt.lines {
+				t.Errorf("折成%d行，want %d", len(physical), tt.lines)
+			}
+			for i, l := range physical {
+				if len(l) > 75 {
+					t.Errorf("第%d行%d字节，超过75", i, len(l))
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Errorf("续行没有以空格开头：%q", l)
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("第%d行拆开了UTF-8字符：%q", i, l)
+				}
+			}
+			// 去掉折行后和原来一样
+			if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != tt.line {
+				t.Errorf("展开后 = %q, want %q", unfolded, tt.line)
+			}
+		})
+	}
+}
+
+func TestCalendarBytes(t *testing.T) {
+	deadline := time.Date(2026, 3, 1, 23, 59, 59, 0, time.FixedZone("CST", 8*3600))
+	cal := &Calendar{
+		Name: "我的作业",
+		Events: []Event{{
+			UID:         "homework-1@homework-system",
+			Summary:     "实验一, 第二部分",
+			Description: "说明;\n第二行",
+			URL:         "https://example.com/homework/1",
+			Start:       deadline.Add(-time.Hour),
+			End:         deadline,
+			Updated:     deadline.Add(-48 * time.Hour),
+			Alarms:      []time.Duration{24 * time.Hour, 30 * time.Minute},
+		}},
+	}
+	out := string(cal.Bytes())
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Error("有不是CRLF的换行")
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+	if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Errorf("首尾行 = %q, %q", lines[0], lines[len(lines)-1])
+	}
+
+	want := []string{
+		"X-WR-CALNAME:我的作业",
+		"BEGIN:VEVENT",
+		"UID:homework-1@homework-system",
+		"LAST-MODIFIED:20260227T155959Z",
+		"DTSTART:20260301T145959Z",
+		"DTEND:20260301T155959Z",
+		`SUMMARY:实验一\, 第二部分`,
+		`DESCRIPTION:说明\;\n第二行`,
+		"URL:https://example.com/homework/1",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		`DESCRIPTION:实验一\, 第二部分`,
+		"TRIGGER:-PT24H",
+		"END:VALARM",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		`DESCRIPTION:实验一\, 第二部分`,
+		"TRIGGER:-PT30M",
+		"END:VALARM",
+		"END:VEVENT",
+	}
+	// 按顺序出现（中间可以有DTSTAMP等其他行）
+	i := 0
+	for _, l := range lines {
+		if i < len(want) && l == want[i] {
+			i++
+		}
+	}
+	if i != len(want) {
+		t.Errorf("缺少或顺序不对：%q\n完整输出：\n%s", want[i], out)
+	}
+}
+
+func TestCalendarWithoutOptionalFields(t *testing.T) {
+	now := time.Now()
+	out := string((&Calendar{Events: []Event{{UID: "x", Summary: "s", Start: now, End: now}}}).Bytes())
+	for _, absent := range []string{"X-WR-CALNAME", "LAST-MODIFIED", "DESCRIPTION", "URL:", "VALARM"} {
+		if strings.Contains(out, absent) {
+			t.Errorf("没有设置时不应输出%s", absent)
+		}
+	}
+}
@@ -30,6 +30,15 @@ func Error(c *gin.Context, code errcode.ErrCode) {
 	})
 }
 
+// 错误响应并附带数据（版本冲突时返回最新数据）
+func ErrorWithData(c *gin.Context, code errcode.ErrCode, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Code:    code,
+		Message: code.Msg(),
+		Data:    data,
+	})
+}
+
 // 分页响应（作业列表、提交列表用）
 type PageResponse struct {
 	List     interface{} `json:"list"`
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package sandbox
+
+import (
+	"os/exec"
+)
+
+// 非unix系统没有进程组，只能杀掉直接子进程（沙箱主要部署在Linux上）
+func setProcessGroup(c *exec.Cmd) {}
+
+func killProcessGroup(c *exec.Cmd) {
+	if c.Process == nil {
+		return
+	}
+	_ = c.Process.Kill()
+}
@@ -0,0 +1,219 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnavailable 要求的隔离做不到（没有unshare、权限不够等），这种情况不运行命令
+var ErrUnavailable = errors.New("sandbox: 无法按要求隔离运行")
+
+// 运行限制
+type Limits struct {
+	Timeout    time.Duration // 墙钟时间
+	CPUSeconds int           // CPU时间（ulimit -t），0表示不限制
+	MemoryMB   int           // 数据段内存（ulimit -d），0表示不限制
+	Processes  int           // 运行用户的进程数（含线程，ulimit -u），防止fork炸弹；root不受限制，0表示不限制
+	FileSizeMB int           // 能写的单个文件大小（ulimit -f），0表示不限制
+	NoNetwork  bool          // 是否放到独立的网络命名空间里（需要系统有unshare）
+	OutputMax  int           // stdout/stderr各自最多保留多少字节，0表示默认64KB
+	// 以下两项需要以root运行服务：切换到非特权用户运行；在私有挂载命名空间里把根目录换成RootDir，
+	// 进程只能看到这个目录（此时Command.Path是RootDir里的路径，工作目录为/）
+	UID, GID int
+	RootDir  string
+}
+
+// 一次运行的配置
+type Command struct {
+	Dir   string   // 工作目录
+	Path  string   // 可执行文件
+	Args  []string // 参数
+	Env   []string // 环境变量（不继承服务进程的环境）
+	Stdin string
+}
+
+// 运行结果
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	TimedOut bool
+	Duration time.Duration
+}
+
+// 找unshare和判断是否root（测试时替换）
+var (
+	lookPath = exec.LookPath
+	geteuid  = os.Geteuid
+)
+
+// 输出超过上限后截断的提示
+const truncatedMark = "\n...(输出过长已截断)"
+
+// Run 在限制条件下运行命令：独立进程组、rlimit、可选断网和隔离，超时后整组杀掉；
+// 要求的隔离做不到时返回ErrUnavailable，不会退化成直接运行
+func Run(ctx context.Context, cmd Command, limits Limits) (*Result, error) {
+	if limits.Timeout <= 0 {
+		return nil, errors.New("sandbox: 必须设置超时时间")
+	}
+	outputMax := limits.OutputMax
+	if outputMax <= 0 {
+		outputMax = 64 * 1024
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+	defer cancel()
+
+	path, args, err := wrap(cmd, limits)
+	if err != nil {
+		return nil, err
+	}
+	c := exec.Command(path, args...)
+	c.Dir = cmd.Dir
+	c.Env = cmd.Env
+	c.Stdin = strings.NewReader(cmd.Stdin)
+	stdout := &limitedBuffer{max: outputMax}
+	stderr := &limitedBuffer{max: outputMax}
+	c.Stdout = stdout
+	c.Stderr = stderr
+	setProcessGroup(c)
+
+	start := time.Now()
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.Wait() }()
+
+	result := &Result{}
+	var waitErr error
+	select {
+	case waitErr = <-done:
+	case <-ctx.Done():
+		result.TimedOut = true
+		killProcessGroup(c)
+		waitErr = <-done
+	}
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case waitErr == nil:
+		result.ExitCode = 0
+	case errors.As(waitErr, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		return nil, waitErr
+	}
+	// unshare自己出错时（内核不允许创建命名空间等）命令根本没运行
+	if limits.isolated() && result.ExitCode != 0 && strings.HasPrefix(result.Stderr, "unshare: ") {
+		return nil, fmt.Errorf("%w：%s", ErrUnavailable, strings.TrimSpace(result.Stderr))
+	}
+	return result, nil
+}
+
+// isolated 是否要经过unshare运行
+func (l Limits) isolated() bool {
+	return l.NoNetwork || l.UID > 0 || l.RootDir != ""
+}
+
+// wrap 用sh设置ulimit后exec真正的命令；需要断网或隔离时中间再套一层unshare
+func wrap(cmd Command, limits Limits) (string, []string, error) {
+	script := ""
+	if limits.CPUSeconds > 0 {
+		script += "ulimit -t " + strconv.Itoa(limits.CPUSeconds) + "; "
+	}
+	// 用-d而不是-v：Go运行时启动时会预留很大的虚拟地址空间，限制-v会直接起不来
+	if limits.MemoryMB > 0 {
+		script += "ulimit -d " + strconv.Itoa(limits.MemoryMB*1024) + "; "
+	}
+	// bash用-u，dash用-p
+	if limits.Processes > 0 {
+		n := strconv.Itoa(limits.Processes)
+		script += "{ ulimit -u " + n + " 2>/dev/null || ulimit -p " + n + "; }; "
+	}
+	// sh的ulimit -f以512字节为单位
+	if limits.FileSizeMB > 0 {
+		script += "ulimit -f " + strconv.Itoa(limits.FileSizeMB*2048) + "; "
+	}
+	// 有一项设置失败就不运行，不会在没有限制的情况下运行
+	if script != "" {
+		script = "set -e; " + script
+	}
+	script += `exec "$@"`
+	if !limits.isolated() {
+		return "/bin/sh", append([]string{"-c", script, "sandbox", cmd.Path}, cmd.Args...), nil
+	}
+
+	// 1. 检查隔离条件
+	unshare, err := lookPath("unshare")
+	if err != nil {
+		return "", nil, fmt.Errorf("%w：找不到unshare", ErrUnavailable)
+	}
+	isRoot := geteuid() == 0
+	if (limits.UID > 0 || limits.RootDir != "") && !isRoot {
+		return "", nil, fmt.Errorf("%w：切换用户和根目录需要以root运行", ErrUnavailable)
+	}
+	// 换了根目录还是root的话可以再chroot逃出去
+	if limits.RootDir != "" && (limits.UID <= 0 || limits.GID <= 0) {
+		return "", nil, fmt.Errorf("%w：换根目录时必须指定非特权用户", ErrUnavailable)
+	}
+
+	// 2. sh设置ulimit后exec unshare，unshare建好命名空间、换根、降权后再exec真正的命令
+	//    （rlimit跨exec继承；不带--fork，进程组还是同一个，超时能整组杀掉）
+	var flags []string
+	if !isRoot {
+		// -r映射成root才能在非特权下创建网络命名空间
+		flags = append(flags, "-r")
+	}
+	if limits.NoNetwork {
+		// 新命名空间里只有一个未启用的lo
+		flags = append(flags, "-n")
+	}
+	if limits.RootDir != "" {
+		flags = append(flags, "-m", "--propagation", "private", "--root", limits.RootDir, "--wd", "/")
+	}
+	if limits.UID > 0 {
+		flags = append(flags, "--setgid", strconv.Itoa(limits.GID), "--setuid", strconv.Itoa(limits.UID))
+	}
+	args := append([]string{"-c", script, "sandbox", unshare}, flags...)
+	args = append(append(args, "--", cmd.Path), cmd.Args...)
+	return "/bin/sh", args, nil
+}
+
+// limitedBuffer 超过上限后丢弃后续输出，防止死循环打印撑爆内存
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.max - b.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			b.buf.Write(p[:remain])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + truncatedMark
+	}
+	return b.buf.String()
+}
@@ -0,0 +1,126 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// stubEnv 替换找unshare和判断root的函数，测试结束后恢复
+func stubEnv(t *testing.T, hasUnshare bool, euid int) {
+	t.Helper()
+	oldLookPath, oldGeteuid := lookPath, geteuid
+	lookPath = func(file string) (string, error) {
+		if !hasUnshare {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + file, nil
+	}
+	geteuid = func() int { return euid }
+	t.Cleanup(func() { lookPath, geteuid = oldLookPath, oldGeteuid })
+}
+
+func TestWrap(t *testing.T) {
+	cmd := Command{Path: "/job/main", Args: []string{"-v", "x"}}
+	tests := []struct {
+		name   string
+		limits Limits
+		euid   int
+		script string
+		prog   []string // sh -c script sandbox 之后的部分
+	}{
+		{"没有限制", Limits{}, 1000, `exec "$@"`,
+			[]string{"/job/main", "-v", "x"}},
+		{"全部rlimit", Limits{CPUSeconds: 5, MemoryMB: 512, Processes: 64, FileSizeMB: 2}, 1000,
+			`set -e; ulimit -t 5; ulimit -d 524288; { ulimit -u 64 2>/dev/null || ulimit -p 64; }; ulimit -f 4096; exec "$@"`,
+			[]string{"/job/main", "-v", "x"}},
+		{"非root断网", Limits{CPUSeconds: 1, NoNetwork: true}, 1000, `set -e; ulimit -t 1; exec "$@"`,
+			[]string{"/usr/bin/unshare", "-r", "-n", "--", "/job/main", "-v", "x"}},
+		{"root断网", Limits{NoNetwork: true}, 0, `exec "$@"`,
+			[]string{"/usr/bin/unshare", "-n", "--", "/job/main", "-v", "x"}},
+		{"root切换用户", Limits{Processes: 8, UID: 65534, GID: 65533}, 0, `set -e; { ulimit -u 8 2>/dev/null || ulimit -p 8; }; exec "$@"`,
+			[]string{"/usr/bin/unshare", "--setgid", "65533", "--setuid", "65534", "--", "/job/main", "-v", "x"}},
+		{"root换根断网", Limits{FileSizeMB: 1, NoNetwork: true, UID: 65534, GID: 65534, RootDir: "/job/root"}, 0,
+			`set -e; ulimit -f 2048; exec "$@"`,
+			[]string{"/usr/bin/unshare", "-n", "-m", "--propagation", "private", "--root", "/job/root", "--wd", "/",
+				"--setgid", "65534", "--setuid", "65534", "--", "/job/main", "-v", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubEnv(t, true, tt.euid)
+			path, args, err := wrap(cmd, tt.limits)
+			if err != nil {
+				t.Fatalf("wrap: %v", err)
+			}
+			if path != "/bin/sh" || len(args) < 3 || args[0] != "-c" || args[2] != "sandbox" {
+				t.Fatalf("wrap = %q %q", path, args)
+			}
+			if args[1] != tt.script {
+				t.Errorf("script = %q, want %q", args[1], tt.script)
+			}
+			if !reflect.DeepEqual(args[3:], tt.prog) {
+				t.Errorf("命令 = %q, want %q", args[3:], tt.prog)
+			}
+		})
+	}
+}
+
+func TestWrapUnavailable(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     Limits
+		hasUnshare bool
+		euid       int
+	}{
+		{"没有unshare", Limits{NoNetwork: true}, false, 0},
+		{"非root切换用户", Limits{UID: 65534, GID: 65534}, true, 1000},
+		{"非root换根", Limits{UID: 65534, GID: 65534, RootDir: "/job/root"}, true, 1000},
+		{"换根不降权", Limits{RootDir: "/job/root"}, true, 0},
+		{"换根没有组", Limits{UID: 65534, RootDir: "/job/root"}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubEnv(t, tt.hasUnshare, tt.euid)
+			if _, _, err := wrap(Command{Path: "/job/main"}, tt.limits); !errors.Is(err, ErrUnavailable) {
+				t.Errorf("err = %v, want ErrUnavailable", err)
+			}
+		})
+	}
+	// 不需要隔离时不找unshare
+	stubEnv(t, false, 1000)
+	if _, _, err := wrap(Command{Path: "/job/main"}, Limits{CPUSeconds: 1}); err != nil {
+		t.Errorf("不需要隔离时不应报错：%v", err)
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{max: 5}
+	for _, s := range []string{"ab", "cde", ""} {
+		if n, err := b.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if b.String() != "abcde" {
+		t.Errorf("刚好到上限不算截断：%q", b.String())
+	}
+	// 超过上限的部分丢掉，但Write照样报告全部写入，子进程不会因此出错
+	if n, err := b.Write([]byte("fg")); n != 2 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if b.String() != "abcde"+truncatedMark {
+		t.Errorf("String = %q", b.String())
+	}
+
+	b = &limitedBuffer{max: 3}
+	b.Write([]byte("abcdef"))
+	if b.String() != "abc"+truncatedMark {
+		t.Errorf("一次写超 = %q", b.String())
+	}
+}
+
+func TestRunRequiresTimeout(t *testing.T) {
+	if _, err := Run(context.Background(), Command{Path: "/bin/true"}, Limits{}); err == nil {
+		t.Error("没有超时时间应该报错")
+	}
+}
@@ -0,0 +1,92 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTimeoutKillsProcessGroup(t *testing.T) {
+	// 子shell在后台不停往文件里写，超时后整组被杀掉，文件不再变长
+	out := filepath.Join(t.TempDir(), "tick")
+	script := `(while :; do echo x >> "$1"; sleep 0.01; done) & wait`
+	res, err := Run(context.Background(), Command{Path: "/bin/sh", Args: []string{"-c", script, "sh", out}},
+		Limits{Timeout: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !res.TimedOut || res.ExitCode == 0 {
+		t.Errorf("TimedOut = %v, ExitCode = %d", res.TimedOut, res.ExitCode)
+	}
+	before, err := os.Stat(out)
+	if err != nil {
+		t.Fatalf("后台进程没有运行：%v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	after, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() != before.Size() {
+		t.Errorf("超时后后台进程还在写：%d -> %d", before.Size(), after.Size())
+	}
+}
+
+func TestRunOutputMax(t *testing.T) {
+	res, err := Run(context.Background(), Command{Path: "/bin/sh", Args: []string{"-c", "yes | head -c 100000; yes e | head -c 10 >&2"}},
+		Limits{Timeout: 5 * time.Second, OutputMax: 1000})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Stdout) != 1000+len(truncatedMark) || !strings.HasSuffix(res.Stdout, truncatedMark) {
+		t.Errorf("stdout %d字节，结尾%q", len(res.Stdout), res.Stdout[max(0, len(res.Stdout)-40):])
+	}
+	if res.Stderr != "e\ne\ne\ne\ne\n" {
+		t.Errorf("没超上限的stderr不应截断：%q", res.Stderr)
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	limits := Limits{Timeout: 5 * time.Second, CPUSeconds: 3, MemoryMB: 64, Processes: 32, FileSizeMB: 1}
+	res, err := Run(context.Background(), Command{Path: "/bin/sh", Args: []string{"-c", "ulimit -t; ulimit -d; { ulimit -u 2>/dev/null || ulimit -p; }; ulimit -f"}}, limits)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := strings.Fields(res.Stdout); strings.Join(got, " ") != "3 65536 32 2048" {
+		t.Errorf("ulimit = %q", got)
+	}
+
+	// 超过文件大小限制的写入失败
+	out := filepath.Join(t.TempDir(), "big")
+	res, err = Run(context.Background(), Command{Path: "/bin/sh", Args: []string{"-c", `head -c 2097152 /dev/zero > "$1"`, "sh", out}}, limits)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.ExitCode == 0 {
+		t.Error("写2MB文件应该失败")
+	}
+	if info, err := os.Stat(out); err != nil || info.Size() > 1<<20 {
+		t.Errorf("文件大小超过了限制：%v %v", info, err)
+	}
+}
+
+func TestRunExitCodeAndEnv(t *testing.T) {
+	res, err := Run(context.Background(), Command{
+		Dir:   t.TempDir(),
+		Path:  "/bin/sh",
+		Args:  []string{"-c", `read line; echo "$line $FOO"; exit 3`},
+		Env:   []string{"FOO=bar"},
+		Stdin: "hello\n",
+	}, Limits{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.ExitCode != 3 || res.Stdout != "hello bar\n" || res.TimedOut {
+		t.Errorf("res = %+v", res)
+	}
+}
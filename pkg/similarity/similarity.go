@@ -0,0 +1,148 @@
+package similarity
+
+import (
+	"hash/fnv"
+)
+
+// Fingerprint winnowing选出的指纹，Pos是k-gram第一个词的下标
+type Fingerprint struct {
+	Hash uint64
+	Pos  int
+}
+
+// Document 切词并计算好指纹的文档
+type Document struct {
+	Tokens []Token
+	Prints []Fingerprint
+	k      int
+}
+
+// Region 原文中匹配上的一段（字节位置，左闭右开）
+type Region struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Result 两份文档的比较结果
+type Result struct {
+	Score    float64  // 共同指纹数 / 较小文档的指纹数（整份抄进更长的作业里也能发现）
+	Jaccard  float64  // 共同指纹数 / 指纹并集
+	RegionsA []Region // A中和B相同的片段
+	RegionsB []Region // B中和A相同的片段
+}
+
+// NewDocument 切词后按k-gram哈希做winnowing（窗口w），k、w越大越不容易误报
+func NewDocument(text string, code bool, k, w int) *Document {
+	doc := &Document{Tokens: Tokenize(text, code), k: k}
+	if len(doc.Tokens) < k {
+		return doc
+	}
+	hashes := make([]uint64, len(doc.Tokens)-k+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, t := range doc.Tokens[i : i+k] {
+			h.Write([]byte(t.Text))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+	doc.Prints = winnow(hashes, w)
+	return doc
+}
+
+// winnow 每个长度为w的窗口选最小的哈希（相同时取最右边的），连续窗口选中同一个位置只记一次
+func winnow(hashes []uint64, w int) []Fingerprint {
+	if len(hashes) == 0 {
+		return nil
+	}
+	if w <= 1 || len(hashes) <= w {
+		// 文档太短时窗口退化成整个文档
+		w = min(max(w, 1), len(hashes))
+	}
+	var prints []Fingerprint
+	last := -1
+	for start := 0; start+w <= len(hashes); start++ {
+		minPos := start
+		for i := start; i < start+w; i++ {
+			if hashes[i] <= hashes[minPos] {
+				minPos = i
+			}
+		}
+		if minPos != last {
+			prints = append(prints, Fingerprint{Hash: hashes[minPos], Pos: minPos})
+			last = minPos
+		}
+	}
+	return prints
+}
+
+// Compare 比较两份文档（k要相同）
+func Compare(a, b *Document) Result {
+	posA := positions(a.Prints)
+	posB := positions(b.Prints)
+	if len(posA) == 0 || len(posB) == 0 {
+		return Result{}
+	}
+
+	// 1. 共同指纹
+	common := 0
+	matchedA := make([]bool, len(a.Tokens))
+	matchedB := make([]bool, len(b.Tokens))
+	for hash, pa := range posA {
+		pb, ok := posB[hash]
+		if !ok {
+			continue
+		}
+		common++
+		markTokens(matchedA, pa, a.k)
+		markTokens(matchedB, pb, b.k)
+	}
+
+	// 2. 相似度
+	result := Result{
+		Score:   float64(common) / float64(min(len(posA), len(posB))),
+		Jaccard: float64(common) / float64(len(posA)+len(posB)-common),
+	}
+	if common == 0 {
+		return result
+	}
+
+	// 3. 把匹配上的词合并成原文中的片段
+	result.RegionsA = regions(a.Tokens, matchedA)
+	result.RegionsB = regions(b.Tokens, matchedB)
+	return result
+}
+
+// positions 指纹哈希 -> 出现的位置
+func positions(prints []Fingerprint) map[uint64][]int {
+	m := make(map[uint64][]int, len(prints))
+	for _, p := range prints {
+		m[p.Hash] = append(m[p.Hash], p.Pos)
+	}
+	return m
+}
+
+// markTokens 标记k-gram覆盖的词
+func markTokens(matched []bool, starts []int, k int) {
+	for _, s := range starts {
+		for i := s; i < s+k && i < len(matched); i++ {
+			matched[i] = true
+		}
+	}
+}
+
+// regions 连续标记的词合并成一个片段
+func regions(tokens []Token, matched []bool) []Region {
+	var list []Region
+	for i := 0; i < len(tokens); i++ {
+		if !matched[i] {
+			continue
+		}
+		start := i
+		for i+1 < len(tokens) && matched[i+1] {
+			i++
+		}
+		list = append(list, Region{Start: tokens[start].Start, End: tokens[i].End})
+	}
+	return list
+}
@@ -0,0 +1,181 @@
+package similarity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWinnow(t *testing.T) {
+	tests := []struct {
+		name   string
+		hashes []uint64
+		w      int
+		want   []Fingerprint
+	}{
+		{"空", nil, 4, nil},
+		{"比窗口短时取整个文档的最小值", []uint64{5, 3, 7}, 4, []Fingerprint{{3, 1}}},
+		{"窗口为1时每个都选", []uint64{5, 3, 7}, 1, []Fingerprint{{5, 0}, {3, 1}, {7, 2}}},
+		{"连续窗口选中同一位置只记一次", []uint64{77, 74, 42, 17, 98, 50, 17, 98, 8, 88, 67, 39, 77, 74, 42, 17, 98}, 4,
+			[]Fingerprint{{17, 3}, {17, 6}, {8, 8}, {39, 11}, {17, 15}}},
+		{"相同时取最右边的", []uint64{1, 1, 1, 1}, 2, []Fingerprint{{1, 1}, {1, 2}, {1, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := winnow(tt.hashes, tt.w); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("winnow = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		code bool
+		want []string
+	}{
+		{"代码归一化标识符、数字和字符串", `x := foo("hi", 42) // 注释`, true,
+			[]string{"ID", ":", "=", "ID", "(", "STR", ",", "NUM", ")"}},
+		{"代码保留关键字并去掉块注释", "func f() { /* c */ return nil }", true,
+			[]string{"func", "ID", "(", ")", "{", "return", "nil", "}"}},
+		{"未结束的字符串到结尾", `s = "abc`, true, []string{"ID", "=", "STR"}},
+		{"文本英文转小写、中文按字", "Hello, World！作业", false, []string{"hello", "world", "作", "业"}},
+		{"文本去掉标点", "a-b  c.", false, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := Tokenize(tt.text, tt.code)
+			got := make([]string, len(tokens))
+			for i, tok := range tokens {
+				got[i] = tok.Text
+				if tok.Start < 0 || tok.End > len(tt.text) || tok.Start >= tok.End {
+					t.Errorf("token %q 位置不对：[%d,%d)", tok.Text, tok.Start, tok.End)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLooksLikeCode(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"package main\n\nfunc main() {\n}", true},
+		{"def f(x):\n    return x", true},
+		{"这是一篇读书报告，不是代码。", false},
+		{"return to sender", false},
+	}
+	for _, tt := range tests {
+		if got := LooksLikeCode(tt.text); got != tt.want {
+			t.Errorf("LooksLikeCode(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+const (
+	codeA = `package main
+
+func sum(nums []int) int {
+	total := 0
+	for _, n := range nums {
+		total += n
+	}
+	return total
+}
+
+func main() {
+	println(sum([]int{1, 2, 3}))
+}`
+	// 只改了变量名和注释
+	codeRenamed = `package main
+
+// 求和
+func add(values []int) int {
+	acc := 0
+	for _, v := range values {
+		acc += v
+	}
+	return acc
+}
+
+func main() {
+	println(add([]int{4, 5, 6}))
+}`
+	codeOther = `package main
+
+type stack struct{ items []string }
+
+func (s *stack) push(x string) { s.items = append(s.items, x) }
+
+func (s *stack) pop() (string, bool) {
+	if len(s.items) == 0 {
+		return "", false
+	}
+	x := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return x, true
+}`
+)
+
+func TestCompare(t *testing.T) {
+	const k, w = 5, 4
+	essay := strings.Repeat("学生独立完成的作业内容各不相同", 2)
+	tests := []struct {
+		name      string
+		a, b      string
+		code      bool
+		minScore  float64
+		maxScore  float64
+		exactJacc float64 // <0表示不检查
+	}{
+		{"完全相同", codeA, codeA, true, 1, 1, 1},
+		{"改变量名", codeA, codeRenamed, true, 1, 1, 1},
+		{"无关代码", codeA, codeOther, true, 0, 0.5, -1},
+		{"太短没有指纹", "a b", "a b", false, 0, 0, 0},
+		{"整段抄进更长的文章", essay, essay + "另外又写了很多自己的内容补充说明", false, 1, 1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Compare(NewDocument(tt.a, tt.code, k, w), NewDocument(tt.b, tt.code, k, w))
+			if r.Score < tt.minScore || r.Score > tt.maxScore {
+				t.Errorf("Score = %v, want [%v, %v]", r.Score, tt.minScore, tt.maxScore)
+			}
+			if tt.exactJacc >= 0 && r.Jaccard != tt.exactJacc {
+				t.Errorf("Jaccard = %v, want %v", r.Jaccard, tt.exactJacc)
+			}
+			if r.Jaccard > r.Score {
+				t.Errorf("Jaccard %v 不应大于 Score %v", r.Jaccard, r.Score)
+			}
+			if r.Score > 0 && (len(r.RegionsA) == 0 || len(r.RegionsB) == 0) {
+				t.Errorf("有共同指纹但没有匹配片段")
+			}
+		})
+	}
+}
+
+func TestCompareRegions(t *testing.T) {
+	// 只有中间一段相同，片段应该落在这一段里
+	shared := "the quick brown fox jumps over the lazy dog"
+	a := "alpha beta gamma delta " + shared + " epsilon zeta eta theta"
+	b := "one two three four five six " + shared
+	r := Compare(NewDocument(a, false, 3, 2), NewDocument(b, false, 3, 2))
+	if r.Score == 0 {
+		t.Fatal("没有找到相同片段")
+	}
+	check := func(name, text string, regions []Region) {
+		start := strings.Index(text, shared)
+		for _, reg := range regions {
+			if reg.Start < start || reg.End > start+len(shared) {
+				t.Errorf("%s 片段 %q 超出了相同的部分", name, text[reg.Start:reg.End])
+			}
+		}
+	}
+	check("A", a, r.RegionsA)
+	check("B", b, r.RegionsB)
+}
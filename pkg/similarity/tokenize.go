@@ -0,0 +1,137 @@
+package similarity
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token 归一化后的词，Start/End是在原文中的字节位置（用于高亮）
+type Token struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// 代码里保留原样的关键字（其余标识符统一替换，改变量名骗不过去）
+var keywords = map[string]bool{
+	// Go
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+	"defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true,
+	"range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true, "var": true,
+	// 其他常见语言
+	"class": true, "def": true, "function": true, "let": true, "new": true, "while": true,
+	"try": true, "catch": true, "throw": true, "public": true, "private": true, "static": true,
+	"void": true, "int": true, "string": true, "bool": true, "true": true, "false": true, "nil": true, "null": true,
+}
+
+// LooksLikeCode 粗略判断是不是代码（决定用哪种归一化）
+func LooksLikeCode(text string) bool {
+	score := 0
+	for _, marker := range []string{"package ", "func ", "import ", "return ", "{\n", "};", ":=", "def ", "function ", "#include"} {
+		if strings.Contains(text, marker) {
+			score++
+		}
+	}
+	return score >= 2
+}
+
+// Tokenize 按代码或文本规则切词
+func Tokenize(text string, code bool) []Token {
+	if code {
+		return tokenizeCode(text)
+	}
+	return tokenizeText(text)
+}
+
+// tokenizeCode 去掉注释和空白，标识符→ID、数字→NUM、字符串→STR，关键字和符号保留
+func tokenizeCode(src string) []Token {
+	var tokens []Token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//") || c == '#':
+			// 行注释（#也当注释，兼容Python/Shell；C的#include一起忽略影响不大）
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 4
+			}
+		case c == '"' || c == '\'' || c == '`':
+			start := i
+			i++
+			for i < len(src) && src[i] != c {
+				if src[i] == '\\' && c != '`' {
+					i++
+				}
+				i++
+			}
+			i++
+			if i > len(src) {
+				i = len(src)
+			}
+			tokens = append(tokens, Token{Text: "STR", Start: start, End: i})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && (isIdentByte(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, Token{Text: "NUM", Start: start, End: i})
+		case isIdentByte(c) || c >= utf8.RuneSelf:
+			start := i
+			for i < len(src) && (isIdentByte(src[i]) || src[i] >= utf8.RuneSelf) {
+				i++
+			}
+			word := src[start:i]
+			if keywords[word] {
+				tokens = append(tokens, Token{Text: word, Start: start, End: i})
+			} else {
+				tokens = append(tokens, Token{Text: "ID", Start: start, End: i})
+			}
+		default:
+			tokens = append(tokens, Token{Text: string(c), Start: i, End: i + 1})
+			i++
+		}
+	}
+	return tokens
+}
+
+// tokenizeText 英文按单词（转小写），中文按单字，标点忽略
+func tokenizeText(text string) []Token {
+	var tokens []Token
+	i := 0
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case unicode.Is(unicode.Han, r):
+			tokens = append(tokens, Token{Text: string(r), Start: i, End: i + size})
+			i += size
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			for i < len(text) {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if unicode.Is(unicode.Han, r) || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, Token{Text: strings.ToLower(text[start:i]), Start: start, End: i})
+		default:
+			i += size
+		}
+	}
+	return tokens
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
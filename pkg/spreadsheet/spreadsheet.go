@@ -0,0 +1,124 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// 支持的表格格式
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ErrUnsupported 不是CSV或XLSX
+var ErrUnsupported = errors.New("spreadsheet: 不支持的文件格式")
+
+// 表格的一行（Line是表格里的行号，从1开始，报错时给用户看）
+type Row struct {
+	Line  int
+	Cells []string
+}
+
+// Cell 取第i列（没有这一列时为空）
+func (r Row) Cell(i int) string {
+	if i < 0 || i >= len(r.Cells) {
+		return ""
+	}
+	return r.Cells[i]
+}
+
+// DetectFormat 按内容识别格式（XLSX是zip包），识别不出来时看扩展名
+func DetectFormat(name string, data []byte) (string, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatXLSX, nil
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".txt", "":
+		return FormatCSV, nil
+	default:
+		return "", ErrUnsupported
+	}
+}
+
+// Read 读取表格的所有行（XLSX只读第一个工作表），单元格去掉首尾空白，全空的行跳过
+func Read(name string, data []byte) (string, []Row, error) {
+	format, err := DetectFormat(name, data)
+	if err != nil {
+		return "", nil, err
+	}
+	var rows []Row
+	if format == FormatXLSX {
+		rows, err = readXLSX(data)
+	} else {
+		rows, err = readCSV(data)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return format, cleanRows(rows), nil
+}
+
+// readCSV 读取CSV（Excel导出的带BOM，每行列数可以不同）
+func readCSV(data []byte) ([]Row, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, Row{Line: line, Cells: record})
+	}
+}
+
+// cleanRows 去掉单元格首尾空白和全空的行
+func cleanRows(rows []Row) []Row {
+	result := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		empty := true
+		for i := range row.Cells {
+			row.Cells[i] = strings.TrimSpace(row.Cells[i])
+			if row.Cells[i] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// WriteCSV 导出CSV：带BOM（Excel按UTF-8打开），以=+-@开头的单元格前加'，防止被Excel当成公式执行
+func WriteCSV(w io.Writer, rows [][]string) error {
+	if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+				cell = "'" + cell
+			}
+			cells[i] = cell
+		}
+		if err := writer.Write(cells); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
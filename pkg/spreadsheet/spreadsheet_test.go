@@ -0,0 +1,226 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+		err  error
+	}{
+		{"grades.csv", "a,b", FormatCSV, nil},
+		{"grades.TXT", "a,b", FormatCSV, nil},
+		{"grades", "a,b", FormatCSV, nil},
+		{"grades.csv", "PK\x03\x04...", FormatXLSX, nil},
+		{"grades.xlsx", "PK\x03\x04...", FormatXLSX, nil},
+		{"grades.xls", "\xd0\xcf\x11\xe0", "", ErrUnsupported},
+		{"grades.pdf", "%PDF", "", ErrUnsupported},
+	}
+	for _, tt := range tests {
+		got, err := DetectFormat(tt.name, []byte(tt.data))
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("DetectFormat(%q) = %q, %v; want %q, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Row
+	}{
+		{"普通", "username,score\nalice,90\nbob,85\n",
+			[]Row{{1, []string{"username", "score"}}, {2, []string{"alice", "90"}}, {3, []string{"bob", "85"}}}},
+		{"Excel的BOM和CRLF", "\xef\xbb\xbfusername,score\r\nalice,90\r\n",
+			[]Row{{1, []string{"username", "score"}}, {2, []string{"alice", "90"}}}},
+		{"去空白、跳过空行、列数不同", "a , b\n\n , \nc\n",
+			[]Row{{1, []string{"a", "b"}}, {4, []string{"c"}}}},
+		{"引号里的逗号和换行", "name,comment\nalice,\"好,很好\n继续加油\"\nbob,ok\n",
+			[]Row{{1, []string{"name", "comment"}}, {2, []string{"alice", "好,很好\n继续加油"}}, {4, []string{"bob", "ok"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, rows, err := Read("grades.csv", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if format != FormatCSV {
+				t.Errorf("format = %q", format)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("rows = %v, want %v", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSVRoundTrip(t *testing.T) {
+	input := [][]string{
+		{"学号", "姓名", "成绩", "评语"},
+		{"2024001", "张三", "90", "不错, 继续保持"},
+		{"2024002", "=HYPERLINK(\"http://evil\")", "+1", "-1"},
+		{"2024003", "@SUM(A1)", "", "多行\n评语"},
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, input); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("\xef\xbb\xbf")) {
+		t.Error("没有BOM")
+	}
+
+	_, rows, err := Read("export.csv", buf.Bytes())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	want := [][]string{
+		input[0],
+		input[1],
+		{"2024002", "'=HYPERLINK(\"http://evil\")", "'+1", "'-1"},
+		{"2024003", "'@SUM(A1)", "", "多行\n评语"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("读回%d行，want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		if !reflect.DeepEqual(row.Cells, want[i]) {
+			t.Errorf("第%d行 = %q, want %q", i, row.Cells, want[i])
+		}
+	}
+}
+
+// buildXLSX 用给定的包内文件生成最小的xlsx
+func buildXLSX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	testWorkbook = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="成绩" sheetId="1" r:id="rId2"/><sheet name="其他" sheetId="2" r:id="rId1"/></sheets>
+</workbook>`
+	testRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Target="/xl/worksheets/grades.xml"/>
+</Relationships>`
+	testShared = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>username</t></si><si><t>score</t></si><si><r><t>ali</t></r><r><t>ce</t></r></si>
+</sst>`
+	testGradesSheet = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="inlineStr"><is><t>passed</t></is></c></row>
+<row r="3"><c r="A3" t="s"><v>2</v></c><c r="B3"><v>92.5</v></c><c r="D3" t="b"><v>1</v></c></row>
+<row r="4"><c r="A4" t="inlineStr"><is><t>  </t></is></c></row>
+<row r="5"><c t="inlineStr"><is><t>bob</t></is></c><c><v>80</v></c></row>
+</sheetData></worksheet>`
+	testOtherSheet = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>不是第一个工作表</t></is></c></row>
+</sheetData></worksheet>`
+)
+
+func TestReadXLSX(t *testing.T) {
+	data := buildXLSX(t, map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testRels,
+		"xl/sharedStrings.xml":       testShared,
+		"xl/worksheets/grades.xml":   testGradesSheet,
+		"xl/worksheets/sheet1.xml":   testOtherSheet,
+	})
+	format, rows, err := Read("成绩.xlsx", data)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if format != FormatXLSX {
+		t.Errorf("format = %q", format)
+	}
+	want := []Row{
+		{1, []string{"username", "score", "", "passed"}},
+		{3, []string{"alice", "92.5", "", "TRUE"}},
+		{5, []string{"bob", "80"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestReadXLSXErrors(t *testing.T) {
+	sheet := func(cells string) string {
+		return `<worksheet><sheetData><row r="1">` + cells + `</row></sheetData></worksheet>`
+	}
+	tests := []struct {
+		name  string
+		parts map[string]string
+	}{
+		{"没有工作表", map[string]string{"xl/styles.xml": "<styleSheet/>"}},
+		{"共享字符串越界", map[string]string{"xl/worksheets/sheet1.xml": sheet(`<c r="A1" t="s"><v>3</v></c>`)}},
+		{"单元格引用不对", map[string]string{"xl/worksheets/sheet1.xml": sheet(`<c r="1A"><v>1</v></c>`)}},
+		{"XML损坏", map[string]string{"xl/worksheets/sheet1.xml": "<worksheet><sheetData>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Read("a.xlsx", buildXLSX(t, tt.parts)); err == nil {
+				t.Error("应该报错")
+			}
+		})
+	}
+	if _, _, err := Read("a.xlsx", []byte("PK\x03\x04不是zip")); err == nil {
+		t.Error("损坏的zip应该报错")
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"A1", 0},
+		{"B12", 1},
+		{"Z3", 25},
+		{"AA1", 26},
+		{"AB12", 27},
+		{"XFD1", 16383},
+		{"XFE1", -1},
+		{"AAAA1", -1},
+		{"1", -1},
+		{"", -1},
+		{"a1", -1},
+	}
+	for _, tt := range tests {
+		if got := columnIndex(tt.ref); got != tt.want {
+			t.Errorf("columnIndex(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestRowCell(t *testing.T) {
+	row := Row{Line: 1, Cells: []string{"a", "b"}}
+	for i, want := range map[int]string{-1: "", 0: "a", 1: "b", 2: ""} {
+		if got := row.Cell(i); got != want {
+			t.Errorf("Cell(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
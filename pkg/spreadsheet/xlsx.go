@@ -0,0 +1,216 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// 解压后单个文件的大小上限（防止压缩炸弹）
+const maxXLSXPartSize = 64 << 20
+
+// workbook.xml里的工作表列表（按显示顺序）
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// workbook.xml.rels：工作表ID到文件路径的映射
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// 共享字符串或内联字符串（富文本时分成多段）
+type xlsxString struct {
+	T    *string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s *xlsxString) text() string {
+	if s.T != nil {
+		return *s.T
+	}
+	var b strings.Builder
+	for _, r := range s.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxString `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string      `xml:"r,attr"`
+			T  string      `xml:"t,attr"`
+			V  string      `xml:"v"`
+			IS *xlsxString `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX 读取第一个工作表
+func readXLSX(data []byte) ([]Row, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	// 1. 找到第一个工作表的文件
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 共享字符串（全是数字的表格可以没有）
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodePart(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. 逐行读单元格，按单元格引用（如C3）放到对应的列，中间空着的列补空字符串
+	var sheet xlsxWorksheet
+	if err := decodePart(files[sheetPath], &sheet); err != nil {
+		return nil, err
+	}
+	rows := make([]Row, 0, len(sheet.Rows))
+	for i, r := range sheet.Rows {
+		line := r.R
+		if line == 0 {
+			line = i + 1
+		}
+		var cells []string
+		for j, c := range r.Cells {
+			col := j
+			if c.R != "" {
+				col = columnIndex(c.R)
+			}
+			if col < 0 {
+				return nil, errors.New("spreadsheet: 单元格引用不正确")
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			switch c.T {
+			case "s":
+				idx, err := strconv.Atoi(c.V)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, errors.New("spreadsheet: 共享字符串不存在")
+				}
+				cells[col] = shared.Items[idx].text()
+			case "inlineStr":
+				if c.IS != nil {
+					cells[col] = c.IS.text()
+				}
+			case "b":
+				if c.V == "1" {
+					cells[col] = "TRUE"
+				} else {
+					cells[col] = "FALSE"
+				}
+			default:
+				cells[col] = c.V
+			}
+		}
+		rows = append(rows, Row{Line: line, Cells: cells})
+	}
+	return rows, nil
+}
+
+// firstSheetPath 按workbook.xml和关系文件找到第一个工作表，找不到时用默认的sheet1.xml
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	wbFile, ok := files["xl/workbook.xml"]
+	relFile, relOK := files["xl/_rels/workbook.xml.rels"]
+	if ok && relOK {
+		var wb xlsxWorkbook
+		var rels xlsxRelationships
+		if err := decodePart(wbFile, &wb); err != nil {
+			return "", err
+		}
+		if err := decodePart(relFile, &rels); err != nil {
+			return "", err
+		}
+		if len(wb.Sheets) > 0 {
+			for _, rel := range rels.Relationships {
+				if rel.ID != wb.Sheets[0].RelID {
+					continue
+				}
+				// Target可以是相对xl/的路径，也可以是以/开头的包内绝对路径
+				target := rel.Target
+				if strings.HasPrefix(target, "/") {
+					target = strings.TrimPrefix(target, "/")
+				} else {
+					target = path.Join("xl", target)
+				}
+				if _, ok := files[target]; ok {
+					return target, nil
+				}
+			}
+		}
+	}
+	if _, ok := files[fallback]; ok {
+		return fallback, nil
+	}
+	return "", ErrUnsupported
+}
+
+// decodePart 解析包里的一个XML文件（限制解压后的大小）
+func decodePart(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	limited := &io.LimitedReader{R: rc, N: maxXLSXPartSize + 1}
+	if err := xml.NewDecoder(limited).Decode(v); err != nil {
+		return err
+	}
+	if limited.N <= 0 {
+		return errors.New("spreadsheet: 文件过大")
+	}
+	return nil
+}
+
+// columnIndex 单元格引用的列号（A1→0，AB12→27），格式不对返回-1
+func columnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch >= 'A' && ch <= 'Z' {
+			col = col*26 + int(ch-'A'+1)
+			n++
+			if n > 3 {
+				return -1
+			}
+			continue
+		}
+		break
+	}
+	// Excel最多16384列（XFD）
+	if n == 0 || col > 16384 {
+		return -1
+	}
+	return col - 1
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalRoundTrip(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "uploads")
+	l, err := NewLocal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "submissions/1/2/abc"
+
+	// 1. 写入后能读出，并且没有残留临时文件
+	if err := l.Put(ctx, key, strings.NewReader("v1"), 2, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := l.Put(ctx, key, strings.NewReader("v2"), 2, ""); err != nil {
+		t.Fatalf("覆盖Put: %v", err)
+	}
+	r, err := l.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "v2" {
+		t.Errorf("Get = %q, want v2", data)
+	}
+	entries, _ := os.ReadDir(filepath.Join(root, "submissions/1/2"))
+	if len(entries) != 1 {
+		t.Errorf("目录里有%d个文件，want 1", len(entries))
+	}
+
+	// 2. 删除后读不到，再删一次也不报错
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后Get err = %v, want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, key); err != nil {
+		t.Errorf("重复Delete: %v", err)
+	}
+
+	// 3. 非法key不能跳出根目录
+	for _, bad := range []string{"", "../secret", "a/../../b", "/etc/passwd", "a\\b"} {
+		if err := l.Put(ctx, bad, strings.NewReader("x"), 1, ""); !errors.Is(err, errInvalidKey) {
+			t.Errorf("Put(%q) err = %v, want errInvalidKey", bad, err)
+		}
+		if _, err := l.Get(ctx, bad); !errors.Is(err, errInvalidKey) {
+			t.Errorf("Get(%q) err = %v, want errInvalidKey", bad, err)
+		}
+	}
+}
+
+func TestVerifyDownload(t *testing.T) {
+	const secret = "test-secret"
+	now := time.Unix(1_700_000_000, 0)
+	expires := now.Add(5 * time.Minute).Unix()
+	sig := SignDownload(secret, 10, 20, expires)
+
+	tests := []struct {
+		name    string
+		secret  string
+		fileID  int64
+		userID  int64
+		expires int64
+		sig     string
+		now     time.Time
+		want    error
+	}{
+		{"有效", secret, 10, 20, expires, sig, now, nil},
+		{"刚好到期那一秒", secret, 10, 20, expires, sig, time.Unix(expires, 0), nil},
+		{"过期", secret, 10, 20, expires, sig, time.Unix(expires+1, 0), ErrURLExpired},
+		{"改了文件ID", secret, 11, 20, expires, sig, now, ErrBadSignature},
+		{"改了用户ID", secret, 10, 21, expires, sig, now, ErrBadSignature},
+		{"延长了有效期", secret, 10, 20, expires + 3600, sig, now, ErrBadSignature},
+		{"密钥不对", "other", 10, 20, expires, sig, now, ErrBadSignature},
+		{"签名为空", secret, 10, 20, expires, "", now, ErrBadSignature},
+		{"签名大小写不同", secret, 10, 20, expires, strings.ToUpper(sig), now, ErrBadSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyDownload(tt.secret, tt.fileID, tt.userID, tt.expires, tt.sig, tt.now); err != tt.want {
+				t.Errorf("VerifyDownload = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
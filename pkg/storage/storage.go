@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("storage: 文件不存在")
+
+// Storage 文件存储后端（本地磁盘、S3兼容对象存储）
+type Storage interface {
+	// Put 写入文件，size为内容长度（-1表示未知）
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取文件，调用方负责关闭
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey 只允许由字母、数字、-_.组成的多级路径，防止路径穿越
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// errInvalidKey 非法的文件key
+var errInvalidKey = errors.New("storage: 非法的文件key")
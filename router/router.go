@@ -12,9 +12,14 @@ func InitRouter() *gin.Engine {
 	// 公开接口（无需认证）
 	publicGroup := r.Group("/")
 	{
-		publicGroup.POST("/user/register", handler.Register)
+		// 注册、发布作业、提交作业支持Idempotency-Key，重试时返回第一次的响应
+		publicGroup.POST("/user/register", middleware.IdempotencyMiddleware(), handler.Register)
 		publicGroup.POST("/user/login", handler.Login)
 		publicGroup.POST("/user/refresh", handler.RefreshToken)
+		// 日历订阅（日历App带不了Token，凭链接里的令牌访问）
+		publicGroup.GET("/calendar/:token", handler.CalendarFeed)
+		// 文件下载（凭签名链接访问，链接由登录用户获取）
+		publicGroup.GET("/file/:id/download", handler.DownloadFile)
 	}
 
 	// 需要认证的接口（所有请求都要带AccessToken）
@@ -26,31 +31,188 @@ func InitRouter() *gin.Engine {
 		{
 			userGroup.GET("/profile", handler.GetProfile)
 			userGroup.DELETE("/account", handler.Logout)
+			// 日历订阅链接：生成/重置、撤销
+			userGroup.POST("/calendar", handler.ResetCalendarToken)
+			userGroup.DELETE("/calendar", handler.RevokeCalendarToken)
+			// 小登收到的交作业提醒
+			userGroup.GET("/reminders", middleware.StudentMiddleware(), handler.ListMyReminders)
+			userGroup.POST("/reminders/read", middleware.StudentMiddleware(), handler.MarkRemindersRead)
 		}
 		// 作业模块
 		homeworkGroup := authGroup.Group("/homework")
 		{
 			// 老登才能发布/修改/删除
-			homeworkGroup.POST("", middleware.AdminMiddleware(), handler.CreateHomework)
+			homeworkGroup.POST("", middleware.AdminMiddleware(), middleware.IdempotencyMiddleware(), handler.CreateHomework)
 			homeworkGroup.PUT("/:id", middleware.AdminMiddleware(), handler.UpdateHomework)
 			homeworkGroup.DELETE("/:id", middleware.AdminMiddleware(), handler.DeleteHomework)
 			// 所有人都能查列表和详情
 			homeworkGroup.GET("", handler.ListHomework)
 			homeworkGroup.GET("/:id", handler.GetHomework)
+			// 评分标准：老登设置/删除，所有人可查看
+			homeworkGroup.PUT("/:id/rubric", middleware.AdminMiddleware(), handler.SaveRubric)
+			homeworkGroup.DELETE("/:id/rubric", middleware.AdminMiddleware(), handler.DeleteRubric)
+			homeworkGroup.GET("/:id/rubric", handler.GetRubric)
+			// 成绩统计（按百分比换算）
+			homeworkGroup.GET("/:id/stats", middleware.AdminMiddleware(), handler.GetHomeworkStats)
+			// 未交名单：老登查看、导出、一键提醒
+			homeworkGroup.GET("/:id/missing", middleware.AdminMiddleware(), handler.ListMissingSubmissions)
+			homeworkGroup.GET("/:id/missing/export", middleware.AdminMiddleware(), handler.ExportMissingSubmissions)
+			homeworkGroup.POST("/:id/missing/remind", middleware.AdminMiddleware(), handler.RemindMissingStudents)
+			// 修改记录：老登查看每次修改和版本对比
+			homeworkGroup.GET("/:id/revisions", middleware.AdminMiddleware(), handler.ListHomeworkRevisions)
+			homeworkGroup.GET("/:id/revisions/diff", middleware.AdminMiddleware(), handler.DiffHomeworkRevisions)
+			// 个人延期
+			homeworkGroup.PUT("/:id/extension", middleware.AdminMiddleware(), handler.GrantExtension)
+			homeworkGroup.DELETE("/:id/extension/:student_id", middleware.AdminMiddleware(), handler.RevokeExtension)
+			// 前置作业
+			homeworkGroup.PUT("/:id/prerequisite", middleware.AdminMiddleware(), handler.SetPrerequisite)
+			// 小组作业：老登设置模式，老登分组或小登自行组队
+			homeworkGroup.PUT("/:id/group", middleware.AdminMiddleware(), handler.UpdateGroupSettings)
+			homeworkGroup.POST("/:id/team", handler.CreateTeam)
+			homeworkGroup.GET("/:id/team", middleware.AdminMiddleware(), handler.ListTeam)
+			homeworkGroup.GET("/:id/team/my", middleware.StudentMiddleware(), handler.GetMyTeam)
+			// 测验：老登组卷、看正确率，小登答题
+			homeworkGroup.PUT("/:id/quiz", middleware.AdminMiddleware(), handler.SaveQuiz)
+			homeworkGroup.GET("/:id/quiz", middleware.StudentMiddleware(), handler.GetQuiz)
+			homeworkGroup.GET("/:id/quiz/stats", middleware.AdminMiddleware(), handler.GetQuizStats)
+			// 草稿：小登自动保存，确认后转为正式提交
+			homeworkGroup.PUT("/:id/draft", middleware.StudentMiddleware(), handler.SaveDraft)
+			homeworkGroup.GET("/:id/draft", middleware.StudentMiddleware(), handler.GetDraft)
+			homeworkGroup.DELETE("/:id/draft", middleware.StudentMiddleware(), handler.DeleteDraft)
+			homeworkGroup.POST("/:id/draft/submit", middleware.StudentMiddleware(), handler.SubmitDraft)
+			// 自动评测：老登配置编程作业的测试用例
+			homeworkGroup.PUT("/:id/testcases", middleware.AdminMiddleware(), handler.SaveTestCases)
+			homeworkGroup.GET("/:id/testcases", middleware.AdminMiddleware(), handler.ListTestCases)
+			// 查重：老登查看可疑提交对、重新查重
+			homeworkGroup.GET("/:id/similarity", middleware.AdminMiddleware(), handler.ListSimilarityPairs)
+			homeworkGroup.POST("/:id/similarity/rescan", middleware.AdminMiddleware(), handler.RescanHomeworkSimilarity)
+			// 匿名批改：老登开关、查看身份查看记录
+			homeworkGroup.PUT("/:id/blind-grading", middleware.AdminMiddleware(), handler.SetBlindGrading)
+			homeworkGroup.GET("/:id/deanonymize-logs", middleware.AdminMiddleware(), handler.ListDeanonymizationLogs)
+			// 成绩发布：老登立即或预定发布、取消预定发布，发布前小登看不到批改结果
+			homeworkGroup.POST("/:id/grades/release", middleware.AdminMiddleware(), handler.ReleaseGrades)
+			homeworkGroup.DELETE("/:id/grades/release", middleware.AdminMiddleware(), handler.CancelGradeRelease)
+			// 互评：老登在截止后开启、提前结束、查看汇总
+			homeworkGroup.POST("/:id/peer-review", middleware.AdminMiddleware(), handler.StartPeerReview)
+			homeworkGroup.POST("/:id/peer-review/close", middleware.AdminMiddleware(), handler.ClosePeerReview)
+			homeworkGroup.GET("/:id/peer-review", middleware.AdminMiddleware(), handler.GetPeerReviewOverview)
+			// 批改分工：老登自动分配批改人、按队列领下一份
+			homeworkGroup.POST("/:id/review-assignments", middleware.AdminMiddleware(), handler.AssignReviewers)
+			homeworkGroup.GET("/:id/review/next", middleware.AdminMiddleware(), handler.NextUnreviewed)
+			// 批量导入成绩：老登上传CSV/XLSX（可先预览），查看导入记录
+			homeworkGroup.POST("/:id/grades/import", middleware.AdminMiddleware(), handler.ImportGrades)
+			homeworkGroup.GET("/:id/grades/imports", middleware.AdminMiddleware(), handler.ListGradeImports)
+		}
+		// 题库模块（老登维护）
+		questionGroup := authGroup.Group("/question")
+		questionGroup.Use(middleware.AdminMiddleware())
+		{
+			questionGroup.POST("", handler.CreateQuestion)
+			questionGroup.PUT("/:id", handler.UpdateQuestion)
+			questionGroup.DELETE("/:id", handler.DeleteQuestion)
+			questionGroup.GET("", handler.ListQuestion)
+		}
+		// 小组模块
+		teamGroup := authGroup.Group("/team")
+		{
+			teamGroup.PUT("/:id/members", middleware.AdminMiddleware(), handler.ReplaceTeamMembers)
+			teamGroup.PUT("/:id/adjustment", middleware.AdminMiddleware(), handler.SetMemberAdjustment)
+			teamGroup.DELETE("/:id", middleware.AdminMiddleware(), handler.DeleteTeam)
+			teamGroup.POST("/:id/join", middleware.StudentMiddleware(), handler.JoinTeam)
+			teamGroup.POST("/:id/leave", middleware.StudentMiddleware(), handler.LeaveTeam)
+		}
+		// 学习路线模块
+		trackGroup := authGroup.Group("/track")
+		{
+			trackGroup.POST("", middleware.AdminMiddleware(), handler.CreateTrack)
+			trackGroup.PUT("/:id", middleware.AdminMiddleware(), handler.UpdateTrack)
+			trackGroup.DELETE("/:id", middleware.AdminMiddleware(), handler.DeleteTrack)
+			trackGroup.GET("", handler.ListTrack)
+			trackGroup.GET("/:id", handler.GetTrack)
+			trackGroup.GET("/:id/progress", handler.GetTrackProgress)
+		}
+		// 文件模块：小登上传，查看权限和提交一致
+		fileGroup := authGroup.Group("/file")
+		{
+			fileGroup.POST("", middleware.StudentMiddleware(), handler.UploadFile)
+			fileGroup.GET("/:id/url", handler.GetFileURL)
+		}
+		// 查重模块（老登核查可疑提交对）
+		similarityGroup := authGroup.Group("/similarity")
+		similarityGroup.Use(middleware.AdminMiddleware())
+		{
+			similarityGroup.GET("/:id", handler.GetSimilarityPair)
+			similarityGroup.PUT("/:id/review", handler.ReviewSimilarityPair)
+		}
+		// 复核模块（老登按部门处理复核申请）
+		regradeGroup := authGroup.Group("/regrade")
+		regradeGroup.Use(middleware.AdminMiddleware())
+		{
+			regradeGroup.GET("", handler.ListRegradeQueue)
+			regradeGroup.PUT("/:id/resolve", handler.ResolveRegrade)
+		}
+		// 批改分工模块（老登查看自己名下的提交、维护导师关系）
+		reviewGroup := authGroup.Group("/review")
+		reviewGroup.Use(middleware.AdminMiddleware())
+		{
+			reviewGroup.GET("/claims", handler.ListMyClaims)
+			reviewGroup.PUT("/mentors", handler.SetMentor)
+			reviewGroup.GET("/mentors", handler.ListMentorships)
+		}
+		// 互评模块（小登匿名评同学的提交）
+		peerReviewGroup := authGroup.Group("/peer-review")
+		peerReviewGroup.Use(middleware.StudentMiddleware())
+		{
+			peerReviewGroup.GET("/tasks", handler.ListMyPeerReviewTasks)
+			peerReviewGroup.PUT("/tasks/:id", handler.SubmitPeerReview)
 		}
 		// 提交模块
 		submissionGroup := authGroup.Group("/submission")
 		{
 			// 小登才能提交
-			submissionGroup.POST("", middleware.StudentMiddleware(), handler.CreateSubmission)
+			submissionGroup.POST("", middleware.StudentMiddleware(), middleware.IdempotencyMiddleware(), handler.CreateSubmission)
+			submissionGroup.POST("/quiz", middleware.StudentMiddleware(), handler.SubmitQuiz)
+			// 仓库作业：小登提交仓库地址和分支，服务端克隆并固定到具体提交
+			submissionGroup.POST("/repository", middleware.StudentMiddleware(), handler.SubmitRepository)
+			submissionGroup.POST("/:id/repository", middleware.StudentMiddleware(), handler.ResubmitRepository)
+			// 小登重新提交（生成新版本）、截止前撤回
+			submissionGroup.POST("/:id/resubmit", middleware.StudentMiddleware(), handler.ResubmitSubmission)
+			submissionGroup.POST("/:id/withdraw", middleware.StudentMiddleware(), handler.WithdrawSubmission)
 			// 小登查自己的提交
 			submissionGroup.GET("/my", middleware.StudentMiddleware(), handler.ListMySubmission)
 			// 老登查部门提交、批改、标记优秀
 			submissionGroup.GET("/homework/:homework_id", middleware.AdminMiddleware(), handler.ListSubmissionByHomework)
 			submissionGroup.PUT("/:id/review", middleware.AdminMiddleware(), handler.ReviewSubmission)
+			submissionGroup.POST("/:id/claim", middleware.AdminMiddleware(), handler.ClaimSubmission)
+			submissionGroup.DELETE("/:id/claim", middleware.AdminMiddleware(), handler.ReleaseClaim)
 			submissionGroup.PUT("/:id/excellent", middleware.AdminMiddleware(), handler.MarkExcellent)
-			// 所有人查优秀作业
-			submissionGroup.GET("/excellent", handler.ListExcellentSubmission)
+			// 仓库快照：老登批改时（小登也能）查看文件列表和文件内容
+			submissionGroup.GET("/:id/repository", handler.ListRepositorySnapshots)
+			submissionGroup.GET("/:id/repository/file", handler.GetRepositoryFile)
+			// 历史版本：老登浏览、对比
+			submissionGroup.GET("/:id/versions", middleware.AdminMiddleware(), handler.ListSubmissionVersions)
+			submissionGroup.GET("/:id/versions/diff", middleware.AdminMiddleware(), handler.DiffSubmissionVersions)
+			// 自动评测结果（老登批改时参考）
+			submissionGroup.GET("/:id/autograde", middleware.AdminMiddleware(), handler.GetGradeJob)
+			submissionGroup.POST("/:id/autograde", middleware.AdminMiddleware(), handler.RerunGradeJob)
+			// 讨论：老登和小登互相回复，老登可写私有备注
+			submissionGroup.GET("/comments/unread", handler.ListUnreadComments)
+			submissionGroup.POST("/:id/comments", handler.CreateSubmissionComment)
+			submissionGroup.GET("/:id/comments", handler.ListSubmissionComments)
+			submissionGroup.POST("/:id/comments/read", handler.MarkSubmissionCommentsRead)
+			// 复核：小登申请，双方查看申请和分数变动记录
+			submissionGroup.POST("/:id/regrade", middleware.StudentMiddleware(), handler.CreateRegrade)
+			submissionGroup.GET("/:id/regrade", handler.GetRegradeHistory)
+			// 收到的互评（老登随时看，小登互评结束后看匿名结果）
+			submissionGroup.GET("/:id/peer-reviews", handler.GetSubmissionPeerReviews)
+			// 匿名批改期间作业发布者查看提交者身份（会记录）
+			submissionGroup.POST("/:id/deanonymize", middleware.AdminMiddleware(), handler.DeanonymizeSubmission)
+			// 作品展：所有人浏览，小登同意（可匿名）后自己的优秀作业才展示
+			submissionGroup.GET("/excellent", handler.ListShowcase)
+			submissionGroup.GET("/:id/showcase-consent", middleware.StudentMiddleware(), handler.GetShowcaseConsent)
+			submissionGroup.PUT("/:id/showcase-consent", middleware.StudentMiddleware(), handler.SetShowcaseConsent)
+			// 提交详情（老登或提交者本人，ETag为版本号）
+			submissionGroup.GET("/:id", handler.GetSubmission)
 		}
 	}
 	return r
@@ -0,0 +1,682 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/sandbox"
+	"github.com/spf13/viper"
+)
+
+// 测试用例入参
+type TestCaseInput struct {
+	Name           string
+	Kind           string
+	Input          string
+	ExpectedOutput string
+	TestSource     string
+	Points         int
+}
+
+// 评测队列（只放任务ID，任务本身存在数据库里，重启后能恢复）
+var gradeQueue chan int64
+
+// 单个用例输出最多保留的字节数
+const gradeOutputMax = 16 * 1024
+
+// StartGradeWorkers 启动自动评测的worker，并把上次没跑完的任务重新排队
+func StartGradeWorkers() {
+	workers := viper.GetInt("autograde.workers")
+	if workers <= 0 {
+		return
+	}
+	gradeQueue = make(chan int64, 1024)
+	for i := 0; i < workers; i++ {
+		go gradeWorker()
+	}
+
+	jobs, err := dao.ListUnfinishedGradeJobs()
+	if err != nil {
+		log.Printf("恢复评测任务失败：%v", err)
+		return
+	}
+	for _, job := range jobs {
+		if job.Status == models.GradeJobRunning {
+			if err := dao.RequeueGradeJob(job.ID); err != nil {
+				log.Printf("重置评测任务%d失败：%v", job.ID, err)
+				continue
+			}
+		}
+		enqueueGradeJob(job.ID)
+	}
+}
+
+// autogradeEnabled 是否启用了自动评测
+func autogradeEnabled() bool {
+	return gradeQueue != nil
+}
+
+// enqueueGradeJob 任务入队；队列满时任务仍是排队状态，下次重启会被恢复
+func enqueueGradeJob(jobID int64) {
+	select {
+	case gradeQueue <- jobID:
+	default:
+		log.Printf("评测队列已满，任务%d等待下次恢复", jobID)
+	}
+}
+
+// scheduleAutograde 新提交后如果作业配了测试用例，就创建评测任务
+func scheduleAutograde(homework *models.Homework, submissionID int64) {
+	if !autogradeEnabled() || homework.Type != models.HomeworkNormal {
+		return
+	}
+	count, err := dao.CountTestCases(homework.ID)
+	if err != nil || count == 0 {
+		return
+	}
+	job, err := dao.ResetGradeJob(submissionID)
+	if err != nil {
+		log.Printf("创建评测任务失败：%v", err)
+		return
+	}
+	enqueueGradeJob(job.ID)
+}
+
+// SaveTestCases 设置作业的测试用例（整体替换）
+func SaveTestCases(homeworkID int64, inputs []TestCaseInput) errcode.ErrCode {
+	// 1. 检查作业是否存在，测验作业不需要代码评测
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	if homework.Type == models.HomeworkQuiz {
+		return errcode.ParamError
+	}
+
+	// 2. 校验用例：io用例要有期望输出，go_test用例要有测试源码
+	cases := make([]models.TestCase, 0, len(inputs))
+	for i, in := range inputs {
+		tc := models.TestCase{
+			HomeworkID: homeworkID,
+			Name:       strings.TrimSpace(in.Name),
+			Kind:       models.TestCaseKind(in.Kind),
+			Points:     in.Points,
+			Position:   i,
+		}
+		if tc.Name == "" || tc.Points <= 0 {
+			return errcode.ParamError
+		}
+		switch tc.Kind {
+		case models.TestCaseIO:
+			if in.ExpectedOutput == "" {
+				return errcode.ParamError
+			}
+			tc.Input = in.Input
+			tc.ExpectedOutput = in.ExpectedOutput
+		case models.TestCaseGoTest:
+			if strings.TrimSpace(in.TestSource) == "" {
+				return errcode.ParamError
+			}
+			tc.TestSource = in.TestSource
+		default:
+			return errcode.ParamError
+		}
+		cases = append(cases, tc)
+	}
+
+	// 3. 保存
+	if err := dao.SaveTestCases(homeworkID, cases); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// ListTestCases 查询作业的测试用例
+func ListTestCases(homeworkID int64) ([]models.TestCase, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	list, err := dao.ListTestCases(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	return list, errcode.Success
+}
+
+// GetGradeJob 查询提交的评测结果
+func GetGradeJob(submissionID int64) (*models.GradeJob, errcode.ErrCode) {
+	job, err := dao.GetGradeJobBySubmission(submissionID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if job == nil {
+		return nil, errcode.DataNotFound
+	}
+	return job, errcode.Success
+}
+
+// RerunGradeJob 重新评测一份提交（比如改了测试用例之后）
+func RerunGradeJob(submissionID int64) (*models.GradeJob, errcode.ErrCode) {
+	// 1. 没启用自动评测时直接报错，避免任务一直排队
+	if !autogradeEnabled() {
+		return nil, errcode.ParamError
+	}
+
+	// 2. 检查提交和作业的测试用例
+	sub, err := dao.GetSubmissionByID(submissionID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if sub == nil {
+		return nil, errcode.DataNotFound
+	}
+	count, err := dao.CountTestCases(sub.HomeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if count == 0 {
+		return nil, errcode.ParamError
+	}
+
+	// 3. 重置任务并入队
+	job, err := dao.ResetGradeJob(submissionID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	enqueueGradeJob(job.ID)
+	return job, errcode.Success
+}
+
+// gradeWorker 从队列里取任务评测
+func gradeWorker() {
+	for jobID := range gradeQueue {
+		claimed, err := dao.ClaimGradeJob(jobID)
+		if err != nil {
+			log.Printf("领取评测任务%d失败：%v", jobID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		runGradeJob(jobID)
+	}
+}
+
+// runGradeJob 执行评测并保存结果，评测系统自身出错时任务标记为failed
+func runGradeJob(jobID int64) {
+	job := &models.GradeJob{ID: jobID}
+	if err := gradeSubmission(job); err != nil {
+		// 沙箱不可用要运维处理，不能退化成不隔离运行学生代码
+		if errors.Is(err, sandbox.ErrUnavailable) {
+			log.Printf("评测任务%d：%v", jobID, err)
+		}
+		job.Status = models.GradeJobFailed
+		job.Log = err.Error()
+		job.Results = nil
+		job.SuggestedScore = nil
+	} else {
+		job.Status = models.GradeJobDone
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	if err := dao.FinishGradeJob(job); err != nil {
+		log.Printf("保存评测任务%d失败：%v", jobID, err)
+	}
+}
+
+// gradeSubmission 编译学生代码并跑所有用例，结果写进job
+func gradeSubmission(job *models.GradeJob) error {
+	// 1. 查询任务对应的提交、作业和测试用例
+	stored, err := dao.GetGradeJobByID(job.ID)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return os.ErrNotExist
+	}
+	job.SubmissionID = stored.SubmissionID
+	sub, err := dao.GetSubmissionByID(stored.SubmissionID)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return os.ErrNotExist
+	}
+	homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+	if err != nil {
+		return err
+	}
+	if homework == nil {
+		return os.ErrNotExist
+	}
+	cases, err := dao.ListTestCases(sub.HomeworkID)
+	if err != nil {
+		return err
+	}
+	for _, tc := range cases {
+		job.TotalPoints += tc.Points
+	}
+
+	// 2. 只评测粘贴在Content里的代码；FileURL是外部链接，服务端不去下载
+	if strings.TrimSpace(sub.Content) == "" {
+		job.Log = "提交内容为空，附件链接不参与自动评测"
+		setSuggestedScore(job, homework)
+		return nil
+	}
+
+	// 3. 每个任务一个独立的临时目录，跑完删除
+	runner, err := newGradeRunner()
+	if err != nil {
+		return err
+	}
+	defer runner.cleanup()
+
+	// 4. io用例共用一个编译好的程序，编译失败时所有io用例都不通过
+	var ioCases, testCases []models.TestCase
+	for _, tc := range cases {
+		if tc.Kind == models.TestCaseGoTest {
+			testCases = append(testCases, tc)
+		} else {
+			ioCases = append(ioCases, tc)
+		}
+	}
+	var logs []string
+	if len(ioCases) > 0 {
+		binary, buildLog, err := runner.build(sub.Content)
+		if err != nil {
+			return err
+		}
+		if binary == "" {
+			logs = append(logs, "编译失败：\n"+buildLog)
+		}
+		for _, tc := range ioCases {
+			result, err := runner.runIO(binary, tc)
+			if err != nil {
+				return err
+			}
+			job.Results = append(job.Results, result)
+		}
+	}
+
+	// 5. go_test用例各自和学生代码放在一起编译测试程序再运行
+	for _, tc := range testCases {
+		result, err := runner.runGoTest(sub.Content, tc)
+		if err != nil {
+			return err
+		}
+		job.Results = append(job.Results, result)
+	}
+
+	// 6. 汇总得分，按作业的评分制换算建议分
+	for _, r := range job.Results {
+		if r.Passed {
+			job.PassedPoints += r.Points
+		}
+	}
+	job.Log = strings.Join(logs, "\n")
+	setSuggestedScore(job, homework)
+	return nil
+}
+
+// setSuggestedScore 按通过的分值比例换算成作业评分制下的建议分
+func setSuggestedScore(job *models.GradeJob, homework *models.Homework) {
+	if job.TotalPoints <= 0 {
+		return
+	}
+	percent := float64(job.PassedPoints) * 100 / float64(job.TotalPoints)
+	score := homework.GradingScale.FromPercentage(percent)
+	job.SuggestedScore = &score
+}
+
+// gradeRunner 一次评测用到的目录和限制
+type gradeRunner struct {
+	dir             string // 本次评测的临时目录
+	goBinary        string
+	cacheDir        string // 多次评测共享的编译缓存（只有编译时用，运行学生代码时看不到）
+	compileTimeout  time.Duration
+	compileMemoryMB int
+	runLimits       sandbox.Limits // 运行学生代码的限制，RootDir每次运行单独设置
+}
+
+func newGradeRunner() (*gradeRunner, error) {
+	// 1. 找到go命令（沙箱里不继承服务的PATH，所以先解析成绝对路径）
+	goBinary, err := exec.LookPath(viper.GetString("autograde.go_binary"))
+	if err != nil {
+		return nil, err
+	}
+	goBinary, err = filepath.Abs(goBinary)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 准备工作目录
+	base := viper.GetString("autograde.work_dir")
+	if base == "" {
+		base = filepath.Join(os.TempDir(), "homework-autograde")
+	}
+	// 编译缓存归沙箱用户所有（以前由服务用户写的缓存不再使用）
+	cacheDir := filepath.Join(base, "gocache-sandbox")
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(base, "job-")
+	if err != nil {
+		return nil, err
+	}
+	// 编译也用沙箱用户，要能进到任务目录里自己的子目录（目录本身不可列出）
+	if err := os.Chmod(dir, 0o711); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	// 3. 学生代码用专门的非特权用户运行（默认nobody），不能用服务自己的用户
+	uid, gid := viper.GetInt("autograde.sandbox_uid"), viper.GetInt("autograde.sandbox_gid")
+	if uid <= 0 {
+		uid = 65534
+	}
+	if gid <= 0 {
+		gid = 65534
+	}
+
+	runner := &gradeRunner{
+		dir:             dir,
+		goBinary:        goBinary,
+		cacheDir:        cacheDir,
+		compileTimeout:  time.Duration(viper.GetInt("autograde.compile_timeout")) * time.Second,
+		compileMemoryMB: viper.GetInt("autograde.compile_memory_mb"),
+		runLimits: sandbox.Limits{
+			Timeout:    time.Duration(viper.GetInt("autograde.run_timeout")) * time.Second,
+			CPUSeconds: viper.GetInt("autograde.cpu_seconds"),
+			MemoryMB:   viper.GetInt("autograde.memory_mb"),
+			Processes:  viper.GetInt("autograde.max_processes"),
+			FileSizeMB: viper.GetInt("autograde.max_file_mb"),
+			NoNetwork:  viper.GetBool("autograde.no_network"),
+			OutputMax:  gradeOutputMax,
+			UID:        uid,
+			GID:        gid,
+		},
+	}
+	home := filepath.Join(dir, "home")
+	err = os.Mkdir(home, 0o700)
+	if err == nil {
+		err = sandboxOwn(home, runner.runLimits)
+	}
+	if err == nil {
+		err = sandboxOwn(cacheDir, runner.runLimits)
+	}
+	if err != nil {
+		runner.cleanup()
+		return nil, err
+	}
+	return runner, nil
+}
+
+// sandboxOwn 把目录交给沙箱用户（编译时要写入），做不到说明服务没以root运行
+func sandboxOwn(path string, limits sandbox.Limits) error {
+	if err := os.Chown(path, limits.UID, limits.GID); err != nil {
+		return fmt.Errorf("%w：%v", sandbox.ErrUnavailable, err)
+	}
+	return nil
+}
+
+// serviceOwn 编译好的程序改回服务用户所有，运行时学生代码改不了给后面用例用的程序
+func serviceOwn(path string) error {
+	if err := os.Chown(path, os.Getuid(), os.Getgid()); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0o755)
+}
+
+func (r *gradeRunner) cleanup() {
+	if err := os.RemoveAll(r.dir); err != nil {
+		log.Printf("清理评测目录失败：%v", err)
+	}
+}
+
+// buildEnv 编译用的环境变量：不联网拉依赖、不用cgo、只用本地工具链
+func (r *gradeRunner) buildEnv() []string {
+	return []string{
+		"PATH=" + filepath.Dir(r.goBinary) + ":/usr/bin:/bin",
+		"HOME=" + filepath.Join(r.dir, "home"),
+		"GOCACHE=" + r.cacheDir,
+		"GOPATH=" + filepath.Join(r.dir, "home", "go"),
+		"GOMAXPROCS=2",
+		"GOPROXY=off",
+		"GOFLAGS=-mod=mod",
+		"GOTOOLCHAIN=local",
+		"CGO_ENABLED=0",
+	}
+}
+
+// runEnv 运行学生代码的环境变量（换根之后的路径，不带编译缓存）
+func runEnv() []string {
+	return []string{
+		"HOME=/tmp",
+		"TMPDIR=/tmp",
+	}
+}
+
+// prepareRoot 给一次运行准备单独的根目录：只有编译好的程序和一个沙箱用户可写的/tmp，
+// 返回设好RootDir的限制和根目录里的程序路径
+func (r *gradeRunner) prepareRoot(name, binary string) (sandbox.Limits, string, error) {
+	limits := r.runLimits
+	root := filepath.Join(r.dir, name)
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return limits, "", err
+	}
+	// 硬链接到新目录，多个用例共用一个编译结果，程序仍归服务用户所有，沙箱用户改不了
+	prog := "/" + filepath.Base(binary)
+	if err := os.Link(binary, filepath.Join(root, prog)); err != nil {
+		return limits, "", err
+	}
+	tmp := filepath.Join(root, "tmp")
+	if err := os.Mkdir(tmp, 0o700); err != nil {
+		return limits, "", err
+	}
+	if err := os.Chown(tmp, limits.UID, limits.GID); err != nil {
+		return limits, "", err
+	}
+	limits.RootDir = root
+	return limits, prog, nil
+}
+
+// compileLimits 编译同样用沙箱用户，限制时间、内存、进程数和文件大小（要用到Go工具链，不换根）
+func (r *gradeRunner) compileLimits() sandbox.Limits {
+	return sandbox.Limits{
+		Timeout:    r.compileTimeout,
+		MemoryMB:   r.compileMemoryMB,
+		Processes:  r.runLimits.Processes,
+		FileSizeMB: r.runLimits.FileSizeMB,
+		NoNetwork:  r.runLimits.NoNetwork,
+		OutputMax:  gradeOutputMax,
+		UID:        r.runLimits.UID,
+		GID:        r.runLimits.GID,
+	}
+}
+
+// prepareModule 在子目录里写入go.mod和学生代码
+func (r *gradeRunner) prepareModule(name, content string) (string, error) {
+	dir := filepath.Join(r.dir, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module submission\n\ngo 1.21\n"), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	// 编译结果写在这个目录里
+	if err := sandboxOwn(dir, r.runLimits); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// build 编译学生程序，编译失败时binary为空、buildLog为编译输出
+func (r *gradeRunner) build(content string) (binary, buildLog string, err error) {
+	dir, err := r.prepareModule("main", content)
+	if err != nil {
+		return "", "", err
+	}
+	binary = filepath.Join(dir, "submission.bin")
+	res, err := sandbox.Run(context.Background(), sandbox.Command{
+		Dir:  dir,
+		Path: r.goBinary,
+		Args: []string{"build", "-o", binary, "."},
+		Env:  r.buildEnv(),
+	}, r.compileLimits())
+	if err != nil {
+		return "", "", err
+	}
+	if res.TimedOut {
+		return "", "编译超时", nil
+	}
+	if res.ExitCode != 0 {
+		return "", res.Stderr, nil
+	}
+	if err := serviceOwn(binary); err != nil {
+		return "", "", err
+	}
+	return binary, "", nil
+}
+
+// runIO 运行一个io用例，比较输出时忽略行尾空白和首尾空行；沙箱本身出错时返回error
+func (r *gradeRunner) runIO(binary string, tc models.TestCase) (models.TestResult, error) {
+	result := models.TestResult{TestCaseID: tc.ID, Name: tc.Name, Points: tc.Points}
+	if binary == "" {
+		result.Message = "编译失败"
+		return result, nil
+	}
+	limits, prog, err := r.prepareRoot("run-"+strconv.FormatInt(tc.ID, 10), binary)
+	if err != nil {
+		return result, err
+	}
+	res, err := sandbox.Run(context.Background(), sandbox.Command{
+		Dir:   limits.RootDir,
+		Path:  prog,
+		Env:   runEnv(),
+		Stdin: tc.Input,
+	}, limits)
+	if err != nil {
+		return result, err
+	}
+	result.Output = res.Stdout
+	result.DurationMs = res.Duration.Milliseconds()
+	switch {
+	case res.TimedOut:
+		result.Message = "运行超时"
+	case res.ExitCode != 0:
+		result.Message = "运行错误：" + truncateMessage(res.Stderr)
+	case normalizeOutput(res.Stdout) != normalizeOutput(tc.ExpectedOutput):
+		result.Message = "输出与期望不一致"
+	default:
+		result.Passed = true
+	}
+	return result, nil
+}
+
+// runGoTest 把隐藏测试文件和学生代码放在一起，先编译成测试程序再在限制下运行；沙箱本身出错时返回error
+func (r *gradeRunner) runGoTest(content string, tc models.TestCase) (models.TestResult, error) {
+	result := models.TestResult{TestCaseID: tc.ID, Name: tc.Name, Points: tc.Points}
+
+	// 1. 写入学生代码和测试文件
+	dir, err := r.prepareModule("test-"+strconv.FormatInt(tc.ID, 10), content)
+	if err != nil {
+		return result, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "grader_test.go"), []byte(tc.TestSource), 0o644); err != nil {
+		return result, err
+	}
+
+	// 2. 编译测试程序
+	binary := filepath.Join(dir, "grader.test")
+	res, err := sandbox.Run(context.Background(), sandbox.Command{
+		Dir:  dir,
+		Path: r.goBinary,
+		Args: []string{"test", "-c", "-o", binary, "."},
+		Env:  r.buildEnv(),
+	}, r.compileLimits())
+	if err != nil {
+		return result, err
+	}
+	if res.TimedOut {
+		result.Message = "编译超时"
+		return result, nil
+	}
+	if res.ExitCode != 0 {
+		result.Message = "编译失败"
+		result.Output = res.Stdout + res.Stderr
+		return result, nil
+	}
+	if err := serviceOwn(binary); err != nil {
+		return result, err
+	}
+
+	// 3. 测试程序放到单独的根目录里运行，隐藏的测试源码和学生代码一起删掉，退出码为0即通过
+	limits, prog, err := r.prepareRoot("run-test-"+strconv.FormatInt(tc.ID, 10), binary)
+	if err != nil {
+		return result, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return result, err
+	}
+	res, err = sandbox.Run(context.Background(), sandbox.Command{
+		Dir:  limits.RootDir,
+		Path: prog,
+		Args: []string{"-test.v"},
+		Env:  runEnv(),
+	}, limits)
+	if err != nil {
+		return result, err
+	}
+	result.Output = res.Stdout + res.Stderr
+	result.DurationMs = res.Duration.Milliseconds()
+	switch {
+	case res.TimedOut:
+		result.Message = "运行超时"
+	case res.ExitCode != 0:
+		result.Message = "测试未通过"
+	default:
+		result.Passed = true
+	}
+	return result, nil
+}
+
+// normalizeOutput 统一换行符，去掉行尾空白和首尾空行
+func normalizeOutput(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// truncateMessage 失败原因只保留开头一段（Message字段有长度限制）
+func truncateMessage(s string) string {
+	const max = 400
+	s = strings.TrimSpace(s)
+	if len(s) <= max {
+		return s
+	}
+	// 截断后去掉可能被截成半个的汉字
+	return strings.ToValidUTF8(s[:max], "") + "..."
+}
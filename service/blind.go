@@ -0,0 +1,162 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/spf13/viper"
+)
+
+// blindActive 作业是否处于匿名批改中（开启了匿名批改且成绩还没发布，预定的发布时间没到也算没发布）
+func blindActive(homework *models.Homework) bool {
+	return homework != nil && homework.BlindGrading && !homework.GradesReleased(time.Now())
+}
+
+// pseudonym 学生在某个作业里的化名：同一作业下固定不变，不同作业之间对不上
+func pseudonym(homeworkID, studentID int64) string {
+	mac := hmac.New(sha256.New, []byte(viper.GetString("jwt.secret")))
+	mac.Write([]byte(strconv.FormatInt(homeworkID, 10) + ":" + strconv.FormatInt(studentID, 10)))
+	return "匿名-" + strings.ToUpper(hex.EncodeToString(mac.Sum(nil))[:6])
+}
+
+// anonymizeUser 把学生信息替换成只有化名的空用户
+func anonymizeUser(homeworkID int64, user *models.User) {
+	if user.ID == 0 {
+		return
+	}
+	*user = models.User{Nickname: pseudonym(homeworkID, user.ID), Role: user.Role, Department: user.Department}
+}
+
+// anonymizeSubmission 匿名批改时去掉提交里所有能看出学生身份的信息
+func anonymizeSubmission(homework *models.Homework, sub *models.Submission) {
+	if !blindActive(homework) {
+		return
+	}
+	sub.Pseudonym = pseudonym(homework.ID, sub.StudentID)
+	sub.StudentID = 0
+	sub.Student = models.User{Nickname: sub.Pseudonym, Role: models.Student}
+	// 仓库地址里一般带着学生的账号
+	if homework.Type == models.HomeworkGit {
+		sub.FileURL = ""
+	}
+	// 小组和组员记录的ID、创建时间都能和小组列表对上，一起去掉
+	sub.TeamID = nil
+	if sub.Team != nil {
+		sub.Team.ID = 0
+		sub.Team.Name = sub.Pseudonym
+		sub.Team.LeaderID = 0
+		sub.Team.CreatedAt, sub.Team.UpdatedAt = time.Time{}, time.Time{}
+		for i := range sub.Team.Members {
+			m := &sub.Team.Members[i]
+			m.ID, m.TeamID, m.StudentID = 0, 0, 0
+			m.CreatedAt = time.Time{}
+			anonymizeUser(homework.ID, &m.Student)
+		}
+	}
+	for i := range sub.Files {
+		sub.Files[i].UploaderID = 0
+	}
+	for i := range sub.Versions {
+		sub.Versions[i].SubmitterID = 0
+	}
+}
+
+// SetBlindGrading 开启或关闭作业的匿名批改；关闭相当于公开所有学生身份，只有作业发布者可以操作并且要记录；
+// ifMatch要和作业当前的版本一致
+func SetBlindGrading(adminID, homeworkID int64, enabled bool, reason string, ifMatch int) errcode.ErrCode {
+	// 1. 查询作业
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	if ifMatch != homework.Version {
+		return errcode.VersionConflict
+	}
+	if homework.BlindGrading == enabled {
+		return errcode.Success
+	}
+
+	// 2. 关闭时按查看身份处理
+	if !enabled && blindActive(homework) {
+		if errCode := logDeanonymization(adminID, homework, nil, reason); errCode != errcode.Success {
+			return errCode
+		}
+	}
+
+	// 3. 保存
+	return homeworkUpdateResult(dao.UpdateHomeworkBlindGrading(homeworkID, ifMatch, enabled))
+}
+
+// DeanonymizeSubmission 匿名批改期间查看提交者的真实身份（只有作业发布者可以，每次都记录）
+func DeanonymizeSubmission(adminID, subID int64, reason string) (*models.User, errcode.ErrCode) {
+	// 1. 查询提交和作业
+	sub, err := dao.GetSubmissionByID(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if sub == nil {
+		return nil, errcode.DataNotFound
+	}
+	homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+
+	// 2. 记录后返回学生信息（没在匿名批改中时本来就能看到，不用记录）
+	if blindActive(homework) {
+		if errCode := logDeanonymization(adminID, homework, &sub.ID, reason); errCode != errcode.Success {
+			return nil, errCode
+		}
+	}
+	student, err := dao.GetUserByID(sub.StudentID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if student == nil {
+		return nil, errcode.DataNotFound
+	}
+	return student, errcode.Success
+}
+
+// logDeanonymization 校验权限并记录查看身份
+func logDeanonymization(adminID int64, homework *models.Homework, subID *int64, reason string) errcode.ErrCode {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errcode.ParamError
+	}
+	if homework.CreatorID != adminID {
+		return errcode.PermissionDenied
+	}
+	err := dao.CreateDeanonymizationLog(&models.DeanonymizationLog{
+		HomeworkID:   homework.ID,
+		SubmissionID: subID,
+		AdminID:      adminID,
+		Reason:       reason,
+	})
+	if err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// ListDeanonymizationLogs 查询作业的身份查看记录
+func ListDeanonymizationLogs(homeworkID int64) ([]models.DeanonymizationLog, errcode.ErrCode) {
+	list, err := dao.ListDeanonymizationLogs(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	return list, errcode.Success
+}
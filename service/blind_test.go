@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+)
+
+func TestAnonymizeTeamSubmission(t *testing.T) {
+	teamID := int64(7)
+	now := time.Now()
+	homework := &models.Homework{ID: 1, BlindGrading: true}
+	sub := &models.Submission{
+		HomeworkID: 1,
+		StudentID:  11,
+		TeamID:     &teamID,
+		Student:    models.User{ID: 11, Nickname: "张三"},
+		Team: &models.Team{ID: teamID, Name: "第一组", LeaderID: 11, CreatedAt: now, UpdatedAt: now, Members: []models.TeamMember{
+			{ID: 3, TeamID: teamID, StudentID: 11, CreatedAt: now, Student: models.User{ID: 11, Nickname: "张三"}},
+			{ID: 4, TeamID: teamID, StudentID: 12, CreatedAt: now, Student: models.User{ID: 12, Nickname: "李四"}},
+		}},
+	}
+	anonymizeSubmission(homework, sub)
+
+	if sub.StudentID != 0 || sub.TeamID != nil || sub.Student.Nickname != sub.Pseudonym {
+		t.Errorf("提交还带着身份：student_id=%d team_id=%v nickname=%q", sub.StudentID, sub.TeamID, sub.Student.Nickname)
+	}
+	team := sub.Team
+	if team.ID != 0 || team.LeaderID != 0 || team.Name != sub.Pseudonym || !team.CreatedAt.IsZero() || !team.UpdatedAt.IsZero() {
+		t.Errorf("小组还带着身份：%+v", team)
+	}
+	for _, m := range team.Members {
+		if m.ID != 0 || m.TeamID != 0 || m.StudentID != 0 || !m.CreatedAt.IsZero() || m.Student.ID != 0 {
+			t.Errorf("组员还带着身份：%+v", m)
+		}
+		if m.Student.Nickname != pseudonym(1, 11) && m.Student.Nickname != pseudonym(1, 12) {
+			t.Errorf("组员昵称 = %q，应该是化名", m.Student.Nickname)
+		}
+	}
+
+	// 没开匿名批改时不变
+	sub = &models.Submission{StudentID: 11, TeamID: &teamID}
+	anonymizeSubmission(&models.Homework{ID: 1}, sub)
+	if sub.StudentID != 11 || sub.TeamID == nil {
+		t.Errorf("没开匿名批改时不应修改：%+v", sub)
+	}
+}
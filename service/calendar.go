@@ -0,0 +1,96 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/ical"
+)
+
+// 截止前的提醒时间
+var calendarAlarms = []time.Duration{24 * time.Hour, time.Hour}
+
+// ResetCalendarToken 生成（或重新生成）用户的日历订阅令牌，旧链接随即失效
+func ResetCalendarToken(userID int64) (string, errcode.ErrCode) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errcode.DBError
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := dao.SaveCalendarToken(&models.CalendarToken{UserID: userID, Token: token}); err != nil {
+		return "", errcode.DBError
+	}
+	return token, errcode.Success
+}
+
+// RevokeCalendarToken 撤销用户的日历订阅令牌
+func RevokeCalendarToken(userID int64) errcode.ErrCode {
+	if err := dao.DeleteCalendarToken(userID); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// BuildCalendar 根据订阅令牌生成用户可见作业的截止日历（含个人延期）
+func BuildCalendar(token, baseURL string) ([]byte, errcode.ErrCode) {
+	// 1. 校验令牌
+	ct, err := dao.GetCalendarTokenByToken(token)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if ct == nil {
+		return nil, errcode.AuthError
+	}
+	user, err := dao.GetUserByID(ct.UserID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if user == nil {
+		// 账号已注销
+		return nil, errcode.AuthError
+	}
+
+	// 2. 只包含本部门的作业
+	homeworks, err := dao.ListHomeworkByDepartment(string(user.Department))
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	// 3. 个人延期覆盖作业截止时间
+	extensions, err := dao.ListDeadlineExtensionByStudent(user.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	extended := make(map[int64]time.Time, len(extensions))
+	for _, e := range extensions {
+		extended[e.HomeworkID] = e.Deadline
+	}
+
+	// 4. 生成日历
+	cal := &ical.Calendar{Name: fmt.Sprintf("作业截止时间 - %s", user.Nickname)}
+	for _, h := range homeworks {
+		deadline := h.Deadline
+		summary := "作业截止：" + h.Title
+		if d, ok := extended[h.ID]; ok {
+			deadline = d
+			summary += "（已延期）"
+		}
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         fmt.Sprintf("homework-%d-user-%d@homework-system", h.ID, user.ID),
+			Summary:     summary,
+			Description: h.Description,
+			URL:         fmt.Sprintf("%s/homework/%d", baseURL, h.ID),
+			Start:       deadline,
+			End:         deadline,
+			Updated:     h.UpdatedAt,
+			Alarms:      calendarAlarms,
+		})
+	}
+	return cal.Bytes(), errcode.Success
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// 发表评论的入参
+type CommentInput struct {
+	Content   string
+	ParentID  *int64
+	LineStart *int
+	LineEnd   *int
+	Private   bool
+}
+
+// 提交讨论（树形，顶层按时间顺序）
+type CommentThread struct {
+	Comments []models.SubmissionComment `json:"comments"`
+	Unread   int                        `json:"unread"` // 当前用户未读的评论数
+}
+
+// CreateSubmissionComment 在提交下发表评论或回复
+func CreateSubmissionComment(userID int64, isAdmin bool, subID int64, input CommentInput) (*models.SubmissionComment, errcode.ErrCode) {
+	// 1. 校验权限，私有备注只有批改人能写
+	sub, errCode := getVisibleSubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	content := strings.TrimSpace(input.Content)
+	if content == "" || (input.Private && !isAdmin) {
+		return nil, errcode.ParamError
+	}
+
+	// 2. 回复的评论要在同一份提交下；回复私有备注的也只能是私有备注
+	if input.ParentID != nil {
+		parent, err := dao.GetSubmissionCommentByID(*input.ParentID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+		if parent == nil || parent.SubmissionID != subID || (parent.Private && !isAdmin) {
+			return nil, errcode.DataNotFound
+		}
+		if parent.Private && !input.Private {
+			return nil, errcode.ParamError
+		}
+	}
+
+	// 3. 锚定行号要成对出现，并且在当前版本的行数范围内
+	if (input.LineStart == nil) != (input.LineEnd == nil) {
+		return nil, errcode.ParamError
+	}
+	if input.LineStart != nil {
+		lines := strings.Count(sub.Content, "\n") + 1
+		if *input.LineStart < 1 || *input.LineEnd < *input.LineStart || *input.LineEnd > lines {
+			return nil, errcode.ParamError
+		}
+	}
+
+	// 4. 保存
+	comment := &models.SubmissionComment{
+		SubmissionID: subID,
+		ParentID:     input.ParentID,
+		AuthorID:     userID,
+		Content:      content,
+		Version:      sub.Attempts,
+		LineStart:    input.LineStart,
+		LineEnd:      input.LineEnd,
+		Private:      input.Private,
+	}
+	if err := dao.CreateSubmissionComment(comment); err != nil {
+		return nil, errcode.DBError
+	}
+	return comment, errcode.Success
+}
+
+// ListSubmissionComments 查询提交的讨论，标出当前用户未读的评论（查看不会自动标记已读）
+func ListSubmissionComments(userID int64, isAdmin bool, subID int64) (*CommentThread, errcode.ErrCode) {
+	// 1. 校验权限
+	sub, errCode := getVisibleSubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+
+	// 2. 查询评论和已读位置
+	list, err := dao.ListSubmissionComments(subID, isAdmin)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	lastRead, err := dao.GetCommentReadPosition(subID, userID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	// 3. 匿名批改时批改人看不到学生评论者的身份
+	if isAdmin {
+		homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+		if blindActive(homework) {
+			for i := range list {
+				if list[i].Author.Role == models.Student {
+					list[i].AuthorID = 0
+					anonymizeUser(homework.ID, &list[i].Author)
+				}
+			}
+		}
+	}
+
+	// 4. 自己写的不算未读
+	thread := &CommentThread{}
+	for i := range list {
+		if list[i].AuthorID != userID && list[i].ID > lastRead {
+			list[i].Unread = true
+			thread.Unread++
+		}
+	}
+	thread.Comments = buildCommentTree(list)
+	return thread, errcode.Success
+}
+
+// buildCommentTree 按ParentID组装成树（list按ID升序，父评论不可见的当作顶层）
+func buildCommentTree(list []models.SubmissionComment) []models.SubmissionComment {
+	visible := make(map[int64]bool, len(list))
+	children := make(map[int64][]models.SubmissionComment)
+	var roots []models.SubmissionComment
+	for _, c := range list {
+		visible[c.ID] = true
+	}
+	for _, c := range list {
+		if c.ParentID != nil && visible[*c.ParentID] {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	var attach func(nodes []models.SubmissionComment) []models.SubmissionComment
+	attach = func(nodes []models.SubmissionComment) []models.SubmissionComment {
+		for i := range nodes {
+			nodes[i].Replies = attach(children[nodes[i].ID])
+		}
+		return nodes
+	}
+	return attach(roots)
+}
+
+// MarkSubmissionCommentsRead 标记读到某条评论（lastCommentID为0时标记全部已读）
+func MarkSubmissionCommentsRead(userID int64, isAdmin bool, subID, lastCommentID int64) errcode.ErrCode {
+	// 1. 校验权限
+	if _, errCode := getVisibleSubmission(userID, isAdmin, subID); errCode != errcode.Success {
+		return errCode
+	}
+
+	// 2. 没指定时读到最新一条可见的评论
+	if lastCommentID == 0 {
+		list, err := dao.ListSubmissionComments(subID, isAdmin)
+		if err != nil {
+			return errcode.DBError
+		}
+		if len(list) == 0 {
+			return errcode.Success
+		}
+		lastCommentID = list[len(list)-1].ID
+	} else {
+		comment, err := dao.GetSubmissionCommentByID(lastCommentID)
+		if err != nil {
+			return errcode.DBError
+		}
+		if comment == nil || comment.SubmissionID != subID || (comment.Private && !isAdmin) {
+			return errcode.DataNotFound
+		}
+	}
+
+	// 3. 保存已读位置
+	if err := dao.MarkCommentsRead(subID, userID, lastCommentID); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// ListUnreadComments 查询当前用户有未读评论的提交
+func ListUnreadComments(userID int64, isAdmin bool) ([]dao.UnreadCommentCount, errcode.ErrCode) {
+	list, err := dao.ListUnreadCommentCounts(userID, isAdmin)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	return list, errcode.Success
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"log"
+	"strings"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// SaveDraft 自动保存草稿（只保存，不做提交校验）
+func SaveDraft(studentID, homeworkID int64, content, fileURL string, fileIDs []int64) (*models.SubmissionDraft, errcode.ErrCode) {
+	// 1. 作业必须存在且已解锁，测验作业没有草稿
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	if homework.Type == models.HomeworkQuiz {
+		return nil, errcode.ParamError
+	}
+	if errCode := checkUnlocked(studentID, homework); errCode != errcode.Success {
+		return nil, errCode
+	}
+
+	// 2. 覆盖保存
+	draft := &models.SubmissionDraft{
+		HomeworkID: homeworkID,
+		StudentID:  studentID,
+		Content:    content,
+		FileURL:    fileURL,
+		FileIDs:    fileIDs,
+	}
+	if err := dao.SaveDraft(draft); err != nil {
+		return nil, errcode.DBError
+	}
+	return draft, errcode.Success
+}
+
+// GetDraft 查询自己的草稿
+func GetDraft(studentID, homeworkID int64) (*models.SubmissionDraft, errcode.ErrCode) {
+	draft, err := dao.GetDraft(homeworkID, studentID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if draft == nil {
+		return nil, errcode.DataNotFound
+	}
+	return draft, errcode.Success
+}
+
+// DeleteDraft 丢弃草稿
+func DeleteDraft(studentID, homeworkID int64) errcode.ErrCode {
+	if err := dao.DeleteDraft(homeworkID, studentID); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// SubmitDraft 把草稿作为正式提交（和直接提交走同样的校验），成功后删除草稿
+func SubmitDraft(studentID, homeworkID int64) errcode.ErrCode {
+	// 1. 查询草稿，空草稿不能提交
+	draft, err := dao.GetDraft(homeworkID, studentID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if draft == nil {
+		return errcode.DataNotFound
+	}
+	if strings.TrimSpace(draft.Content) == "" {
+		return errcode.ParamError
+	}
+
+	// 2. 已经交过的（小组作业包括组员交的）按重新提交处理，否则按首次提交处理
+	existing, errCode := existingSubmission(studentID, homeworkID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	if existing != nil {
+		errCode = ResubmitSubmission(studentID, existing.ID, draft.Content, draft.FileURL, draft.FileIDs)
+	} else {
+		errCode = CreateSubmission(studentID, homeworkID, draft.Content, draft.FileURL, draft.FileIDs)
+	}
+	if errCode != errcode.Success {
+		return errCode
+	}
+
+	// 3. 提交成功后草稿就没用了（删除失败不影响提交结果）
+	if err := dao.DeleteDraft(homeworkID, studentID); err != nil {
+		log.Printf("删除草稿失败：%v", err)
+	}
+	return errcode.Success
+}
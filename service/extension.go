@@ -0,0 +1,65 @@
+package service
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// GrantExtension 给学生单独延长某个作业的截止时间
+func GrantExtension(homeworkID, studentID int64, deadline time.Time, reason string, grantedBy int64) errcode.ErrCode {
+	// 1. 检查作业和学生
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	student, err := dao.GetUserByID(studentID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if student == nil || student.Role != models.Student {
+		return errcode.DataNotFound
+	}
+
+	// 2. 延期只能往后延
+	if !deadline.After(homework.Deadline) {
+		return errcode.ParamError
+	}
+
+	ext := &models.DeadlineExtension{
+		HomeworkID: homeworkID,
+		StudentID:  studentID,
+		Deadline:   deadline,
+		Reason:     reason,
+		GrantedBy:  grantedBy,
+	}
+	if err := dao.SaveDeadlineExtension(ext); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// RevokeExtension 取消学生的个人延期
+func RevokeExtension(homeworkID, studentID int64) errcode.ErrCode {
+	if err := dao.DeleteDeadlineExtension(homeworkID, studentID); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// effectiveDeadline 学生在某个作业上的实际截止时间（有个人延期则以延期为准）
+func effectiveDeadline(homework *models.Homework, studentID int64) (time.Time, error) {
+	ext, err := dao.GetDeadlineExtension(homework.ID, studentID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ext != nil {
+		return ext.Deadline, nil
+	}
+	return homework.Deadline, nil
+}
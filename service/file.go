@@ -0,0 +1,327 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/storage"
+	"github.com/spf13/viper"
+)
+
+var (
+	fileStorage     storage.Storage
+	fileStorageErr  error
+	fileStorageOnce sync.Once
+)
+
+// getStorage 按配置创建存储后端（第一次用到时创建）
+func getStorage() (storage.Storage, error) {
+	fileStorageOnce.Do(func() {
+		switch viper.GetString("storage.driver") {
+		case "s3":
+			fileStorage = &storage.S3{
+				Endpoint:  viper.GetString("storage.s3.endpoint"),
+				Region:    viper.GetString("storage.s3.region"),
+				Bucket:    viper.GetString("storage.s3.bucket"),
+				AccessKey: viper.GetString("storage.s3.access_key"),
+				SecretKey: viper.GetString("storage.s3.secret_key"),
+				PathStyle: viper.GetBool("storage.s3.path_style"),
+				Client:    &http.Client{Timeout: 5 * time.Minute},
+			}
+		default:
+			root := viper.GetString("storage.local_root")
+			if root == "" {
+				root = "./uploads"
+			}
+			fileStorage, fileStorageErr = storage.NewLocal(root)
+		}
+	})
+	return fileStorage, fileStorageErr
+}
+
+// maxUploadSize 单个文件大小上限（字节）
+func maxUploadSize() int64 {
+	mb := viper.GetInt64("upload.max_size_mb")
+	if mb <= 0 {
+		mb = 20
+	}
+	return mb << 20
+}
+
+// MaxUploadRequestSize 上传请求体的上限（文件上限加上表单其他字段的余量）
+func MaxUploadRequestSize() int64 {
+	return maxUploadSize() + 1<<20
+}
+
+// UploadFile 上传文件：校验大小和类型，计算SHA-256后写入存储
+func UploadFile(uploaderID, homeworkID int64, header *multipart.FileHeader) (*models.StoredFile, errcode.ErrCode) {
+	// 1. 作业必须存在
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+
+	// 2. 校验大小
+	if header.Size <= 0 {
+		return nil, errcode.ParamError
+	}
+	if header.Size > maxUploadSize() {
+		return nil, errcode.FileTooLarge
+	}
+
+	// 3. 按文件开头的内容识别类型（客户端传的Content-Type不可信）
+	f, err := header.Open()
+	if err != nil {
+		return nil, errcode.ParamError
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, errcode.ParamError
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !allowedContentType(mediaType) {
+		return nil, errcode.FileTypeNotAllowed
+	}
+
+	// 4. 边写入存储边计算SHA-256
+	store, err := getStorage()
+	if err != nil {
+		return nil, errcode.StorageError
+	}
+	key, err := newStorageKey(homeworkID, uploaderID)
+	if err != nil {
+		return nil, errcode.StorageError
+	}
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), f), hash)
+	if err := store.Put(context.Background(), key, body, header.Size, contentType); err != nil {
+		log.Printf("上传文件失败：%v", err)
+		return nil, errcode.StorageError
+	}
+
+	// 5. 保存文件信息，失败时删掉已写入的文件
+	file := &models.StoredFile{
+		UploaderID:  uploaderID,
+		HomeworkID:  homeworkID,
+		Name:        header.Filename,
+		Size:        header.Size,
+		ContentType: contentType,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}
+	if err := dao.CreateStoredFile(file); err != nil {
+		_ = store.Delete(context.Background(), key)
+		return nil, errcode.DBError
+	}
+	return file, errcode.Success
+}
+
+// allowedContentType 是否在允许上传的类型列表里
+func allowedContentType(mediaType string) bool {
+	for _, t := range viper.GetStringSlice("upload.allowed_types") {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// newStorageKey 生成不可猜测的存储路径
+func newStorageKey(homeworkID, uploaderID int64) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("submissions/%d/%d/%s", homeworkID, uploaderID, hex.EncodeToString(buf)), nil
+}
+
+// checkSubmissionFiles 校验提交要关联的文件：同一作业下自己上传且未关联的，或者本提交之前版本已关联的
+func checkSubmissionFiles(studentID, homeworkID, subID int64, fileIDs []int64) (models.IDList, errcode.ErrCode) {
+	// 1. 去重并限制数量
+	seen := make(map[int64]bool, len(fileIDs))
+	ids := make(models.IDList, 0, len(fileIDs))
+	for _, id := range fileIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errcode.Success
+	}
+	maxFiles := viper.GetInt("upload.max_files")
+	if maxFiles > 0 && len(ids) > maxFiles {
+		return nil, errcode.ParamError
+	}
+
+	// 2. 逐个校验归属
+	files, err := dao.ListStoredFileByIDs(ids)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if len(files) != len(ids) {
+		return nil, errcode.DataNotFound
+	}
+	for _, f := range files {
+		if f.HomeworkID != homeworkID {
+			return nil, errcode.ParamError
+		}
+		if f.SubmissionID == nil {
+			if f.UploaderID != studentID {
+				return nil, errcode.PermissionDenied
+			}
+		} else if subID == 0 || *f.SubmissionID != subID {
+			return nil, errcode.ParamError
+		}
+	}
+	return ids, errcode.Success
+}
+
+// FileDownloadURL 校验查看权限后生成短时有效的下载链接
+func FileDownloadURL(userID int64, isAdmin bool, fileID int64, baseURL string) (string, time.Time, errcode.ErrCode) {
+	// 1. 查询文件并校验权限
+	file, err := dao.GetStoredFileByID(fileID)
+	if err != nil {
+		return "", time.Time{}, errcode.DBError
+	}
+	if file == nil {
+		return "", time.Time{}, errcode.DataNotFound
+	}
+	if errCode := checkFileVisible(userID, isAdmin, file); errCode != errcode.Success {
+		return "", time.Time{}, errCode
+	}
+
+	// 2. 签名：文件ID、用户ID、过期时间
+	ttl := viper.GetInt("upload.url_expire")
+	if ttl <= 0 {
+		ttl = 300
+	}
+	expiresAt := time.Now().Add(time.Duration(ttl) * time.Second)
+	expires := expiresAt.Unix()
+	url := fmt.Sprintf("%s/file/%d/download?uid=%d&expires=%d&sig=%s",
+		baseURL, fileID, userID, expires, storage.SignDownload(downloadSecret(), fileID, userID, expires))
+	return url, expiresAt, errcode.Success
+}
+
+// OpenSignedFile 校验下载链接的签名和有效期，并重新检查用户权限后打开文件
+func OpenSignedFile(fileID, userID, expires int64, sig string) (*models.StoredFile, io.ReadCloser, errcode.ErrCode) {
+	// 1. 签名和有效期
+	switch storage.VerifyDownload(downloadSecret(), fileID, userID, expires, sig, time.Now()) {
+	case storage.ErrBadSignature:
+		return nil, nil, errcode.PermissionDenied
+	case storage.ErrURLExpired:
+		return nil, nil, errcode.TokenExpired
+	}
+
+	// 2. 链接有效期内权限可能已经变了（比如取消了优秀作业），重新检查
+	file, err := dao.GetStoredFileByID(fileID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if file == nil {
+		return nil, nil, errcode.DataNotFound
+	}
+	user, err := dao.GetUserByID(userID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if user == nil {
+		return nil, nil, errcode.PermissionDenied
+	}
+	if errCode := checkFileVisible(userID, user.Role == models.Admin, file); errCode != errcode.Success {
+		return nil, nil, errCode
+	}
+
+	// 3. 打开文件
+	store, err := getStorage()
+	if err != nil {
+		return nil, nil, errcode.StorageError
+	}
+	reader, err := store.Get(context.Background(), file.StorageKey)
+	if err == storage.ErrNotFound {
+		return nil, nil, errcode.DataNotFound
+	}
+	if err != nil {
+		log.Printf("读取文件失败：%v", err)
+		return nil, nil, errcode.StorageError
+	}
+	return file, reader, errcode.Success
+}
+
+// checkFileVisible 谁能看文件：管理员、上传者、所属提交的学生或组员、被分配互评的同学；放进作品展的作品的当前附件所有人可见
+func checkFileVisible(userID int64, isAdmin bool, file *models.StoredFile) errcode.ErrCode {
+	if isAdmin || file.UploaderID == userID {
+		return errcode.Success
+	}
+	if file.SubmissionID == nil {
+		return errcode.PermissionDenied
+	}
+	sub, err := dao.GetSubmissionByID(*file.SubmissionID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if sub == nil {
+		return errcode.DataNotFound
+	}
+	if sub.StudentID == userID {
+		return errcode.Success
+	}
+	// 作品展里的作品（成绩已发布、学生同意展示）只公开当前版本的附件
+	if sub.IsExcellent && slices.Contains(sub.FileIDs, file.ID) {
+		showcased, err := dao.IsShowcased(sub.ID)
+		if err != nil {
+			return errcode.DBError
+		}
+		if showcased {
+			return errcode.Success
+		}
+	}
+	if sub.TeamID != nil {
+		team, err := dao.GetTeamByStudentAndHomework(userID, sub.HomeworkID)
+		if err != nil {
+			return errcode.DBError
+		}
+		if team != nil && team.ID == *sub.TeamID {
+			return errcode.Success
+		}
+	}
+	reviewer, err := dao.IsPeerReviewer(sub.ID, userID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if reviewer {
+		return errcode.Success
+	}
+	return errcode.PermissionDenied
+}
+
+// downloadSecret 下载链接的签名密钥
+func downloadSecret() string {
+	secret := viper.GetString("upload.sign_secret")
+	if secret == "" {
+		secret = viper.GetString("jwt.secret")
+	}
+	return secret
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/spreadsheet"
+	"github.com/spf13/viper"
+)
+
+// 导入表格的列：表头不区分大小写，中英文都可以，其他列忽略
+const (
+	importColSubmission = "submission_id"
+	importColUsername   = "username"
+	importColScore      = "score"
+	importColComment    = "comment"
+	importColExcellent  = "excellent"
+)
+
+var importHeaders = map[string]string{
+	"submission_id": importColSubmission,
+	"提交id":          importColSubmission,
+	"username":      importColUsername,
+	"用户名":           importColUsername,
+	"score":         importColScore,
+	"grade":         importColScore,
+	"分数":            importColScore,
+	"成绩":            importColScore,
+	"comment":       importColComment,
+	"评语":            importColComment,
+	"excellent":     importColExcellent,
+	"优秀":            importColExcellent,
+}
+
+// 导入的一行的校验结果
+type GradeImportRowResult struct {
+	Line         int      `json:"line"` // 表格里的行号
+	SubmissionID int64    `json:"submission_id,omitempty"`
+	Username     string   `json:"username,omitempty"` // 表格里填的用户名（原样返回）
+	OldScore     *int     `json:"old_score,omitempty"`
+	NewScore     *int     `json:"new_score,omitempty"`
+	ScoreDisplay string   `json:"score_display,omitempty"`
+	Comment      string   `json:"comment,omitempty"`
+	Excellent    *bool    `json:"excellent,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// 导入结果（预览时Applied为false）
+type GradeImportResult struct {
+	DryRun   bool                   `json:"dry_run"`
+	Format   string                 `json:"format"`
+	Total    int                    `json:"total"`   // 数据行数（不含表头）
+	Invalid  int                    `json:"invalid"` // 有错误的行数
+	Applied  bool                   `json:"applied"`
+	ImportID int64                  `json:"import_id,omitempty"` // 正式导入时的审计记录ID
+	Rows     []GradeImportRowResult `json:"rows"`
+}
+
+// maxImportSize 导入表格的大小上限
+func maxImportSize() int64 {
+	mb := viper.GetInt64("review.import_max_size_mb")
+	if mb <= 0 {
+		mb = 5
+	}
+	return mb << 20
+}
+
+// MaxImportRequestSize 导入请求体的大小上限（表格加上表单的其他字段）
+func MaxImportRequestSize() int64 {
+	return maxImportSize() + 1<<20
+}
+
+// ImportGrades 从CSV/XLSX批量导入作业成绩：每行按提交ID或用户名找到提交，写分数、评语和优秀标记；
+// 先校验所有行，dryRun时只返回预览，有任何一行出错都不导入，正式导入在一个事务里完成并记录
+func ImportGrades(adminID, homeworkID int64, header *multipart.FileHeader, dryRun, override bool) (*GradeImportResult, errcode.ErrCode) {
+	// 1. 查询作业：测验自动判分，有评分标准的要按分项批改，都不能导入总分
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	if homework.Type == models.HomeworkQuiz {
+		return nil, errcode.ParamError
+	}
+	rubric, err := dao.GetRubricByHomeworkID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if rubric != nil {
+		return nil, errcode.ParamError
+	}
+
+	// 2. 读取表格
+	if header.Size > maxImportSize() {
+		return nil, errcode.FileTooLarge
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, errcode.ParamError
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxImportSize()+1))
+	if err != nil {
+		return nil, errcode.ParamError
+	}
+	if int64(len(data)) > maxImportSize() {
+		return nil, errcode.FileTooLarge
+	}
+	format, rows, err := spreadsheet.Read(header.Filename, data)
+	if err != nil || len(rows) < 2 {
+		return nil, errcode.ParamError
+	}
+	maxRows := viper.GetInt("review.import_max_rows")
+	if maxRows > 0 && len(rows)-1 > maxRows {
+		return nil, errcode.ParamError
+	}
+
+	// 3. 解析表头：要有分数列，以及提交ID或用户名至少一列
+	columns := make(map[string]int)
+	for i, name := range rows[0].Cells {
+		if col, ok := importHeaders[strings.ToLower(name)]; ok {
+			if _, dup := columns[col]; !dup {
+				columns[col] = i
+			}
+		}
+	}
+	_, hasID := columns[importColSubmission]
+	_, hasUsername := columns[importColUsername]
+	if _, ok := columns[importColScore]; !ok || (!hasID && !hasUsername) {
+		return nil, errcode.ParamError
+	}
+	// 匿名批改期间按用户名匹配（预览里会返回对应的提交ID）等于查看身份，只能按提交ID导入
+	if hasUsername && blindActive(homework) {
+		return nil, errcode.PermissionDenied
+	}
+	cell := func(row spreadsheet.Row, col string) string {
+		i, ok := columns[col]
+		if !ok {
+			return ""
+		}
+		return row.Cell(i)
+	}
+
+	// 4. 按提交ID和用户名（小组作业时任一组员）索引作业的提交
+	subs, err := dao.ListSubmissionsForImport(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	byID := make(map[int64]*models.Submission, len(subs))
+	byUsername := make(map[string]*models.Submission, len(subs))
+	for i := range subs {
+		sub := &subs[i]
+		byID[sub.ID] = sub
+		byUsername[sub.Student.Username] = sub
+		if sub.Team != nil {
+			for _, m := range sub.Team.Members {
+				byUsername[m.Student.Username] = sub
+			}
+		}
+	}
+
+	// 5. 逐行校验
+	result := &GradeImportResult{DryRun: dryRun, Format: format, Total: len(rows) - 1}
+	seen := make(map[int64]int)
+	var matched []*models.Submission
+	var records models.GradeImportRows
+	for _, row := range rows[1:] {
+		r := GradeImportRowResult{Line: row.Line, Username: cell(row, importColUsername)}
+		sub := matchImportRow(&r, cell(row, importColSubmission), byID, byUsername)
+		if sub != nil {
+			r.SubmissionID = sub.ID
+			r.OldScore = sub.Score
+			if line, ok := seen[sub.ID]; ok {
+				r.Errors = append(r.Errors, fmt.Sprintf("和第%d行是同一份提交", line))
+			} else {
+				seen[sub.ID] = row.Line
+			}
+			if sub.Withdrawn {
+				r.Errors = append(r.Errors, "提交已撤回")
+			}
+			if !override {
+				if errCode := checkReviewClaim(sub, adminID); errCode != errcode.Success {
+					r.Errors = append(r.Errors, errCode.Msg())
+				}
+			}
+			if errCode := checkGradeLocked(homework, sub); errCode == errcode.DBError {
+				return nil, errCode
+			} else if errCode != errcode.Success {
+				r.Errors = append(r.Errors, errCode.Msg())
+			}
+		}
+
+		// 分数：数字按分数（表格里的85.0也算85），否则按等级（如A、通过）换算
+		value := cell(row, importColScore)
+		var score *int
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f == math.Trunc(f) && math.Abs(f) < 1e9 {
+			n := int(f)
+			score = &n
+		}
+		grade := ""
+		if score == nil {
+			grade = value
+		}
+		if newScore, _, errCode := computeScore(homework, nil, score, grade, nil); errCode != errcode.Success {
+			r.Errors = append(r.Errors, "分数不符合作业的评分制")
+		} else {
+			r.NewScore = newScore
+			r.ScoreDisplay = homework.GradingScale.Display(*newScore)
+		}
+
+		// 评语为空时保留原来的
+		r.Comment = cell(row, importColComment)
+		if r.Comment == "" && sub != nil {
+			r.Comment = sub.Comment
+		}
+
+		// 优秀标记为空时不改
+		if value := cell(row, importColExcellent); value != "" {
+			excellent, ok := parseImportBool(value)
+			if !ok {
+				r.Errors = append(r.Errors, "优秀标记只能填是/否")
+			} else {
+				r.Excellent = &excellent
+			}
+		}
+
+		if len(r.Errors) > 0 {
+			result.Invalid++
+		} else {
+			matched = append(matched, sub)
+			records = append(records, models.GradeImportRow{
+				Line:         row.Line,
+				SubmissionID: sub.ID,
+				OldScore:     sub.Score,
+				NewScore:     *r.NewScore,
+				Comment:      r.Comment,
+				Excellent:    r.Excellent,
+			})
+		}
+		result.Rows = append(result.Rows, r)
+	}
+	if dryRun {
+		return result, errcode.Success
+	}
+	if result.Invalid > 0 {
+		return result, errcode.GradeImportInvalid
+	}
+
+	// 6. 正式导入（同一事务）并记录
+	now := time.Now()
+	changes := make([]*models.ScoreChange, len(matched))
+	for i, sub := range matched {
+		record := records[i]
+		changes[i] = scoreChange(sub.Score, record.NewScore, models.ScoreSourceImport, adminID, record.Comment)
+		score := record.NewScore
+		sub.Score = &score
+		sub.Comment = record.Comment
+		sub.ReviewerID = &adminID
+		sub.ReviewedAt = &now
+	}
+	gradeImport := &models.GradeImport{
+		HomeworkID: homeworkID,
+		ImporterID: adminID,
+		FileName:   header.Filename,
+		Format:     format,
+		RowCount:   len(records),
+		Rows:       records,
+	}
+	if err := dao.ImportGrades(gradeImport, matched, changes); err != nil {
+		if errors.Is(err, dao.ErrVersionConflict) {
+			return nil, errcode.VersionConflict
+		}
+		return nil, errcode.DBError
+	}
+	result.Applied = true
+	result.ImportID = gradeImport.ID
+	return result, errcode.Success
+}
+
+// matchImportRow 按提交ID或用户名找到行对应的提交（两个都填时要一致），找不到时记录错误
+func matchImportRow(r *GradeImportRowResult, idValue string, byID map[int64]*models.Submission, byUsername map[string]*models.Submission) *models.Submission {
+	var sub *models.Submission
+	if idValue != "" {
+		id, err := strconv.ParseInt(idValue, 10, 64)
+		if err != nil || byID[id] == nil {
+			r.Errors = append(r.Errors, "提交ID在这个作业里不存在")
+			return nil
+		}
+		sub = byID[id]
+	}
+	if r.Username != "" {
+		byName := byUsername[r.Username]
+		if byName == nil {
+			r.Errors = append(r.Errors, "这个用户在作业里没有提交")
+			return nil
+		}
+		if sub != nil && sub != byName {
+			r.Errors = append(r.Errors, "提交ID和用户名对不上")
+			return nil
+		}
+		sub = byName
+	}
+	if sub == nil {
+		r.Errors = append(r.Errors, "提交ID和用户名至少填一个")
+	}
+	return sub
+}
+
+// parseImportBool 解析优秀标记（是/否、true/false、1/0、y/n）
+func parseImportBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "是", "true", "1", "y", "yes":
+		return true, true
+	case "否", "false", "0", "n", "no":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// ListGradeImports 查询作业的成绩导入记录
+func ListGradeImports(homeworkID int64) ([]models.GradeImport, errcode.ErrCode) {
+	list, err := dao.ListGradeImports(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	return list, errcode.Success
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// ReleaseGrades 发布作业成绩：releaseAt为空（或已经过去）时立即发布，否则预定到那个时间发布；
+// 发布后学生能看到分数、评语和优秀标记，匿名批改随之结束，已有的成绩锁定
+func ReleaseGrades(homeworkID int64, releaseAt *time.Time) (*models.Homework, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	now := time.Now()
+	at := now
+	if releaseAt != nil && releaseAt.After(now) {
+		at = *releaseAt
+	}
+	released, err := dao.ReleaseHomeworkGrades(homeworkID, at, now)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	// 已经发布过的不能再发布或改时间
+	if !released {
+		return nil, errcode.ParamError
+	}
+	homework.GradesReleasedAt = &at
+	homework.Version++
+	return homework, errcode.Success
+}
+
+// CancelGradeRelease 取消还没到时间的预定发布
+func CancelGradeRelease(homeworkID int64) errcode.ErrCode {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	cancelled, err := dao.CancelHomeworkGradeRelease(homeworkID, time.Now())
+	if err != nil {
+		return errcode.DBError
+	}
+	if !cancelled {
+		return errcode.ParamError
+	}
+	return errcode.Success
+}
+
+// hideUnreleasedGrade 成绩发布前对学生隐藏批改结果（分数、评语、优秀标记和分项得分都还是草稿）
+func hideUnreleasedGrade(homework *models.Homework, sub *models.Submission, now time.Time) {
+	if homework.GradesVisible(now) {
+		return
+	}
+	sub.Score = nil
+	sub.Comment = ""
+	sub.IsExcellent = false
+	sub.ExcellentReason = ""
+	sub.ReviewerID = nil
+	sub.ReviewedAt = nil
+	sub.CriterionScores = nil
+	sub.ScoreDisplay = ""
+	sub.ScorePercent = nil
+}
+
+// checkGradeLocked 成绩发布后已有的成绩锁定，只有学生申请了复核（待处理）时才能重新批改
+func checkGradeLocked(homework *models.Homework, sub *models.Submission) errcode.ErrCode {
+	if sub.Score == nil || !homework.GradesReleased(time.Now()) {
+		return errcode.Success
+	}
+	pending, err := dao.HasPendingRegrade(sub.ID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if !pending {
+		return errcode.GradesLocked
+	}
+	return errcode.Success
+}
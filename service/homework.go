@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"github.com/chuji555/homework-system/dao"
 	"github.com/chuji555/homework-system/models"
 	"github.com/chuji555/homework-system/pkg/errcode"
@@ -8,15 +9,37 @@ import (
 )
 
 // CreateHomework 创建作业
-func CreateHomework(title, desc, dept string, creatorID int64, deadline time.Time, allowLate bool) errcode.ErrCode {
+func CreateHomework(title, desc, dept string, creatorID int64, deadline time.Time, allowLate bool, maxAttempts int, gradingScale, homeworkType string) errcode.ErrCode {
+	// 未指定评分制时默认百分制
+	scale := models.GradingScale(gradingScale)
+	if scale == "" {
+		scale = models.ScalePercentage
+	}
+	if !scale.IsValid() {
+		return errcode.ParamError
+	}
+	// 未指定类型时为普通作业（测验由题库接口创建）
+	kind := models.HomeworkType(homeworkType)
+	if kind == "" {
+		kind = models.HomeworkNormal
+	}
+	if kind != models.HomeworkNormal && kind != models.HomeworkGit {
+		return errcode.ParamError
+	}
+
 	// 先声明并初始化 homework 变量
 	homework := &models.Homework{
-		Title:       title,
-		Description: desc,
-		Department:  models.Department(dept),
-		CreatorID:   creatorID,
-		Deadline:    deadline,
-		AllowLate:   allowLate,
+		Title:        title,
+		Description:  desc,
+		Department:   models.Department(dept),
+		CreatorID:    creatorID,
+		Deadline:     deadline,
+		AllowLate:    allowLate,
+		MaxAttempts:  maxAttempts,
+		GradingScale: scale,
+		Type:         kind,
+		// 批改结果先作为草稿，发布成绩后学生才能看到
+		RequireGradeRelease: true,
 	}
 
 	// 调用 dao 层创建
@@ -27,8 +50,8 @@ func CreateHomework(title, desc, dept string, creatorID int64, deadline time.Tim
 }
 
 // UpdateHomework 修改作业
-func UpdateHomework(homeworkID int64, title, desc, dept string, deadline *time.Time, allowLate *bool) errcode.ErrCode {
-	// 1. 先查询作业是否存在
+func UpdateHomework(homeworkID, editorID int64, title, desc, dept string, deadline *time.Time, allowLate *bool, maxAttempts *int, gradingScale string, ifMatch int) errcode.ErrCode {
+	// 1. 先查询作业是否存在，If-Match的版本要和当前一致
 	homework, err := dao.GetHomeworkByID(homeworkID)
 	if err != nil {
 		return errcode.DBError
@@ -36,8 +59,12 @@ func UpdateHomework(homeworkID int64, title, desc, dept string, deadline *time.T
 	if homework == nil {
 		return errcode.DataNotFound
 	}
+	if ifMatch != homework.Version {
+		return errcode.VersionConflict
+	}
 
 	// 2. 只更新传了的字段（指针判断是否传值）
+	before := homework.Snapshot()
 	if title != "" {
 		homework.Title = title
 	}
@@ -53,11 +80,59 @@ func UpdateHomework(homeworkID int64, title, desc, dept string, deadline *time.T
 	if allowLate != nil {
 		homework.AllowLate = *allowLate
 	}
+	if maxAttempts != nil {
+		homework.MaxAttempts = *maxAttempts
+	}
+	if gradingScale != "" && models.GradingScale(gradingScale) != homework.GradingScale {
+		if errCode := checkScaleChange(homeworkID, models.GradingScale(gradingScale)); errCode != errcode.Success {
+			return errCode
+		}
+		homework.GradingScale = models.GradingScale(gradingScale)
+	}
+
+	// 3. 和修改前比较，没有实际改动就不生成新版本
+	changes := models.DiffSnapshots(before, homework.Snapshot())
+	if len(changes) == 0 {
+		return errcode.Success
+	}
+
+	// 4. 调用 dao 层修改，同时记录修改人和改动的字段
+	revision := &models.HomeworkRevision{
+		EditorID: editorID,
+		Snapshot: homework.Snapshot(),
+		Changes:  changes,
+		Notable:  changes.IsNotable(),
+	}
+	if err := dao.UpdateHomework(homework, before, revision); err != nil {
+		if errors.Is(err, dao.ErrVersionConflict) {
+			return errcode.VersionConflict
+		}
+		return errcode.DBError
+	}
+	return errcode.Success
+}
 
-	// 3. 调用 dao 层修改
-	if err := dao.UpdateHomework(homework); err != nil {
+// checkScaleChange 校验能否切换评分制：已批改过的作业不能改，评分标准总分要落在新评分制范围内
+func checkScaleChange(homeworkID int64, scale models.GradingScale) errcode.ErrCode {
+	if !scale.IsValid() {
+		return errcode.ParamError
+	}
+	reviewed, err := dao.CountReviewedSubmissionByHomework(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if reviewed > 0 {
+		return errcode.ParamError
+	}
+	rubric, err := dao.GetRubricByHomeworkID(homeworkID)
+	if err != nil {
 		return errcode.DBError
 	}
+	if rubric != nil {
+		if !scale.IsNumeric() || rubricMaxPoints(rubric) > scale.MaxScore() {
+			return errcode.ParamError
+		}
+	}
 	return errcode.Success
 }
 
@@ -79,17 +154,30 @@ func DeleteHomework(homeworkID int64) errcode.ErrCode {
 	return errcode.Success
 }
 
-// ListHomework 分页查询作业列表
-func ListHomework(department string, page, pageSize int) ([]models.Homework, int64, errcode.ErrCode) {
+// ListHomework 分页查询作业列表（studentID不为0时标记对该学生锁定的作业）
+func ListHomework(department string, page, pageSize int, studentID int64) ([]models.Homework, int64, errcode.ErrCode) {
 	list, total, err := dao.ListHomework(department, page, pageSize)
 	if err != nil {
 		return nil, 0, errcode.DBError
 	}
+	if studentID != 0 {
+		locked, err := lockedHomework(studentID, list)
+		if err != nil {
+			return nil, 0, errcode.DBError
+		}
+		for i := range list {
+			list[i].Locked = locked[list[i].ID]
+		}
+		// 标记学生看过之后又改了截止时间或要求的作业
+		if err := markUpdatedHomework(studentID, list); err != nil {
+			return nil, 0, errcode.DBError
+		}
+	}
 	return list, total, errcode.Success
 }
 
-// GetHomeworkByID 查询作业详情
-func GetHomeworkByID(homeworkID int64) (*models.Homework, errcode.ErrCode) {
+// GetHomeworkByID 查询作业详情（studentID不为0时标记是否对该学生锁定）
+func GetHomeworkByID(homeworkID, studentID int64) (*models.Homework, errcode.ErrCode) {
 	homework, err := dao.GetHomeworkByID(homeworkID)
 	if err != nil {
 		return nil, errcode.DBError
@@ -97,5 +185,94 @@ func GetHomeworkByID(homeworkID int64) (*models.Homework, errcode.ErrCode) {
 	if homework == nil {
 		return nil, errcode.DataNotFound
 	}
+	if studentID != 0 {
+		if errCode := checkUnlocked(studentID, homework); errCode == errcode.HomeworkLocked {
+			homework.Locked = true
+		} else if errCode != errcode.Success {
+			return nil, errCode
+		}
+		// 带上学生上次查看之后的改动，并记录本次查看
+		if err := fillHomeworkChanges(studentID, homework); err != nil {
+			return nil, errcode.DBError
+		}
+	}
 	return homework, errcode.Success
 }
+
+// 作业成绩统计（分数统一换算成百分比）
+type HomeworkStats struct {
+	SubmittedCount int64   `json:"submitted_count"`
+	ReviewedCount  int64   `json:"reviewed_count"`
+	AvgPercent     float64 `json:"avg_percent"`
+	MaxPercent     float64 `json:"max_percent"`
+	MinPercent     float64 `json:"min_percent"`
+	PassRate       float64 `json:"pass_rate"` // 百分比>=60视为及格
+}
+
+// GetHomeworkStats 查询作业成绩统计
+func GetHomeworkStats(homeworkID int64) (*HomeworkStats, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+
+	submitted, err := dao.CountSubmissionByHomework(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	scores, err := dao.ListReviewedScoresByHomework(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	stats := &HomeworkStats{
+		SubmittedCount: submitted,
+		ReviewedCount:  int64(len(scores)),
+	}
+	// 小组作业按每个组员的个人得分统计
+	if homework.IsGroup {
+		memberScores, err := dao.ListReviewedTeamMemberScores(homeworkID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+		scores = make([]int, 0, len(memberScores))
+		for _, ms := range memberScores {
+			scores = append(scores, teamMemberScore(ms.Score, ms.ScoreAdjustment, homework.GradingScale))
+		}
+	}
+	if len(scores) == 0 {
+		return stats, errcode.Success
+	}
+	sum, passed := 0.0, 0
+	stats.MinPercent = 100
+	for _, score := range scores {
+		p := homework.GradingScale.Percentage(score)
+		sum += p
+		if p > stats.MaxPercent {
+			stats.MaxPercent = p
+		}
+		if p < stats.MinPercent {
+			stats.MinPercent = p
+		}
+		if p >= 60 {
+			passed++
+		}
+	}
+	stats.AvgPercent = sum / float64(len(scores))
+	stats.PassRate = float64(passed) / float64(len(scores))
+	return stats, errcode.Success
+}
+
+// homeworkUpdateResult 按版本号修改作业设置的结果转换成错误码
+func homeworkUpdateResult(err error) errcode.ErrCode {
+	if errors.Is(err, dao.ErrVersionConflict) {
+		return errcode.VersionConflict
+	}
+	if err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// 幂等键的保留时间
+const idempotencyTTL = 24 * time.Hour
+
+// BeginIdempotentRequest 开始处理带幂等键的请求：
+// 第一次请求返回新记录（replay=false），重试且第一次已完成时返回保存的记录（replay=true）
+func BeginIdempotentRequest(userID int64, key, path, requestHash string) (*models.IdempotencyRecord, bool, errcode.ErrCode) {
+	// 最多两次：第二次是删掉过期记录之后重新占用
+	for i := 0; i < 2; i++ {
+		// 1. 尝试占用幂等键
+		record := &models.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Path:        path,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(idempotencyTTL),
+		}
+		err := dao.CreateIdempotencyRecord(record)
+		if err == nil {
+			return record, false, errcode.Success
+		}
+		if !dao.IsDuplicateKey(err) {
+			return nil, false, errcode.DBError
+		}
+
+		// 2. 已被占用：查出原记录
+		existing, err := dao.GetIdempotencyRecord(userID, key)
+		if err != nil {
+			return nil, false, errcode.DBError
+		}
+		if existing == nil {
+			// 刚好被删掉了，重新占用
+			continue
+		}
+		if time.Now().After(existing.ExpiresAt) {
+			if err := dao.DeleteIdempotencyRecord(existing.ID); err != nil {
+				return nil, false, errcode.DBError
+			}
+			continue
+		}
+
+		// 3. 同一个key只能用于同一个请求；第一次还没处理完时让客户端稍后再试
+		if existing.Path != path || existing.RequestHash != requestHash || !existing.Done {
+			return nil, false, errcode.IdempotencyConflict
+		}
+		return existing, true, errcode.Success
+	}
+	return nil, false, errcode.IdempotencyConflict
+}
+
+// FinishIdempotentRequest 请求处理完：成功的响应保存下来供重试时返回，失败的删除记录让客户端可以用同一个key重试
+func FinishIdempotentRequest(recordID int64, success bool, status int, contentType, body string) {
+	var err error
+	if success {
+		err = dao.FinishIdempotencyRecord(recordID, status, contentType, body)
+	} else {
+		err = dao.DeleteIdempotencyRecord(recordID)
+	}
+	if err != nil {
+		log.Printf("保存幂等记录失败：%v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/spf13/viper"
+)
+
+// 没交作业的一个学生
+type MissingStudent struct {
+	StudentID      int64      `json:"student_id"`
+	Username       string     `json:"username"`
+	Nickname       string     `json:"nickname"`
+	Email          string     `json:"email,omitempty"`
+	LastLoginAt    *time.Time `json:"last_login_at,omitempty"` // 从没登录过为空
+	Deadline       time.Time  `json:"deadline"`                // 有个人延期时是延期后的时间
+	Extended       bool       `json:"extended"`
+	Overdue        bool       `json:"overdue"`          // 已经过了截止时间
+	PriorHomeworks int        `json:"prior_homeworks"`  // 以往（本部门截止更早、注册之后）的作业数
+	PriorSubmitted int        `json:"prior_submitted"`  // 其中交了的
+	PriorRate      *float64   `json:"prior_rate"`       // 以往提交率（0~1），没有以往作业时为空
+	LastRemindedAt *time.Time `json:"last_reminded_at"` // 最近一次收到这个作业的提醒
+}
+
+// 作业的未交名单
+type MissingSubmissions struct {
+	HomeworkID    int64            `json:"homework_id"`
+	Title         string           `json:"title"`
+	Department    string           `json:"department"`
+	Deadline      time.Time        `json:"deadline"`
+	TotalStudents int              `json:"total_students"` // 部门学生数
+	Submitted     int              `json:"submitted"`
+	Missing       []MissingStudent `json:"missing"`
+}
+
+// 一键提醒的结果
+type RemindResult struct {
+	Sent    int     `json:"sent"`
+	Skipped []int64 `json:"skipped"` // 最近刚提醒过的学生
+}
+
+// reminderInterval 同一作业给同一学生发提醒的最短间隔
+func reminderInterval() time.Duration {
+	hours := viper.GetInt("reminder.min_interval_hours")
+	if hours < 0 {
+		hours = 0
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// ListMissingSubmissions 查询作业所属部门里还没交（或撤回后没再交）的学生，附上最近登录时间和以往提交率；
+// 小组作业按组算，组里交了就算交了
+func ListMissingSubmissions(homeworkID int64) (*MissingSubmissions, errcode.ErrCode) {
+	// 1. 查询作业和部门学生
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	students, err := dao.ListStudentsByDepartment(string(homework.Department))
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	// 2. 一次查出这个作业和以往作业的提交
+	prior, err := dao.ListHomeworkBefore(string(homework.Department), homework.Deadline)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	homeworkIDs := []int64{homework.ID}
+	for _, h := range prior {
+		if h.ID != homework.ID {
+			homeworkIDs = append(homeworkIDs, h.ID)
+		}
+	}
+	submitted, err := submittedHomeworks(homeworkIDs)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	// 3. 个人延期和提醒记录
+	extensions, err := dao.ListDeadlineExtensionByHomework(homework.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	extended := make(map[int64]time.Time, len(extensions))
+	for _, e := range extensions {
+		extended[e.StudentID] = e.Deadline
+	}
+	reminded, err := dao.LastReminderTimes(homework.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	// 4. 逐个学生整理
+	now := time.Now()
+	result := &MissingSubmissions{
+		HomeworkID:    homework.ID,
+		Title:         homework.Title,
+		Department:    string(homework.Department),
+		Deadline:      homework.Deadline,
+		TotalStudents: len(students),
+		Missing:       make([]MissingStudent, 0),
+	}
+	for _, s := range students {
+		if submitted[s.ID][homework.ID] {
+			result.Submitted++
+			continue
+		}
+		m := MissingStudent{
+			StudentID:   s.ID,
+			Username:    s.Username,
+			Nickname:    s.Nickname,
+			Email:       s.Email,
+			LastLoginAt: s.LastLoginAt,
+			Deadline:    homework.Deadline,
+		}
+		if deadline, ok := extended[s.ID]; ok {
+			m.Deadline = deadline
+			m.Extended = true
+		}
+		m.Overdue = now.After(m.Deadline)
+		// 注册之前就截止的作业不算
+		for _, h := range prior {
+			if h.ID == homework.ID || h.Deadline.Before(s.CreatedAt) {
+				continue
+			}
+			m.PriorHomeworks++
+			if submitted[s.ID][h.ID] {
+				m.PriorSubmitted++
+			}
+		}
+		if m.PriorHomeworks > 0 {
+			rate := float64(m.PriorSubmitted) / float64(m.PriorHomeworks)
+			m.PriorRate = &rate
+		}
+		if at, ok := reminded[s.ID]; ok {
+			m.LastRemindedAt = &at
+		}
+		result.Missing = append(result.Missing, m)
+	}
+	return result, errcode.Success
+}
+
+// submittedHomeworks 学生ID -> 作业ID -> 是否交了（不含撤回的，小组提交算到每个组员头上）
+func submittedHomeworks(homeworkIDs []int64) (map[int64]map[int64]bool, error) {
+	subs, err := dao.ListSubmissionByHomeworkIDs(homeworkIDs)
+	if err != nil {
+		return nil, err
+	}
+	teamIDs := make([]int64, 0)
+	for _, s := range subs {
+		if s.TeamID != nil {
+			teamIDs = append(teamIDs, *s.TeamID)
+		}
+	}
+	members, err := dao.ListTeamMembersByTeamIDs(teamIDs)
+	if err != nil {
+		return nil, err
+	}
+	teamMembers := make(map[int64][]int64)
+	for _, m := range members {
+		teamMembers[m.TeamID] = append(teamMembers[m.TeamID], m.StudentID)
+	}
+	result := make(map[int64]map[int64]bool)
+	for _, s := range subs {
+		owners := []int64{s.StudentID}
+		if s.TeamID != nil {
+			owners = append(owners, teamMembers[*s.TeamID]...)
+		}
+		for _, studentID := range owners {
+			if result[studentID] == nil {
+				result[studentID] = make(map[int64]bool)
+			}
+			result[studentID][s.HomeworkID] = true
+		}
+	}
+	return result, nil
+}
+
+// RemindMissingStudents 给作业的未交名单里的学生发站内提醒，最近刚提醒过的跳过；message为空时用默认内容
+func RemindMissingStudents(adminID, homeworkID int64, message string) (*RemindResult, errcode.ErrCode) {
+	// 1. 未交名单
+	missing, errCode := ListMissingSubmissions(homeworkID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+
+	// 2. 逐个生成提醒（按学生自己的截止时间写默认内容）
+	message = strings.TrimSpace(message)
+	now := time.Now()
+	interval := reminderInterval()
+	result := &RemindResult{Skipped: make([]int64, 0)}
+	reminders := make([]models.Reminder, 0, len(missing.Missing))
+	for _, m := range missing.Missing {
+		if m.LastRemindedAt != nil && now.Sub(*m.LastRemindedAt) < interval {
+			result.Skipped = append(result.Skipped, m.StudentID)
+			continue
+		}
+		text := message
+		if text == "" {
+			text = fmt.Sprintf("作业《%s》你还没有提交，截止时间：%s", missing.Title, m.Deadline.Format("2006-01-02 15:04"))
+		}
+		reminders = append(reminders, models.Reminder{
+			HomeworkID: homeworkID,
+			StudentID:  m.StudentID,
+			SenderID:   adminID,
+			Message:    text,
+		})
+	}
+
+	// 3. 保存
+	if err := dao.CreateReminders(reminders); err != nil {
+		return nil, errcode.DBError
+	}
+	result.Sent = len(reminders)
+	return result, errcode.Success
+}
+
+// MissingSubmissionsCSV 未交名单导出成表格的行（第一行是表头）
+func MissingSubmissionsCSV(missing *MissingSubmissions) [][]string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+	rows := [][]string{{"学生ID", "用户名", "昵称", "邮箱", "最近登录", "截止时间", "已过截止", "以往作业数", "以往已交", "以往提交率", "最近提醒"}}
+	for _, m := range missing.Missing {
+		rate := ""
+		if m.PriorRate != nil {
+			rate = strconv.FormatFloat(*m.PriorRate*100, 'f', 1, 64) + "%"
+		}
+		overdue := "否"
+		if m.Overdue {
+			overdue = "是"
+		}
+		rows = append(rows, []string{
+			strconv.FormatInt(m.StudentID, 10),
+			m.Username,
+			m.Nickname,
+			m.Email,
+			formatTime(m.LastLoginAt),
+			formatTime(&m.Deadline),
+			overdue,
+			strconv.Itoa(m.PriorHomeworks),
+			strconv.Itoa(m.PriorSubmitted),
+			rate,
+			formatTime(m.LastRemindedAt),
+		})
+	}
+	return rows
+}
+
+// ListMyReminders 学生查询收到的提醒
+func ListMyReminders(studentID int64, page, pageSize int) ([]models.Reminder, int64, errcode.ErrCode) {
+	if page <= 0 || pageSize <= 0 || pageSize > 100 {
+		return nil, 0, errcode.ParamError
+	}
+	list, total, err := dao.ListRemindersByStudent(studentID, page, pageSize)
+	if err != nil {
+		return nil, 0, errcode.DBError
+	}
+	return list, total, errcode.Success
+}
+
+// MarkRemindersRead 学生把提醒全部标为已读
+func MarkRemindersRead(studentID int64) errcode.ErrCode {
+	if err := dao.MarkRemindersRead(studentID, time.Now()); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
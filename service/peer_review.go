@@ -0,0 +1,370 @@
+package service
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// 互评打分的入参（和批改一样：有评分标准时按分项打分，否则打总分或等级）
+type PeerReviewInput struct {
+	Score           *int
+	Grade           string
+	CriterionScores []CriterionScoreInput
+	Comment         string
+}
+
+// 学生看到的互评任务（不含被评同学的身份）
+type PeerReviewTask struct {
+	ID              int64                      `json:"id"`
+	HomeworkID      int64                      `json:"homework_id"`
+	Deadline        time.Time                  `json:"deadline"`
+	Open            bool                       `json:"open"`
+	Content         string                     `json:"content"`
+	FileURL         string                     `json:"file_url,omitempty"`
+	Files           []PeerReviewFile           `json:"files,omitempty"`
+	Score           *int                       `json:"score,omitempty"`
+	CriterionScores models.PeerCriterionScores `json:"criterion_scores,omitempty"`
+	Comment         string                     `json:"comment,omitempty"`
+	SubmittedAt     *time.Time                 `json:"submitted_at,omitempty"`
+}
+
+// 互评任务里的附件（不含上传者）
+type PeerReviewFile struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// 一份提交收到的单条互评（学生查看时不含评阅人）
+type PeerReviewFeedback struct {
+	Score           *int                       `json:"score,omitempty"`
+	CriterionScores models.PeerCriterionScores `json:"criterion_scores,omitempty"`
+	Comment         string                     `json:"comment,omitempty"`
+	SubmittedAt     *time.Time                 `json:"submitted_at,omitempty"`
+	Reviewer        *models.User               `json:"reviewer,omitempty"` // 只有管理员能看到
+}
+
+// 一份提交的互评汇总（批改时参考）
+type PeerReviewSummary struct {
+	SubmissionID int64                   `json:"submission_id"`
+	Assigned     int                     `json:"assigned"`          // 分配的评阅人数
+	Completed    int                     `json:"completed"`         // 已评的人数
+	Score        *float64                `json:"score,omitempty"`   // 去掉极端分后的平均分
+	Trimmed      int                     `json:"trimmed"`           // 两端各去掉了几个分数
+	Reviews      []PeerReviewFeedback    `json:"reviews,omitempty"` // 逐条互评
+	Round        *models.PeerReviewRound `json:"round,omitempty"`
+}
+
+// 作业的互评概览（管理员）
+type PeerReviewOverview struct {
+	Round       models.PeerReviewRound `json:"round"`
+	Open        bool                   `json:"open"`
+	Submissions []PeerReviewSummary    `json:"submissions"`
+}
+
+// StartPeerReview 作业截止后开启互评：每份提交随机分给N个同作业的其他提交者（小组作业是其他小组的全体组员），互相匿名
+func StartPeerReview(adminID, homeworkID int64, reviewersPerSubmission int, deadline time.Time) (*models.PeerReviewRound, errcode.ErrCode) {
+	// 1. 作业要存在且已截止，测验不需要互评
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	now := time.Now()
+	if homework.Type == models.HomeworkQuiz || now.Before(homework.Deadline) {
+		return nil, errcode.ParamError
+	}
+	if reviewersPerSubmission <= 0 || !deadline.After(now) {
+		return nil, errcode.ParamError
+	}
+
+	// 2. 每个作业只有一轮互评
+	existing, err := dao.GetPeerReviewRoundByHomework(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if existing != nil {
+		return nil, errcode.ParamError
+	}
+
+	// 3. 参与互评的是开启时已有的提交，提交数要比每份的评阅人数多（不能评自己）
+	subs, err := dao.ListSubmissionByHomeworkIDs([]int64{homeworkID})
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if len(subs) <= reviewersPerSubmission {
+		return nil, errcode.ParamError
+	}
+
+	// 4. 小组作业每个组员都参与互评，不只是提交的人
+	reviewers, errCode := submissionReviewers(subs)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+
+	// 5. 打乱顺序后按环分配：第i份提交的提交者（小组作业是全体组员）各自评后面的N份，
+	// 这样每人恰好评N份，个人作业每份提交恰好有N个评阅人，小组作业按评阅小组的人数多一些
+	rand.Shuffle(len(subs), func(i, j int) { subs[i], subs[j] = subs[j], subs[i] })
+	assignments := make([]models.PeerReviewAssignment, 0, len(subs)*reviewersPerSubmission)
+	for i, sub := range subs {
+		for _, reviewerID := range reviewers[sub.ID] {
+			for k := 1; k <= reviewersPerSubmission; k++ {
+				assignments = append(assignments, models.PeerReviewAssignment{
+					SubmissionID: subs[(i+k)%len(subs)].ID,
+					ReviewerID:   reviewerID,
+				})
+			}
+		}
+	}
+
+	// 6. 保存
+	round := &models.PeerReviewRound{
+		HomeworkID:             homeworkID,
+		ReviewersPerSubmission: reviewersPerSubmission,
+		Deadline:               deadline,
+		CreatorID:              adminID,
+	}
+	if err := dao.CreatePeerReviewRound(round, assignments); err != nil {
+		if dao.IsDuplicateKey(err) {
+			return nil, errcode.ParamError
+		}
+		return nil, errcode.DBError
+	}
+	return round, errcode.Success
+}
+
+// submissionReviewers 每份提交由谁去评别人：个人作业是提交者，小组作业是全体组员
+func submissionReviewers(subs []models.Submission) (map[int64][]int64, errcode.ErrCode) {
+	var teamIDs []int64
+	for _, sub := range subs {
+		if sub.TeamID != nil {
+			teamIDs = append(teamIDs, *sub.TeamID)
+		}
+	}
+	members, err := dao.ListTeamMembersByTeamIDs(teamIDs)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	teamMembers := make(map[int64][]int64, len(teamIDs))
+	for _, m := range members {
+		teamMembers[m.TeamID] = append(teamMembers[m.TeamID], m.StudentID)
+	}
+	reviewers := make(map[int64][]int64, len(subs))
+	for _, sub := range subs {
+		if sub.TeamID != nil && len(teamMembers[*sub.TeamID]) > 0 {
+			reviewers[sub.ID] = teamMembers[*sub.TeamID]
+		} else {
+			reviewers[sub.ID] = []int64{sub.StudentID}
+		}
+	}
+	return reviewers, errcode.Success
+}
+
+// ClosePeerReview 管理员提前结束互评
+func ClosePeerReview(homeworkID int64) errcode.ErrCode {
+	round, err := dao.GetPeerReviewRoundByHomework(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if round == nil {
+		return errcode.DataNotFound
+	}
+	if !round.Open(time.Now()) {
+		return errcode.ParamError
+	}
+	if err := dao.ClosePeerReviewRound(round.ID, time.Now()); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// GetPeerReviewOverview 管理员查看作业互评的进度和每份提交的汇总分
+func GetPeerReviewOverview(homeworkID int64) (*PeerReviewOverview, errcode.ErrCode) {
+	round, err := dao.GetPeerReviewRoundByHomework(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if round == nil {
+		return nil, errcode.DataNotFound
+	}
+	assignments, err := dao.ListPeerAssignmentsByRound(round.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	// 按提交分组汇总（assignments已按提交ID排序）
+	overview := &PeerReviewOverview{Round: *round, Open: round.Open(time.Now())}
+	for start := 0; start < len(assignments); {
+		end := start
+		for end < len(assignments) && assignments[end].SubmissionID == assignments[start].SubmissionID {
+			end++
+		}
+		overview.Submissions = append(overview.Submissions, summarizePeerReviews(assignments[start].SubmissionID, assignments[start:end], true))
+		start = end
+	}
+	return overview, errcode.Success
+}
+
+// summarizePeerReviews 汇总一份提交收到的互评
+func summarizePeerReviews(subID int64, assignments []models.PeerReviewAssignment, withReviewer bool) PeerReviewSummary {
+	summary := PeerReviewSummary{SubmissionID: subID, Assigned: len(assignments)}
+	var scores []int
+	for i := range assignments {
+		a := &assignments[i]
+		if a.Score == nil {
+			continue
+		}
+		scores = append(scores, *a.Score)
+		feedback := PeerReviewFeedback{
+			Score:           a.Score,
+			CriterionScores: a.CriterionScores,
+			Comment:         a.Comment,
+			SubmittedAt:     a.SubmittedAt,
+		}
+		if withReviewer {
+			feedback.Reviewer = &a.Reviewer
+		}
+		summary.Reviews = append(summary.Reviews, feedback)
+	}
+	summary.Completed = len(scores)
+	summary.Score, summary.Trimmed = trimmedMean(scores)
+	return summary
+}
+
+// trimmedMean 去掉极端分后求平均：3个及以上分数时两端各去掉20%（至少各去1个）
+func trimmedMean(scores []int) (*float64, int) {
+	if len(scores) == 0 {
+		return nil, 0
+	}
+	sorted := append([]int(nil), scores...)
+	sort.Ints(sorted)
+	trim := 0
+	if len(sorted) >= 3 {
+		trim = max(1, len(sorted)/5)
+	}
+	kept := sorted[trim : len(sorted)-trim]
+	sum := 0
+	for _, s := range kept {
+		sum += s
+	}
+	mean := float64(sum) / float64(len(kept))
+	return &mean, trim
+}
+
+// ListMyPeerReviewTasks 学生查询自己要评的提交
+func ListMyPeerReviewTasks(studentID int64) ([]PeerReviewTask, errcode.ErrCode) {
+	list, err := dao.ListPeerAssignmentsByReviewer(studentID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	now := time.Now()
+	tasks := make([]PeerReviewTask, 0, len(list))
+	for _, a := range list {
+		task := PeerReviewTask{
+			ID:              a.ID,
+			HomeworkID:      a.Round.HomeworkID,
+			Deadline:        a.Round.Deadline,
+			Open:            a.Round.Open(now),
+			Content:         a.Submission.Content,
+			Score:           a.Score,
+			CriterionScores: a.CriterionScores,
+			Comment:         a.Comment,
+			SubmittedAt:     a.SubmittedAt,
+		}
+		// 仓库作业的地址里一般带着作者的账号，不给；仓库快照在附件里，通过文件下载查看
+		if a.Submission.Homework.Type != models.HomeworkGit {
+			task.FileURL = a.Submission.FileURL
+		}
+		// 只给当前版本的附件
+		current := make(map[int64]bool, len(a.Submission.FileIDs))
+		for _, id := range a.Submission.FileIDs {
+			current[id] = true
+		}
+		for _, f := range a.Submission.Files {
+			if current[f.ID] {
+				task.Files = append(task.Files, PeerReviewFile{ID: f.ID, Name: f.Name, Size: f.Size, ContentType: f.ContentType})
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, errcode.Success
+}
+
+// SubmitPeerReview 学生提交（或修改）互评，互评结束后不能再改
+func SubmitPeerReview(studentID, assignmentID int64, input PeerReviewInput) errcode.ErrCode {
+	// 1. 只能评分给自己的
+	assignment, err := dao.GetPeerAssignmentByID(assignmentID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if assignment == nil || assignment.ReviewerID != studentID {
+		return errcode.DataNotFound
+	}
+	if !assignment.Round.Open(time.Now()) {
+		return errcode.DeadlinePassed
+	}
+
+	// 2. 按作业的评分标准或评分制算分
+	homework, err := dao.GetHomeworkByID(assignment.Round.HomeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	rubric, err := dao.GetRubricByHomeworkID(homework.ID)
+	if err != nil {
+		return errcode.DBError
+	}
+	score, scores, errCode := computeScore(homework, rubric, input.Score, input.Grade, input.CriterionScores)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	var criterionScores models.PeerCriterionScores
+	for _, s := range scores {
+		criterionScores = append(criterionScores, models.PeerCriterionScore{CriterionID: s.CriterionID, Score: s.Score, Comment: s.Comment})
+	}
+
+	// 3. 保存
+	if err := dao.SavePeerReview(assignmentID, *score, criterionScores, strings.TrimSpace(input.Comment), time.Now()); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// GetSubmissionPeerReviews 查询提交收到的互评：管理员随时可看（含评阅人），学生在互评结束后看匿名结果
+func GetSubmissionPeerReviews(userID int64, isAdmin bool, subID int64) (*PeerReviewSummary, errcode.ErrCode) {
+	// 1. 校验权限
+	sub, errCode := getVisibleSubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	round, err := dao.GetPeerReviewRoundByHomework(sub.HomeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if round == nil {
+		return nil, errcode.DataNotFound
+	}
+	if !isAdmin && round.Open(time.Now()) {
+		return nil, errcode.PermissionDenied
+	}
+
+	// 2. 汇总
+	assignments, err := dao.ListPeerAssignmentsBySubmission(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	summary := summarizePeerReviews(subID, assignments, isAdmin)
+	summary.Round = round
+	return &summary, errcode.Success
+}
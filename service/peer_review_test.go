@@ -0,0 +1,42 @@
+package service
+
+import "testing"
+
+func TestTrimmedMean(t *testing.T) {
+	tests := []struct {
+		name    string
+		scores  []int
+		want    float64
+		trimmed int
+		none    bool
+	}{
+		{name: "没有分数", scores: nil, none: true},
+		{name: "1个不去", scores: []int{70}, want: 70},
+		{name: "2个不去", scores: []int{60, 91}, want: 75.5},
+		{name: "3个两端各去1个", scores: []int{100, 0, 80}, want: 80, trimmed: 1},
+		{name: "3个相同", scores: []int{85, 85, 85}, want: 85, trimmed: 1},
+		{name: "5个至少各去1个", scores: []int{10, 90, 80, 85, 100}, want: 85, trimmed: 1},
+		{name: "10个各去20%", scores: []int{0, 0, 70, 72, 74, 76, 78, 80, 100, 100}, want: 75, trimmed: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]int(nil), tt.scores...)
+			got, trimmed := trimmedMean(input)
+			if tt.none {
+				if got != nil || trimmed != 0 {
+					t.Errorf("trimmedMean = %v, %d; want nil, 0", got, trimmed)
+				}
+				return
+			}
+			if got == nil || *got != tt.want || trimmed != tt.trimmed {
+				t.Errorf("trimmedMean = %v, %d; want %v, %d", got, trimmed, tt.want, tt.trimmed)
+			}
+			// 不改调用方的切片
+			for i := range input {
+				if input[i] != tt.scores[i] {
+					t.Fatalf("输入被修改：%v", input)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// 题目入参
+type QuestionInput struct {
+	Department  string
+	Type        string
+	Stem        string
+	Options     []string
+	Answer      string
+	Explanation string
+	Points      int
+}
+
+// 测验题目入参（Points为0时使用题目默认分值）
+type QuizItemInput struct {
+	QuestionID int64
+	Points     int
+}
+
+// 学生作答入参
+type QuizAnswerInput struct {
+	QuestionID int64
+	Answer     string
+}
+
+// 学生看到的测验题目（不含答案）
+type QuizQuestionView struct {
+	QuestionID int64               `json:"question_id"`
+	Type       models.QuestionType `json:"type"`
+	Stem       string              `json:"stem"`
+	Options    []string            `json:"options,omitempty"`
+	Points     int                 `json:"points"`
+}
+
+// 测验提交结果
+type QuizResult struct {
+	SubmissionID int64 `json:"submission_id"`
+	Earned       int   `json:"earned"` // 得分（按题目分值累加）
+	Total        int   `json:"total"`  // 满分
+	Correct      int   `json:"correct"`
+	Questions    int   `json:"questions"`
+	Score        int   `json:"score"` // 换算成作业评分制后的分数
+}
+
+// 单道题的正确率统计
+type QuestionStatView struct {
+	QuestionID  int64               `json:"question_id"`
+	Type        models.QuestionType `json:"type"`
+	Stem        string              `json:"stem"`
+	Attempts    int64               `json:"attempts"`
+	Correct     int64               `json:"correct"`
+	CorrectRate float64             `json:"correct_rate"`
+}
+
+// CreateQuestion 新增题目
+func CreateQuestion(in QuestionInput, creatorID int64) (*models.Question, errcode.ErrCode) {
+	question := &models.Question{CreatorID: creatorID}
+	if errCode := fillQuestion(question, in); errCode != errcode.Success {
+		return nil, errCode
+	}
+	if err := dao.CreateQuestion(question); err != nil {
+		return nil, errcode.DBError
+	}
+	return question, errcode.Success
+}
+
+// UpdateQuestion 修改题目（已被测验引用的题目也可以改，改动对之后的作答生效）
+func UpdateQuestion(questionID int64, in QuestionInput) errcode.ErrCode {
+	question, err := dao.GetQuestionByID(questionID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if question == nil {
+		return errcode.DataNotFound
+	}
+	if errCode := fillQuestion(question, in); errCode != errcode.Success {
+		return errCode
+	}
+	if err := dao.UpdateQuestion(question); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// DeleteQuestion 删除题目（被测验引用时不允许删除）
+func DeleteQuestion(questionID int64) errcode.ErrCode {
+	question, err := dao.GetQuestionByID(questionID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if question == nil {
+		return errcode.DataNotFound
+	}
+	count, err := dao.CountQuizUsage(questionID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if count > 0 {
+		return errcode.ParamError
+	}
+	if err := dao.DeleteQuestion(questionID); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// ListQuestion 分页查询题库
+func ListQuestion(department, questionType string, page, pageSize int) ([]models.Question, int64, errcode.ErrCode) {
+	list, total, err := dao.ListQuestion(department, questionType, page, pageSize)
+	if err != nil {
+		return nil, 0, errcode.DBError
+	}
+	return list, total, errcode.Success
+}
+
+// SaveQuiz 从题库为作业组卷（整体替换），作业随之变为测验类型；ifMatch要和作业当前的版本一致
+func SaveQuiz(homeworkID int64, items []QuizItemInput, shuffle bool, ifMatch int) errcode.ErrCode {
+	// 1. 检查作业，已经有人提交后不能再改卷
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	if ifMatch != homework.Version {
+		return errcode.VersionConflict
+	}
+	submitted, err := dao.CountSubmissionByHomework(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if submitted > 0 {
+		return errcode.ParamError
+	}
+
+	// 2. 题目必须存在、不重复、且属于作业所在部门的题库
+	ids := make([]int64, 0, len(items))
+	seen := make(map[int64]bool, len(items))
+	for _, item := range items {
+		if seen[item.QuestionID] || item.Points < 0 {
+			return errcode.ParamError
+		}
+		seen[item.QuestionID] = true
+		ids = append(ids, item.QuestionID)
+	}
+	questions, err := dao.ListQuestionByIDs(ids)
+	if err != nil {
+		return errcode.DBError
+	}
+	if len(questions) != len(ids) {
+		return errcode.DataNotFound
+	}
+	defaultPoints := make(map[int64]int, len(questions))
+	for _, q := range questions {
+		if q.Department != homework.Department {
+			return errcode.ParamError
+		}
+		defaultPoints[q.ID] = q.Points
+	}
+
+	// 3. 保存
+	quiz := make([]models.QuizQuestion, 0, len(items))
+	for i, item := range items {
+		points := item.Points
+		if points == 0 {
+			points = defaultPoints[item.QuestionID]
+		}
+		quiz = append(quiz, models.QuizQuestion{
+			HomeworkID: homeworkID,
+			QuestionID: item.QuestionID,
+			Position:   i,
+			Points:     points,
+		})
+	}
+	return homeworkUpdateResult(dao.SaveQuiz(homeworkID, ifMatch, quiz, shuffle))
+}
+
+// GetQuiz 学生获取测验题目（不含答案；开启乱序时每个学生的题目顺序不同但固定）
+func GetQuiz(homeworkID, studentID int64) ([]QuizQuestionView, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	if homework.Type != models.HomeworkQuiz {
+		return nil, errcode.ParamError
+	}
+	if errCode := checkUnlocked(studentID, homework); errCode != errcode.Success {
+		return nil, errCode
+	}
+
+	items, err := dao.ListQuizQuestions(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	views := make([]QuizQuestionView, 0, len(items))
+	for _, item := range items {
+		views = append(views, QuizQuestionView{
+			QuestionID: item.QuestionID,
+			Type:       item.Question.Type,
+			Stem:       item.Question.Stem,
+			Options:    item.Question.Options,
+			Points:     item.Points,
+		})
+	}
+	if homework.ShuffleQuestions {
+		// 用作业ID+学生ID做种子，同一个学生刷新页面顺序不变
+		r := rand.New(rand.NewSource(homeworkID*1000003 + studentID))
+		r.Shuffle(len(views), func(i, j int) { views[i], views[j] = views[j], views[i] })
+	}
+	return views, errcode.Success
+}
+
+// SubmitQuiz 学生提交测验，自动判分并写入Submission.Score
+func SubmitQuiz(studentID, homeworkID int64, answers []QuizAnswerInput) (*QuizResult, errcode.ErrCode) {
+	// 1. 通用提交校验（重复提交、解锁、迟交、小组）
+	homework, submission, errCode := newSubmission(studentID, homeworkID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	if homework.Type != models.HomeworkQuiz {
+		return nil, errcode.ParamError
+	}
+
+	// 2. 逐题判分（没作答的题按答错处理）
+	items, err := dao.ListQuizQuestions(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if len(items) == 0 {
+		return nil, errcode.ParamError
+	}
+	given := make(map[int64]string, len(answers))
+	for _, a := range answers {
+		given[a.QuestionID] = a.Answer
+	}
+	result := &QuizResult{Questions: len(items)}
+	quizAnswers := make([]models.QuizAnswer, 0, len(items))
+	for _, item := range items {
+		answer := given[item.QuestionID]
+		correct := checkAnswer(&item.Question, answer)
+		points := 0
+		if correct {
+			points = item.Points
+			result.Correct++
+		}
+		result.Earned += points
+		result.Total += item.Points
+		quizAnswers = append(quizAnswers, models.QuizAnswer{
+			QuestionID: item.QuestionID,
+			Answer:     answer,
+			IsCorrect:  correct,
+			Points:     points,
+		})
+	}
+
+	// 3. 换算成作业评分制的分数，作为已批改保存
+	percent := 0.0
+	if result.Total > 0 {
+		percent = float64(result.Earned) * 100 / float64(result.Total)
+	}
+	result.Score = homework.GradingScale.FromPercentage(percent)
+	now := time.Now()
+	submission.Content = fmt.Sprintf("测验作答：答对%d/%d题", result.Correct, result.Questions)
+	submission.Score = &result.Score
+	submission.Comment = "系统自动判分"
+	submission.ReviewedAt = &now
+
+	if err := dao.CreateQuizSubmission(submission, quizAnswers); err != nil {
+		if dao.IsDuplicateKey(err) {
+			return nil, errcode.AlreadySubmitted
+		}
+		return nil, errcode.DBError
+	}
+	result.SubmissionID = submission.ID
+	return result, errcode.Success
+}
+
+// GetQuizStats 管理员查询测验每道题的正确率
+func GetQuizStats(homeworkID int64) ([]QuestionStatView, errcode.ErrCode) {
+	items, err := dao.ListQuizQuestions(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	stats, err := dao.ListQuestionStats(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	statMap := make(map[int64]dao.QuestionStat, len(stats))
+	for _, s := range stats {
+		statMap[s.QuestionID] = s
+	}
+
+	list := make([]QuestionStatView, 0, len(items))
+	for _, item := range items {
+		s := statMap[item.QuestionID]
+		view := QuestionStatView{
+			QuestionID: item.QuestionID,
+			Type:       item.Question.Type,
+			Stem:       item.Question.Stem,
+			Attempts:   s.Attempts,
+			Correct:    s.Correct,
+		}
+		if s.Attempts > 0 {
+			view.CorrectRate = float64(s.Correct) / float64(s.Attempts)
+		}
+		list = append(list, view)
+	}
+	return list, errcode.Success
+}
+
+// fillQuestion 校验题目并写入字段（答案统一规范化存储）
+func fillQuestion(question *models.Question, in QuestionInput) errcode.ErrCode {
+	if in.Points <= 0 || strings.TrimSpace(in.Stem) == "" {
+		return errcode.ParamError
+	}
+	qType := models.QuestionType(in.Type)
+	answer := strings.TrimSpace(in.Answer)
+	options := in.Options
+
+	switch qType {
+	case models.SingleChoice, models.MultipleChoice:
+		if len(options) < 2 || len(options) > 26 {
+			return errcode.ParamError
+		}
+		letters := normalizeChoice(answer)
+		if len(letters) == 0 || (qType == models.SingleChoice && len(letters) != 1) {
+			return errcode.ParamError
+		}
+		for _, l := range letters {
+			if int(l[0]-'A') >= len(options) {
+				return errcode.ParamError
+			}
+		}
+		answer = strings.Join(letters, ",")
+	case models.TrueFalse:
+		answer = strings.ToLower(answer)
+		if answer != "true" && answer != "false" {
+			return errcode.ParamError
+		}
+		options = nil
+	case models.FillBlank:
+		if answer == "" {
+			return errcode.ParamError
+		}
+		options = nil
+	default:
+		return errcode.ParamError
+	}
+
+	question.Department = models.Department(in.Department)
+	question.Type = qType
+	question.Stem = in.Stem
+	question.Options = options
+	question.Answer = answer
+	question.Explanation = in.Explanation
+	question.Points = in.Points
+	return errcode.Success
+}
+
+// checkAnswer 判断作答是否正确
+func checkAnswer(question *models.Question, answer string) bool {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return false
+	}
+	switch question.Type {
+	case models.SingleChoice, models.MultipleChoice:
+		// 多选题必须完全选对，顺序无关
+		return strings.Join(normalizeChoice(answer), ",") == question.Answer
+	case models.TrueFalse:
+		return strings.EqualFold(answer, question.Answer)
+	case models.FillBlank:
+		// 多个可接受答案用"|"分隔，忽略首尾空格和大小写
+		for _, accepted := range strings.Split(question.Answer, "|") {
+			if strings.EqualFold(strings.TrimSpace(accepted), answer) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// normalizeChoice 把选择题答案（如"c, a"、"AC"）规范成去重排序的大写字母列表
+func normalizeChoice(answer string) []string {
+	seen := make(map[string]bool)
+	letters := make([]string, 0)
+	for _, r := range strings.ToUpper(answer) {
+		if r == ',' || r == ' ' {
+			continue
+		}
+		if r < 'A' || r > 'Z' {
+			return nil
+		}
+		if !seen[string(r)] {
+			seen[string(r)] = true
+			letters = append(letters, string(r))
+		}
+	}
+	sort.Strings(letters)
+	return letters
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/spf13/viper"
+)
+
+// 处理复核申请的入参
+type RegradeResolution struct {
+	Decision        string // keep：维持原分，change：改分
+	Score           *int
+	Grade           string
+	CriterionScores []CriterionScoreInput
+	Explanation     string
+}
+
+// 提交的复核申请和分数变动记录
+type RegradeHistory struct {
+	Requests     []models.RegradeRequest `json:"requests"`
+	ScoreChanges []models.ScoreChange    `json:"score_changes"`
+}
+
+// regradeWindow 批改后可以申请复核的时长
+func regradeWindow() time.Duration {
+	days := viper.GetInt("regrade.window_days")
+	if days <= 0 {
+		days = 7
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// maxRegradeRequests 每份提交最多的复核申请次数（0表示不限，没配置时为1）
+func maxRegradeRequests() int {
+	if !viper.IsSet("regrade.max_requests") {
+		return 1
+	}
+	return viper.GetInt("regrade.max_requests")
+}
+
+// CreateRegradeRequest 学生对已批改的提交申请复核
+func CreateRegradeRequest(studentID, subID int64, reason string) (*models.RegradeRequest, errcode.ErrCode) {
+	// 1. 查询提交记录并校验是自己（或自己小组）的，且已经批改
+	sub, homework, errCode := getOwnSubmission(studentID, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	// 成绩还没发布时学生看不到分数，也不能申请
+	now := time.Now()
+	hideUnreleasedGrade(homework, sub, now)
+	reason = strings.TrimSpace(reason)
+	if sub.Score == nil || reason == "" {
+		return nil, errcode.ParamError
+	}
+
+	// 2. 只能在最近一次批改（成绩发布前批改的从发布时算起）后的期限内申请
+	reviewedAt := sub.SubmittedAt
+	if sub.ReviewedAt != nil {
+		reviewedAt = *sub.ReviewedAt
+	}
+	if homework.GradesReleasedAt != nil && homework.GradesReleasedAt.After(reviewedAt) {
+		reviewedAt = *homework.GradesReleasedAt
+	}
+	if now.After(reviewedAt.Add(regradeWindow())) {
+		return nil, errcode.RegradeWindowClosed
+	}
+
+	// 3. 同时只能有一个待处理的申请，总次数有限
+	list, err := dao.ListRegradeRequestsBySubmission(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	for _, r := range list {
+		if r.Status == models.RegradePending {
+			return nil, errcode.RegradePending
+		}
+	}
+	if limit := maxRegradeRequests(); limit > 0 && len(list) >= limit {
+		return nil, errcode.RegradeLimitReached
+	}
+
+	// 4. 进入作业所属部门的队列
+	request := &models.RegradeRequest{
+		SubmissionID:  subID,
+		HomeworkID:    homework.ID,
+		Department:    homework.Department,
+		StudentID:     studentID,
+		Reason:        reason,
+		Status:        models.RegradePending,
+		OriginalScore: *sub.Score,
+	}
+	if err := dao.CreateRegradeRequest(request); err != nil {
+		return nil, errcode.DBError
+	}
+	return request, errcode.Success
+}
+
+// GetRegradeHistory 查询提交的复核申请和分数变动记录（学生和管理员都能看）
+func GetRegradeHistory(userID int64, isAdmin bool, subID int64) (*RegradeHistory, errcode.ErrCode) {
+	// 1. 校验权限
+	sub, errCode := getVisibleSubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+
+	// 2. 查询申请和分数变动
+	requests, err := dao.ListRegradeRequestsBySubmission(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if isAdmin {
+		for i := range requests {
+			anonymizeRegradeRequest(homework, &requests[i])
+		}
+	}
+	changes, err := dao.ListScoreChanges(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	// 3. 成绩发布前学生看不到分数变动，其他组员的个人分数调整也看不到；匿名批改时不给管理员看调整的是谁
+	if !isAdmin && !homework.GradesVisible(time.Now()) {
+		changes = []models.ScoreChange{}
+	}
+	visible := make([]models.ScoreChange, 0, len(changes))
+	for _, change := range changes {
+		if change.StudentID != nil {
+			if !isAdmin && *change.StudentID != userID {
+				continue
+			}
+			if isAdmin && blindActive(homework) {
+				change.StudentID = nil
+			}
+		}
+		visible = append(visible, change)
+	}
+	changes = visible
+	return &RegradeHistory{Requests: requests, ScoreChanges: changes}, errcode.Success
+}
+
+// ListRegradeQueue 管理员查询部门的复核申请（不指定部门时看自己所在部门）
+func ListRegradeQueue(adminID int64, department, status string, page, pageSize int) ([]models.RegradeRequest, int64, errcode.ErrCode) {
+	// 1. 校验筛选条件
+	switch models.RegradeStatus(status) {
+	case "", models.RegradePending, models.RegradeKept, models.RegradeChanged:
+	default:
+		return nil, 0, errcode.ParamError
+	}
+	if page <= 0 || pageSize <= 0 || pageSize > 100 {
+		return nil, 0, errcode.ParamError
+	}
+	if department == "" {
+		admin, err := dao.GetUserByID(adminID)
+		if err != nil {
+			return nil, 0, errcode.DBError
+		}
+		if admin == nil {
+			return nil, 0, errcode.AuthError
+		}
+		department = string(admin.Department)
+	}
+
+	// 2. 查询（匿名批改中的作业隐藏申请人身份）
+	list, total, err := dao.ListRegradeQueue(department, status, page, pageSize)
+	if err != nil {
+		return nil, 0, errcode.DBError
+	}
+	for i := range list {
+		if list[i].Submission != nil {
+			anonymizeRegradeRequest(&list[i].Submission.Homework, &list[i])
+		}
+	}
+	return list, total, errcode.Success
+}
+
+// anonymizeRegradeRequest 匿名批改时隐藏复核申请人和提交的身份
+func anonymizeRegradeRequest(homework *models.Homework, request *models.RegradeRequest) {
+	if !blindActive(homework) {
+		return
+	}
+	request.StudentID = 0
+	anonymizeUser(homework.ID, &request.Student)
+	if request.Submission != nil {
+		anonymizeSubmission(homework, request.Submission)
+	}
+}
+
+// ResolveRegradeRequest 管理员处理复核申请：维持原分或改分，都要写明理由
+func ResolveRegradeRequest(adminID, requestID int64, resolution RegradeResolution) errcode.ErrCode {
+	// 1. 查询申请，只能处理待处理的
+	request, err := dao.GetRegradeRequestByID(requestID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if request == nil {
+		return errcode.DataNotFound
+	}
+	explanation := strings.TrimSpace(resolution.Explanation)
+	if request.Status != models.RegradePending || explanation == "" {
+		return errcode.ParamError
+	}
+
+	now := time.Now()
+	request.ResolverID = &adminID
+	request.Explanation = explanation
+	request.ResolvedAt = &now
+
+	// 2. 按处理结果准备要保存的数据
+	var sub *models.Submission
+	var scores []models.SubmissionCriterionScore
+	var change *models.ScoreChange
+	switch resolution.Decision {
+	case "keep":
+		// 维持原分，只记录处理结果
+		request.Status = models.RegradeKept
+	case "change":
+		// 改分：和批改一样按评分标准或评分制算分
+		sub, err = dao.GetSubmissionByID(request.SubmissionID)
+		if err != nil {
+			return errcode.DBError
+		}
+		if sub == nil {
+			return errcode.DataNotFound
+		}
+		homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+		if err != nil {
+			return errcode.DBError
+		}
+		if homework == nil {
+			return errcode.DataNotFound
+		}
+		rubric, err := dao.GetRubricByHomeworkID(sub.HomeworkID)
+		if err != nil {
+			return errcode.DBError
+		}
+		var score *int
+		var errCode errcode.ErrCode
+		score, scores, errCode = computeScore(homework, rubric, resolution.Score, resolution.Grade, resolution.CriterionScores)
+		if errCode != errcode.Success {
+			return errCode
+		}
+		// 分数没变应该选维持原分
+		change = scoreChange(sub.Score, *score, models.ScoreSourceRegrade, adminID, explanation)
+		if change == nil {
+			return errcode.ParamError
+		}
+		change.RegradeRequestID = &request.ID
+		sub.Score = score
+		request.Status = models.RegradeChanged
+		request.NewScore = score
+	default:
+		return errcode.ParamError
+	}
+
+	// 3. 保存（并发处理时只有一个能成功）
+	resolved, err := dao.ResolveRegradeRequest(request, sub, scores, change)
+	if err != nil {
+		// 读取之后提交被别人改过分
+		if errors.Is(err, dao.ErrVersionConflict) {
+			return errcode.VersionConflict
+		}
+		return errcode.DBError
+	}
+	if !resolved {
+		return errcode.ParamError
+	}
+	return errcode.Success
+}
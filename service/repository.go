@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/gitrepo"
+	"github.com/chuji555/homework-system/pkg/storage"
+	"github.com/spf13/viper"
+)
+
+// 提交的仓库快照（Current表示是当前版本的）
+type RepositorySnapshotView struct {
+	models.RepositorySnapshot
+	Current bool `json:"current"`
+}
+
+// 快照里一个文件的内容（不是UTF-8文本时只返回大小）
+type RepositoryFile struct {
+	SnapshotID int64  `json:"snapshot_id"`
+	CommitSHA  string `json:"commit_sha"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	Binary     bool   `json:"binary"`
+	Content    string `json:"content,omitempty"`
+}
+
+// gitOptions 按配置生成克隆参数
+func gitOptions() gitrepo.Options {
+	timeout := viper.GetInt("git.timeout_seconds")
+	if timeout <= 0 {
+		timeout = 120
+	}
+	maxSize := viper.GetInt64("git.max_size_mb")
+	if maxSize <= 0 {
+		maxSize = 50
+	}
+	return gitrepo.Options{
+		GitBinary:  viper.GetString("git.binary"),
+		WorkDir:    viper.GetString("git.work_dir"),
+		Timeout:    time.Duration(timeout) * time.Second,
+		MaxSize:    maxSize << 20,
+		AllowLocal: viper.GetBool("git.allow_local"),
+	}
+}
+
+// maxRepositoryFileView 在线查看单个文件的大小上限
+func maxRepositoryFileView() int64 {
+	kb := viper.GetInt64("git.file_view_max_kb")
+	if kb <= 0 {
+		kb = 512
+	}
+	return kb << 10
+}
+
+// SubmitRepository 提交仓库作业：克隆仓库、固定到ref当前指向的提交，快照作为附件走普通的提交流程
+// （撤回过的按重新提交处理）
+func SubmitRepository(studentID, homeworkID int64, repoURL, ref, note string) errcode.ErrCode {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	if homework.Type != models.HomeworkGit {
+		return errcode.ParamError
+	}
+	snapshot, errCode := snapshotRepository(studentID, homeworkID, repoURL, ref)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	return createSubmission(studentID, homeworkID, models.HomeworkGit,
+		repositoryContent(snapshot, note), repoURL, []int64{snapshot.FileID})
+}
+
+// ResubmitRepository 重新提交仓库作业（重新克隆，生成新版本）
+func ResubmitRepository(studentID, subID int64, repoURL, ref, note string) errcode.ErrCode {
+	// 克隆比较慢，先把明显不能重新提交的情况挡掉，其余校验和普通重新提交一样
+	sub, homework, errCode := getOwnSubmission(studentID, subID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	if homework.Type != models.HomeworkGit || sub.Score != nil {
+		return errcode.ParamError
+	}
+	if homework.MaxAttempts > 0 && sub.Attempts >= homework.MaxAttempts {
+		return errcode.AttemptsUsedUp
+	}
+	snapshot, errCode := snapshotRepository(studentID, sub.HomeworkID, repoURL, ref)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	return resubmitSubmission(studentID, subID, models.HomeworkGit,
+		repositoryContent(snapshot, note), repoURL, []int64{snapshot.FileID})
+}
+
+// repositoryContent 提交内容：学生的说明，没写时用仓库和提交的摘要
+func repositoryContent(snapshot *models.RepositorySnapshot, note string) string {
+	if note != "" {
+		return note
+	}
+	return fmt.Sprintf("%s@%s（%s）", snapshot.Ref, snapshot.CommitSHA, snapshot.CommittedAt.Format(time.RFC3339))
+}
+
+// snapshotRepository 克隆仓库并打包写入存储，保存为学生上传的文件和快照记录（还没关联到提交）
+func snapshotRepository(studentID, homeworkID int64, repoURL, ref string) (*models.RepositorySnapshot, errcode.ErrCode) {
+	// 1. 克隆并打包
+	opts := gitOptions()
+	if gitrepo.ValidateURL(repoURL, opts.AllowLocal) != nil || gitrepo.ValidateRef(ref) != nil {
+		return nil, errcode.RepositoryInvalid
+	}
+	snap, err := gitrepo.Take(context.Background(), opts, repoURL, ref)
+	if err != nil {
+		if errors.Is(err, gitrepo.ErrTooLarge) {
+			return nil, errcode.FileTooLarge
+		}
+		// 克隆失败、找不到ref、超时是学生填的仓库有问题，其他的记日志
+		if !errors.Is(err, gitrepo.ErrCloneFailed) && !errors.Is(err, gitrepo.ErrRefNotFound) &&
+			!errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("仓库快照失败：%v", err)
+		}
+		return nil, errcode.RepositoryFailed
+	}
+	defer snap.Close()
+
+	// 2. 边写入存储边计算SHA-256
+	store, err := getStorage()
+	if err != nil {
+		return nil, errcode.StorageError
+	}
+	key, err := newStorageKey(homeworkID, studentID)
+	if err != nil {
+		return nil, errcode.StorageError
+	}
+	f, err := os.Open(snap.Archive)
+	if err != nil {
+		return nil, errcode.StorageError
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if err := store.Put(context.Background(), key, io.TeeReader(f, hash), snap.ArchiveSize, "application/x-tar"); err != nil {
+		log.Printf("保存仓库快照失败：%v", err)
+		return nil, errcode.StorageError
+	}
+
+	// 3. 保存文件和快照信息，失败时删掉已写入的文件
+	file := &models.StoredFile{
+		UploaderID:  studentID,
+		HomeworkID:  homeworkID,
+		Name:        fmt.Sprintf("%s.tar", snap.CommitSHA),
+		Size:        snap.ArchiveSize,
+		ContentType: "application/x-tar",
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}
+	tree := make(models.RepoTree, 0, len(snap.Tree))
+	for _, e := range snap.Tree {
+		tree = append(tree, models.RepoTreeEntry{Path: e.Path, Mode: e.Mode, Size: e.Size})
+	}
+	snapshot := &models.RepositorySnapshot{
+		HomeworkID:  homeworkID,
+		StudentID:   studentID,
+		RepoURL:     repoURL,
+		Ref:         ref,
+		CommitSHA:   snap.CommitSHA,
+		CommittedAt: snap.CommittedAt,
+		FileCount:   len(tree),
+		Tree:        tree,
+	}
+	if err := dao.CreateRepositorySnapshot(file, snapshot); err != nil {
+		_ = store.Delete(context.Background(), key)
+		return nil, errcode.DBError
+	}
+	return snapshot, errcode.Success
+}
+
+// ListRepositorySnapshots 查询提交的仓库快照（管理员或提交者本人/组员；匿名批改时对管理员隐藏仓库地址）
+func ListRepositorySnapshots(userID int64, isAdmin bool, subID int64) ([]RepositorySnapshotView, errcode.ErrCode) {
+	sub, homework, errCode := visibleRepositorySubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	list, err := dao.ListRepositorySnapshotsBySubmission(sub.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	hideURL := isAdmin && blindActive(homework)
+	views := make([]RepositorySnapshotView, 0, len(list))
+	for _, s := range list {
+		if hideURL {
+			s.RepoURL = ""
+			s.StudentID = 0
+		}
+		views = append(views, RepositorySnapshotView{RepositorySnapshot: s, Current: slices.Contains(sub.FileIDs, s.FileID)})
+	}
+	return views, errcode.Success
+}
+
+// GetRepositoryFile 读取快照里的一个文件（snapshotID为0时读当前版本的快照）
+func GetRepositoryFile(userID int64, isAdmin bool, subID, snapshotID int64, path string) (*RepositoryFile, errcode.ErrCode) {
+	// 1. 找到快照
+	sub, _, errCode := visibleRepositorySubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	list, err := dao.ListRepositorySnapshotsBySubmission(sub.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	var snapshot *models.RepositorySnapshot
+	for i := range list {
+		if (snapshotID == 0 && slices.Contains(sub.FileIDs, list[i].FileID)) || list[i].ID == snapshotID {
+			snapshot = &list[i]
+			break
+		}
+	}
+	if snapshot == nil {
+		return nil, errcode.DataNotFound
+	}
+
+	// 2. 文件要在快照的文件列表里
+	var entry *models.RepoTreeEntry
+	for i := range snapshot.Tree {
+		if snapshot.Tree[i].Path == path {
+			entry = &snapshot.Tree[i]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, errcode.DataNotFound
+	}
+	result := &RepositoryFile{SnapshotID: snapshot.ID, CommitSHA: snapshot.CommitSHA, Path: path, Size: entry.Size}
+	if entry.Size > maxRepositoryFileView() {
+		return nil, errcode.FileTooLarge
+	}
+
+	// 3. 从存储里的tar读出文件
+	file, err := dao.GetStoredFileByID(snapshot.FileID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if file == nil {
+		return nil, errcode.DataNotFound
+	}
+	store, err := getStorage()
+	if err != nil {
+		return nil, errcode.StorageError
+	}
+	reader, err := store.Get(context.Background(), file.StorageKey)
+	if err == storage.ErrNotFound {
+		return nil, errcode.DataNotFound
+	}
+	if err != nil {
+		log.Printf("读取仓库快照失败：%v", err)
+		return nil, errcode.StorageError
+	}
+	defer reader.Close()
+	data, err := gitrepo.ReadFile(reader, path, maxRepositoryFileView())
+	switch {
+	case errors.Is(err, gitrepo.ErrFileNotFound):
+		// 符号链接、子模块不在tar里按普通文件存
+		return nil, errcode.DataNotFound
+	case errors.Is(err, gitrepo.ErrTooLarge):
+		return nil, errcode.FileTooLarge
+	case err != nil:
+		log.Printf("读取仓库快照失败：%v", err)
+		return nil, errcode.StorageError
+	}
+	if utf8.Valid(data) {
+		result.Content = string(data)
+	} else {
+		result.Binary = true
+	}
+	return result, errcode.Success
+}
+
+// visibleRepositorySubmission 查询能看的仓库作业提交
+func visibleRepositorySubmission(userID int64, isAdmin bool, subID int64) (*models.Submission, *models.Homework, errcode.ErrCode) {
+	sub, errCode := getVisibleSubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		return nil, nil, errCode
+	}
+	homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, nil, errcode.DataNotFound
+	}
+	if homework.Type != models.HomeworkGit {
+		return nil, nil, errcode.ParamError
+	}
+	return sub, homework, errcode.Success
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/spf13/viper"
+)
+
+// 批改人的分配方式
+const (
+	AssignRoundRobin = "round_robin" // 按顺序轮流分
+	AssignLoad       = "load"        // 分给手上没批改的最少的人
+	AssignMentor     = "mentor"      // 分给学生的导师
+)
+
+// 批改队列里的下一份提交
+type NextReview struct {
+	Submission *models.Submission `json:"submission"`
+	ExpiresAt  *time.Time         `json:"expires_at,omitempty"` // 认领到期时间，分配的为空
+	Remaining  int                `json:"remaining"`            // 除这份外还能批改的份数（自己名下的加上没人认领的）
+}
+
+// claimDuration 从队列领取的认领多久后自动释放
+func claimDuration() time.Duration {
+	minutes := viper.GetInt("review.claim_minutes")
+	if minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// checkReviewClaim 校验批改人能不能批改这份提交：别人持有有效认领或者别人已经批改过的不行
+func checkReviewClaim(sub *models.Submission, reviewerID int64) errcode.ErrCode {
+	if sub.ReviewerID != nil && *sub.ReviewerID != reviewerID {
+		return errcode.ReviewClaimed
+	}
+	claim, err := dao.GetReviewClaim(sub.ID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if claim != nil && claim.ReviewerID != reviewerID && claim.Active(time.Now()) {
+		return errcode.ReviewClaimed
+	}
+	return errcode.Success
+}
+
+// checkAdmins 校验用户都是管理员
+func checkAdmins(userIDs []int64) errcode.ErrCode {
+	users, err := dao.ListUsersByIDs(userIDs)
+	if err != nil {
+		return errcode.DBError
+	}
+	if len(users) != len(userIDs) {
+		return errcode.ParamError
+	}
+	for _, u := range users {
+		if u.Role != models.Admin {
+			return errcode.ParamError
+		}
+	}
+	return errcode.Success
+}
+
+// AssignReviewers 把作业里还没人负责的提交自动分给批改人（分配的不过期），返回分出去的份数
+func AssignReviewers(homeworkID int64, strategy string, reviewerIDs []int64) (int, errcode.ErrCode) {
+	// 1. 校验作业和批改人
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return 0, errcode.DBError
+	}
+	if homework == nil {
+		return 0, errcode.DataNotFound
+	}
+	reviewerIDs = uniqueIDs(reviewerIDs)
+	switch strategy {
+	case AssignRoundRobin, AssignLoad:
+		if len(reviewerIDs) == 0 {
+			return 0, errcode.ParamError
+		}
+	case AssignMentor:
+	default:
+		return 0, errcode.ParamError
+	}
+	if len(reviewerIDs) > 0 {
+		if errCode := checkAdmins(reviewerIDs); errCode != errcode.Success {
+			return 0, errCode
+		}
+	}
+
+	// 2. 查询待分配的提交
+	now := time.Now()
+	subs, err := dao.ListUnclaimedSubmissions(homeworkID, now)
+	if err != nil {
+		return 0, errcode.DBError
+	}
+	if len(subs) == 0 {
+		return 0, errcode.Success
+	}
+
+	// 3. 按分配方式选批改人
+	claims := make([]models.ReviewClaim, 0, len(subs))
+	assign := func(sub *models.Submission, reviewerID int64) {
+		claims = append(claims, models.ReviewClaim{SubmissionID: sub.ID, HomeworkID: homeworkID, ReviewerID: reviewerID})
+	}
+	switch strategy {
+	case AssignRoundRobin:
+		for i := range subs {
+			assign(&subs[i], reviewerIDs[i%len(reviewerIDs)])
+		}
+	case AssignLoad:
+		load, err := dao.CountOpenClaims(reviewerIDs, now)
+		if err != nil {
+			return 0, errcode.DBError
+		}
+		for i := range subs {
+			// 负载一样时按传入的顺序
+			best := reviewerIDs[0]
+			for _, id := range reviewerIDs[1:] {
+				if load[id] < load[best] {
+					best = id
+				}
+			}
+			assign(&subs[i], best)
+			load[best]++
+		}
+	case AssignMentor:
+		studentIDs := make([]int64, 0, len(subs))
+		for _, sub := range subs {
+			studentIDs = append(studentIDs, sub.StudentID)
+		}
+		mentors, err := dao.MapMentors(studentIDs)
+		if err != nil {
+			return 0, errcode.DBError
+		}
+		allowed := make(map[int64]bool, len(reviewerIDs))
+		for _, id := range reviewerIDs {
+			allowed[id] = true
+		}
+		// 没有导师（或导师不在指定的批改人里）的提交留在队列里
+		for i := range subs {
+			mentorID, ok := mentors[subs[i].StudentID]
+			if !ok || (len(allowed) > 0 && !allowed[mentorID]) {
+				continue
+			}
+			assign(&subs[i], mentorID)
+		}
+	}
+
+	// 4. 保存
+	if err := dao.AssignReviewClaims(claims); err != nil {
+		return 0, errcode.DBError
+	}
+	return len(claims), errcode.Success
+}
+
+// ClaimSubmission 批改人从队列认领一份提交，到期自动释放；force为true时接手别人的认领
+func ClaimSubmission(reviewerID, subID int64, force bool) (*models.ReviewClaim, errcode.ErrCode) {
+	// 1. 只有没撤回、还没批改的提交可以认领
+	sub, err := dao.GetSubmissionByID(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if sub == nil {
+		return nil, errcode.DataNotFound
+	}
+	if sub.Withdrawn || sub.Score != nil {
+		return nil, errcode.ParamError
+	}
+
+	// 2. 认领
+	expiresAt := time.Now().Add(claimDuration())
+	claimed, err := dao.ClaimSubmission(sub, reviewerID, &expiresAt, force)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if !claimed {
+		return nil, errcode.ReviewClaimed
+	}
+	claim, err := dao.GetReviewClaim(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if claim == nil {
+		return nil, errcode.DataNotFound
+	}
+	return claim, errcode.Success
+}
+
+// ReleaseClaim 批改人放弃自己的认领，提交回到队列
+func ReleaseClaim(reviewerID, subID int64) errcode.ErrCode {
+	released, err := dao.ReleaseReviewClaim(subID, reviewerID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if !released {
+		return errcode.DataNotFound
+	}
+	return errcode.Success
+}
+
+// NextUnreviewed 批改人的下一份提交：先给自己名下的，没有了再从队列里领一份（队列也空了返回nil）
+func NextUnreviewed(reviewerID, homeworkID int64) (*NextReview, errcode.ErrCode) {
+	// 1. 查询作业
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(claimDuration())
+	mine, err := dao.ListClaimedSubmissions(reviewerID, homeworkID, now)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	unclaimed, err := dao.ListUnclaimedSubmissions(homeworkID, now)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+
+	// 2. 自己名下的和队列里的依次尝试认领（自己的会续期，队列里的可能刚被别人领走）
+	candidates := append(mine, unclaimed...)
+	for i := range candidates {
+		sub := &candidates[i]
+		claimed, err := dao.ClaimSubmission(sub, reviewerID, &expiresAt, false)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+		if !claimed {
+			continue
+		}
+		claim, err := dao.GetReviewClaim(sub.ID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+
+		// 3. 取完整的提交返回
+		full, err := dao.GetSubmissionByID(sub.ID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+		if full == nil {
+			continue
+		}
+		full.FillScoreDisplay(homework.GradingScale)
+		anonymizeSubmission(homework, full)
+		next := &NextReview{Submission: full, Remaining: len(candidates) - i - 1}
+		if claim != nil {
+			next.ExpiresAt = claim.ExpiresAt
+		}
+		return next, errcode.Success
+	}
+	return nil, errcode.Success
+}
+
+// ListMyClaims 批改人名下还没批改的提交（homeworkID为0时查所有作业）
+func ListMyClaims(reviewerID, homeworkID int64) ([]models.Submission, errcode.ErrCode) {
+	list, err := dao.ListClaimedSubmissions(reviewerID, homeworkID, time.Now())
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	for i := range list {
+		// 匿名批改时隐藏学生身份
+		anonymizeSubmission(&list[i].Homework, &list[i])
+	}
+	return list, errcode.Success
+}
+
+// SetMentor 设置导师负责的学生
+func SetMentor(mentorID int64, studentIDs []int64) errcode.ErrCode {
+	studentIDs = uniqueIDs(studentIDs)
+	if len(studentIDs) == 0 {
+		return errcode.ParamError
+	}
+	if errCode := checkAdmins([]int64{mentorID}); errCode != errcode.Success {
+		return errCode
+	}
+	students, err := dao.ListUsersByIDs(studentIDs)
+	if err != nil {
+		return errcode.DBError
+	}
+	if len(students) != len(studentIDs) {
+		return errcode.ParamError
+	}
+	for _, s := range students {
+		if s.Role != models.Student {
+			return errcode.ParamError
+		}
+	}
+	if err := dao.SetMentor(mentorID, studentIDs); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// ListMentorships 查询导师关系（mentorID为0时查全部）
+func ListMentorships(mentorID int64) ([]models.Mentorship, errcode.ErrCode) {
+	list, err := dao.ListMentorships(mentorID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	return list, errcode.Success
+}
+
+// uniqueIDs 去重（保持原来的顺序）
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	result := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
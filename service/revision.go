@@ -0,0 +1,212 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// 两个版本的对比结果
+type RevisionDiff struct {
+	From            int                 `json:"from"`
+	To              int                 `json:"to"`
+	Changes         models.FieldChanges `json:"changes"`          // 有变化的字段
+	DescriptionDiff []DiffLine          `json:"description_diff"` // 作业要求的逐行对比（没变化时为空）
+}
+
+// 逐行对比中的一行
+type DiffLine struct {
+	Op   string `json:"op"` // "+"新增，"-"删除，" "未变
+	Text string `json:"text"`
+}
+
+// ListHomeworkRevisions 查询作业的修改记录
+func ListHomeworkRevisions(homeworkID int64) ([]models.HomeworkRevision, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	list, err := dao.ListHomeworkRevisions(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	return list, errcode.Success
+}
+
+// DiffHomeworkRevisions 对比作业的两个版本
+func DiffHomeworkRevisions(homeworkID int64, from, to int) (*RevisionDiff, errcode.ErrCode) {
+	// 1. 查询两个版本
+	fromRev, err := dao.GetHomeworkRevision(homeworkID, from)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	toRev, err := dao.GetHomeworkRevision(homeworkID, to)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if fromRev == nil || toRev == nil {
+		return nil, errcode.DataNotFound
+	}
+
+	// 2. 逐字段对比，作业要求额外给出逐行对比
+	diff := &RevisionDiff{
+		From:    from,
+		To:      to,
+		Changes: models.DiffSnapshots(fromRev.Snapshot, toRev.Snapshot),
+	}
+	if fromRev.Snapshot.Description != toRev.Snapshot.Description {
+		lines, ok := diffLines(fromRev.Snapshot.Description, toRev.Snapshot.Description)
+		if !ok {
+			return nil, errcode.ParamError
+		}
+		diff.DescriptionDiff = lines
+	}
+	return diff, errcode.Success
+}
+
+// maxDiffCells 逐行对比时动态规划表的格数上限（去掉相同的开头和结尾后两边行数相乘），防止超长内容占满内存
+const maxDiffCells = 4 << 20
+
+// diffLines 基于最长公共子序列的逐行对比，内容太长对比不了时返回false
+func diffLines(oldText, newText string) ([]DiffLine, bool) {
+	a := strings.Split(oldText, "\n")
+	b := strings.Split(newText, "\n")
+
+	// 1. 相同的开头和结尾不参与计算
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) > maxDiffCells {
+		return nil, false
+	}
+
+	// 2. lcs[i][j]：midA[i:]和midB[j:]的最长公共子序列长度
+	lcs := make([][]int32, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// 3. 按表回溯出逐行结果
+	lines := make([]DiffLine, 0, len(a)+len(midB))
+	for _, text := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: " ", Text: text})
+	}
+	i, j := 0, 0
+	for i < len(midA) && j < len(midB) {
+		switch {
+		case midA[i] == midB[j]:
+			lines = append(lines, DiffLine{Op: " ", Text: midA[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: "-", Text: midA[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: "+", Text: midB[j]})
+			j++
+		}
+	}
+	for ; i < len(midA); i++ {
+		lines = append(lines, DiffLine{Op: "-", Text: midA[i]})
+	}
+	for ; j < len(midB); j++ {
+		lines = append(lines, DiffLine{Op: "+", Text: midB[j]})
+	}
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: " ", Text: text})
+	}
+	return lines, true
+}
+
+// fillHomeworkChanges 学生查看作业详情时，带上上次查看（或提交）之后截止时间、要求的改动，并记录本次查看
+func fillHomeworkChanges(studentID int64, homework *models.Homework) error {
+	// 1. 上次看到作业的时间：查看时间和提交时间取较晚的
+	seen, err := lastSeenHomework(studentID, []int64{homework.ID})
+	if err != nil {
+		return err
+	}
+
+	// 2. 合并这之后的改动：同一字段改了多次时取最早的旧值和最新的新值
+	if since, ok := seen[homework.ID]; ok {
+		revisions, err := dao.ListNotableRevisionsSince(homework.ID, since)
+		if err != nil {
+			return err
+		}
+		index := make(map[string]int)
+		for _, revision := range revisions {
+			for _, change := range revision.Changes.Notable() {
+				if i, ok := index[change.Field]; ok {
+					homework.Changes[i].New = change.New
+					continue
+				}
+				index[change.Field] = len(homework.Changes)
+				homework.Changes = append(homework.Changes, change)
+			}
+		}
+	}
+
+	// 3. 记录本次查看
+	return dao.SaveHomeworkView(homework.ID, studentID, time.Now())
+}
+
+// markUpdatedHomework 作业列表中标记学生看过之后又有改动的作业
+func markUpdatedHomework(studentID int64, list []models.Homework) error {
+	ids := make([]int64, 0, len(list))
+	for _, h := range list {
+		ids = append(ids, h.ID)
+	}
+	seen, err := lastSeenHomework(studentID, ids)
+	if err != nil {
+		return err
+	}
+	latest, err := dao.MapLatestNotableRevisionTime(ids)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		since, ok := seen[list[i].ID]
+		changedAt, changed := latest[list[i].ID]
+		list[i].Updated = ok && changed && changedAt.After(since)
+	}
+	return nil
+}
+
+// lastSeenHomework 学生最后一次看到作业内容的时间（查看详情或提交，取较晚的）；从没看过的作业不在结果里
+func lastSeenHomework(studentID int64, homeworkIDs []int64) (map[int64]time.Time, error) {
+	seen, err := dao.MapHomeworkViews(studentID, homeworkIDs)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := dao.ListSubmissionByStudentAndHomeworkIDs(studentID, homeworkIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		if viewed, ok := seen[sub.HomeworkID]; !ok || sub.SubmittedAt.After(viewed) {
+			seen[sub.HomeworkID] = sub.SubmittedAt
+		}
+	}
+	return seen, nil
+}
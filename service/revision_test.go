@@ -0,0 +1,59 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string // 每行为Op+Text
+	}{
+		{"相同", "a\nb", "a\nb", " a| b"},
+		{"中间改一行", "a\nb\nc", "a\nx\nc", " a|-b|+x| c"},
+		{"开头新增", "b\nc", "a\nb\nc", "+a| b| c"},
+		{"结尾删除", "a\nb\nc", "a\nb", " a| b|-c"},
+		{"整体替换", "a\nb", "c\nd", "-a|-b|+c|+d"},
+		{"重复行", "a\na\nb", "a\nb\nb", " a|-a|+b| b"},
+		{"空到有", "", "a", "-|+a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, ok := diffLines(tt.old, tt.new)
+			if !ok {
+				t.Fatal("diffLines返回false")
+			}
+			got := make([]string, len(lines))
+			for i, l := range lines {
+				got[i] = l.Op + l.Text
+			}
+			if want := strings.Split(tt.want, "|"); !reflect.DeepEqual(got, want) {
+				t.Errorf("diffLines = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	// 两边各3000行且完全不同，超过上限
+	var a, b []string
+	for i := 0; i < 3000; i++ {
+		a = append(a, "old "+strings.Repeat("x", i%7))
+		b = append(b, "new "+strings.Repeat("y", i%7))
+	}
+	if _, ok := diffLines(strings.Join(a, "\n"), strings.Join(b, "\n")); ok {
+		t.Error("超长内容应该返回false")
+	}
+
+	// 行数很多但只改了一行，去掉相同的开头结尾后可以对比
+	c := append([]string(nil), a...)
+	c[1500] = "changed"
+	lines, ok := diffLines(strings.Join(a, "\n"), strings.Join(c, "\n"))
+	if !ok || len(lines) != 3001 {
+		t.Errorf("diffLines ok=%v, %d行，want true, 3001", ok, len(lines))
+	}
+}
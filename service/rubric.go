@@ -0,0 +1,159 @@
+package service
+
+import (
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// 评分项入参（handler层转换后传入）
+type CriterionInput struct {
+	Name        string
+	Description string
+	MaxPoints   int
+	Levels      []LevelInput
+}
+
+// 等级描述入参
+type LevelInput struct {
+	Points      int
+	Description string
+}
+
+// 分项得分入参
+type CriterionScoreInput struct {
+	CriterionID int64
+	Score       int
+	Comment     string
+}
+
+// SaveRubric 设置作业的评分标准（整体替换），ifMatch要和作业当前的版本一致
+func SaveRubric(homeworkID int64, criteria []CriterionInput, ifMatch int) errcode.ErrCode {
+	// 1. 检查作业是否存在
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	if ifMatch != homework.Version {
+		return errcode.VersionConflict
+	}
+
+	// 2. 已经有人按旧标准批改过，就不允许再改（否则分项得分对不上）
+	count, err := dao.CountCriterionScoresByHomework(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if count > 0 {
+		return errcode.ParamError
+	}
+
+	// 3. 只有数值型评分制才能配评分标准
+	if !homework.GradingScale.IsNumeric() {
+		return errcode.ParamError
+	}
+
+	// 4. 校验评分项：满分之和不能超过评分制的满分（总分存在Submission.Score里）
+	total := 0
+	rubric := &models.Rubric{HomeworkID: homeworkID}
+	for i, c := range criteria {
+		if c.MaxPoints <= 0 {
+			return errcode.ParamError
+		}
+		total += c.MaxPoints
+		criterion := models.RubricCriterion{
+			Name:        c.Name,
+			Description: c.Description,
+			MaxPoints:   c.MaxPoints,
+			SortOrder:   i,
+		}
+		for _, l := range c.Levels {
+			// 等级分值必须落在 0~该项满分 之间
+			if l.Points < 0 || l.Points > c.MaxPoints {
+				return errcode.ParamError
+			}
+			criterion.Levels = append(criterion.Levels, models.RubricLevel{
+				Points:      l.Points,
+				Description: l.Description,
+			})
+		}
+		rubric.Criteria = append(rubric.Criteria, criterion)
+	}
+	if total > homework.GradingScale.MaxScore() {
+		return errcode.ParamError
+	}
+
+	// 5. 保存
+	return homeworkUpdateResult(dao.SaveRubric(rubric, ifMatch))
+}
+
+// GetRubric 查询作业的评分标准
+func GetRubric(homeworkID int64) (*models.Rubric, errcode.ErrCode) {
+	rubric, err := dao.GetRubricByHomeworkID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if rubric == nil {
+		return nil, errcode.DataNotFound
+	}
+	return rubric, errcode.Success
+}
+
+// DeleteRubric 删除作业的评分标准（已有分项得分时不允许删除）
+func DeleteRubric(homeworkID int64) errcode.ErrCode {
+	count, err := dao.CountCriterionScoresByHomework(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if count > 0 {
+		return errcode.ParamError
+	}
+	if err := dao.DeleteRubric(homeworkID); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// rubricMaxPoints 评分标准的满分之和
+func rubricMaxPoints(rubric *models.Rubric) int {
+	total := 0
+	for _, c := range rubric.Criteria {
+		total += c.MaxPoints
+	}
+	return total
+}
+
+// scoreByRubric 按评分标准校验分项得分并计算总分
+func scoreByRubric(rubric *models.Rubric, inputs []CriterionScoreInput) (int, []models.SubmissionCriterionScore, errcode.ErrCode) {
+	// 每个评分项都必须打分，且只能打一次
+	if len(inputs) != len(rubric.Criteria) {
+		return 0, nil, errcode.ParamError
+	}
+	maxPoints := make(map[int64]int, len(rubric.Criteria))
+	for _, c := range rubric.Criteria {
+		maxPoints[c.ID] = c.MaxPoints
+	}
+
+	total := 0
+	scores := make([]models.SubmissionCriterionScore, 0, len(inputs))
+	for _, in := range inputs {
+		max, ok := maxPoints[in.CriterionID]
+		if !ok {
+			// 不属于该作业的评分项，或者重复打分
+			return 0, nil, errcode.ParamError
+		}
+		if in.Score < 0 || in.Score > max {
+			return 0, nil, errcode.ParamError
+		}
+		delete(maxPoints, in.CriterionID)
+		total += in.Score
+		scores = append(scores, models.SubmissionCriterionScore{
+			CriterionID: in.CriterionID,
+			Score:       in.Score,
+			Comment:     in.Comment,
+		})
+	}
+	return total, scores, errcode.Success
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// 学生查看的作品展同意情况
+type ShowcaseConsentState struct {
+	SubmissionID int64 `json:"submission_id"`
+	IsExcellent  bool  `json:"is_excellent"` // 成绩发布前始终为false
+	Consented    bool  `json:"consented"`    // 自己是否同意了展示
+	Anonymous    bool  `json:"anonymous"`    // 自己是否选了匿名展示
+	Showcased    bool  `json:"showcased"`    // 现在是否已经在作品展里（小组作业要所有组员都同意）
+}
+
+// 作品展里的一份作品（只包含可以公开的字段，不含分数、评语和学生账号信息）
+type ExcellentWork struct {
+	SubmissionID    int64     `json:"submission_id"`
+	HomeworkID      int64     `json:"homework_id"`
+	HomeworkTitle   string    `json:"homework_title"`
+	Department      string    `json:"department"`
+	DepartmentLabel string    `json:"department_label"`
+	Content         string    `json:"content"`
+	FileURL         string    `json:"file_url,omitempty"`
+	FileIDs         []int64   `json:"file_ids"` // 当前版本的附件，可以通过文件接口下载
+	Reason          string    `json:"reason,omitempty"`
+	Anonymous       bool      `json:"anonymous"`
+	Authors         []string  `json:"authors,omitempty"`   // 作者昵称，匿名时为空
+	TeamName        string    `json:"team_name,omitempty"` // 小组作业的组名，匿名时为空
+	SubmittedAt     time.Time `json:"submitted_at"`
+}
+
+// GetShowcaseConsent 学生查询自己（或小组）提交的作品展同意情况
+func GetShowcaseConsent(studentID, subID int64) (*ShowcaseConsentState, errcode.ErrCode) {
+	sub, homework, errCode := getOwnSubmission(studentID, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	return showcaseConsentState(studentID, homework, sub)
+}
+
+// SetShowcaseConsent 学生同意或撤回把提交放进作品展，可以选匿名展示；还没被标为优秀时也可以先同意
+func SetShowcaseConsent(studentID, subID int64, consent, anonymous bool) (*ShowcaseConsentState, errcode.ErrCode) {
+	sub, homework, errCode := getOwnSubmission(studentID, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	if consent {
+		err := dao.SaveShowcaseConsent(&models.ShowcaseConsent{
+			SubmissionID: sub.ID,
+			StudentID:    studentID,
+			Anonymous:    anonymous,
+		})
+		if err != nil {
+			return nil, errcode.DBError
+		}
+	} else if err := dao.DeleteShowcaseConsent(sub.ID, studentID); err != nil {
+		return nil, errcode.DBError
+	}
+	return showcaseConsentState(studentID, homework, sub)
+}
+
+// showcaseConsentState 汇总学生自己的同意和提交是否已经展示
+func showcaseConsentState(studentID int64, homework *models.Homework, sub *models.Submission) (*ShowcaseConsentState, errcode.ErrCode) {
+	consent, err := dao.GetShowcaseConsent(sub.ID, studentID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	showcased, err := dao.IsShowcased(sub.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	hideUnreleasedGrade(homework, sub, time.Now())
+	state := &ShowcaseConsentState{
+		SubmissionID: sub.ID,
+		IsExcellent:  sub.IsExcellent,
+		Consented:    consent != nil,
+		Showcased:    showcased,
+	}
+	if consent != nil {
+		state.Anonymous = consent.Anonymous
+	}
+	return state, errcode.Success
+}
+
+// ListShowcase 查询作品展：只有标了优秀、成绩已发布、学生（小组作业时所有组员）同意展示的提交，
+// 可按部门和作业筛选，任一作者选了匿名时不显示作者
+func ListShowcase(department string, homeworkID int64, page, pageSize int) ([]ExcellentWork, int64, errcode.ErrCode) {
+	// 1. 分页查询
+	subs, total, err := dao.ListShowcase(department, homeworkID, page, pageSize)
+	if err != nil {
+		return nil, 0, errcode.DBError
+	}
+
+	// 2. 查询作者的同意（看是否匿名）
+	subIDs := make([]int64, 0, len(subs))
+	for _, s := range subs {
+		subIDs = append(subIDs, s.ID)
+	}
+	consents, err := dao.ListShowcaseConsentsBySubmissionIDs(subIDs)
+	if err != nil {
+		return nil, 0, errcode.DBError
+	}
+	anonymous := make(map[int64]bool, len(subs))
+	for _, c := range consents {
+		if c.Anonymous {
+			anonymous[c.SubmissionID] = true
+		}
+	}
+
+	// 3. 组装成对外展示的数据
+	list := make([]ExcellentWork, 0, len(subs))
+	for _, s := range subs {
+		work := ExcellentWork{
+			SubmissionID:    s.ID,
+			HomeworkID:      s.HomeworkID,
+			HomeworkTitle:   s.Homework.Title,
+			Department:      string(s.Homework.Department),
+			DepartmentLabel: s.Homework.DepartmentLabel(),
+			Content:         s.Content,
+			FileURL:         s.FileURL,
+			FileIDs:         s.FileIDs,
+			Reason:          s.ExcellentReason,
+			Anonymous:       anonymous[s.ID],
+			SubmittedAt:     s.SubmittedAt,
+		}
+		if work.FileIDs == nil {
+			work.FileIDs = []int64{}
+		}
+		if !work.Anonymous {
+			if s.Team != nil {
+				work.TeamName = s.Team.Name
+				for _, m := range s.Team.Members {
+					work.Authors = append(work.Authors, m.Student.Nickname)
+				}
+			} else {
+				work.Authors = []string{s.Student.Nickname}
+			}
+		}
+		list = append(list, work)
+	}
+	return list, total, errcode.Success
+}
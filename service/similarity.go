@@ -0,0 +1,264 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+	"github.com/chuji555/homework-system/pkg/similarity"
+	"github.com/spf13/viper"
+)
+
+// 查重参数：代码按归一化后的token，文本按单词/单字；k是k-gram长度，w是winnowing窗口
+const (
+	codeGramSize   = 12
+	codeWindowSize = 8
+	textGramSize   = 8
+	textWindowSize = 4
+)
+
+// 查重队列（放提交ID，同一份提交排队期间只算一次）
+var (
+	similarityQueue   chan int64
+	similarityPending sync.Map
+)
+
+// 一段带标记的原文（前端按顺序拼接，matched的高亮）
+type HighlightSegment struct {
+	Text    string `json:"text"`
+	Matched bool   `json:"matched"`
+}
+
+// 可疑提交对详情
+type SimilarityDetail struct {
+	models.SimilarityPair
+	HighlightA []HighlightSegment `json:"highlight_a"`
+	HighlightB []HighlightSegment `json:"highlight_b"`
+}
+
+// StartSimilarityWorker 启动查重worker（每次提交后在后台和同作业的其他提交比较）
+func StartSimilarityWorker() {
+	if !viper.GetBool("plagiarism.enabled") {
+		return
+	}
+	similarityQueue = make(chan int64, 1024)
+	go func() {
+		for subID := range similarityQueue {
+			similarityPending.Delete(subID)
+			if err := checkSimilarity(subID); err != nil {
+				log.Printf("提交%d查重失败：%v", subID, err)
+			}
+		}
+	}()
+}
+
+// scheduleSimilarityCheck 新提交或重新提交后排队查重（测验作业不查）
+func scheduleSimilarityCheck(homework *models.Homework, submissionID int64) {
+	if similarityQueue == nil || homework.Type != models.HomeworkNormal {
+		return
+	}
+	if _, queued := similarityPending.LoadOrStore(submissionID, true); queued {
+		return
+	}
+	select {
+	case similarityQueue <- submissionID:
+	default:
+		similarityPending.Delete(submissionID)
+		log.Printf("查重队列已满，提交%d需要手动重新查重", submissionID)
+	}
+}
+
+// similarityThreshold 相似度阈值
+func similarityThreshold() float64 {
+	threshold := viper.GetFloat64("plagiarism.threshold")
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.5
+	}
+	return threshold
+}
+
+// newSimilarityDocument 按代码或文本规则计算指纹
+func newSimilarityDocument(content string, code bool) *similarity.Document {
+	if code {
+		return similarity.NewDocument(content, true, codeGramSize, codeWindowSize)
+	}
+	return similarity.NewDocument(content, false, textGramSize, textWindowSize)
+}
+
+// checkSimilarity 把一份提交和同作业的其他提交逐一比较，保存超过阈值的
+func checkSimilarity(subID int64) error {
+	// 1. 查询提交（已撤回的不查）
+	sub, err := dao.GetSubmissionByID(subID)
+	if err != nil || sub == nil || sub.Withdrawn {
+		return err
+	}
+	others, err := dao.ListSubmissionByHomeworkIDs([]int64{sub.HomeworkID})
+	if err != nil {
+		return err
+	}
+
+	// 2. 只比较文字内容（附件不解析）；看起来是代码时两边都按代码归一化
+	code := similarity.LooksLikeCode(sub.Content)
+	doc := newSimilarityDocument(sub.Content, code)
+	threshold := similarityThreshold()
+	var pairs []models.SimilarityPair
+	for i := range others {
+		other := &others[i]
+		if other.ID == sub.ID {
+			continue
+		}
+		result := similarity.Compare(doc, newSimilarityDocument(other.Content, code))
+		if result.Score < threshold {
+			continue
+		}
+		// 3. 小ID在前，保证一对提交只有一条记录
+		pair := models.SimilarityPair{
+			HomeworkID:    sub.HomeworkID,
+			SubmissionAID: sub.ID,
+			SubmissionBID: other.ID,
+			Score:         result.Score,
+			Jaccard:       result.Jaccard,
+			MatchesA:      toMatchRegions(result.RegionsA),
+			MatchesB:      toMatchRegions(result.RegionsB),
+		}
+		if other.ID < sub.ID {
+			pair.SubmissionAID, pair.SubmissionBID = other.ID, sub.ID
+			pair.MatchesA, pair.MatchesB = pair.MatchesB, pair.MatchesA
+		}
+		pairs = append(pairs, pair)
+	}
+	return dao.SaveSimilarityPairs(sub.ID, pairs)
+}
+
+// toMatchRegions 转换成数据库存储的片段
+func toMatchRegions(regions []similarity.Region) models.MatchRegions {
+	list := make(models.MatchRegions, 0, len(regions))
+	for _, r := range regions {
+		list = append(list, models.MatchRegion{Start: r.Start, End: r.End})
+	}
+	return list
+}
+
+// RescanHomeworkSimilarity 管理员对整个作业重新查重（修改阈值后或历史作业），返回排队的提交数
+func RescanHomeworkSimilarity(homeworkID int64) (int, errcode.ErrCode) {
+	// 1. 检查作业
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return 0, errcode.DBError
+	}
+	if homework == nil {
+		return 0, errcode.DataNotFound
+	}
+	if homework.Type == models.HomeworkQuiz || similarityQueue == nil {
+		return 0, errcode.ParamError
+	}
+
+	// 2. 所有提交排队
+	subs, err := dao.ListSubmissionByHomeworkIDs([]int64{homeworkID})
+	if err != nil {
+		return 0, errcode.DBError
+	}
+	for _, sub := range subs {
+		scheduleSimilarityCheck(homework, sub.ID)
+	}
+	return len(subs), errcode.Success
+}
+
+// ListSimilarityPairs 查询作业的可疑提交对
+func ListSimilarityPairs(homeworkID int64, status string, minScore float64, page, pageSize int) ([]models.SimilarityPair, int64, errcode.ErrCode) {
+	switch models.SimilarityStatus(status) {
+	case "", models.SimilarityUnreviewed, models.SimilarityReviewed, models.SimilarityAcceptable:
+	default:
+		return nil, 0, errcode.ParamError
+	}
+	if page <= 0 || pageSize <= 0 || pageSize > 100 {
+		return nil, 0, errcode.ParamError
+	}
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, 0, errcode.DBError
+	}
+	list, total, err := dao.ListSimilarityPairs(homeworkID, status, minScore, page, pageSize)
+	if err != nil {
+		return nil, 0, errcode.DBError
+	}
+	// 列表只看分数，不返回两份提交的全文和片段；匿名批改时隐藏学生身份
+	for i := range list {
+		list[i].MatchesA, list[i].MatchesB = nil, nil
+		list[i].SubmissionA.Content, list[i].SubmissionB.Content = "", ""
+		anonymizeSubmission(homework, &list[i].SubmissionA)
+		anonymizeSubmission(homework, &list[i].SubmissionB)
+	}
+	return list, total, errcode.Success
+}
+
+// GetSimilarityPair 查询可疑提交对详情，按匹配片段切分两份提交的原文
+func GetSimilarityPair(pairID int64) (*SimilarityDetail, errcode.ErrCode) {
+	pair, err := dao.GetSimilarityPairByID(pairID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if pair == nil {
+		return nil, errcode.DataNotFound
+	}
+	homework, err := dao.GetHomeworkByID(pair.HomeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	anonymizeSubmission(homework, &pair.SubmissionA)
+	anonymizeSubmission(homework, &pair.SubmissionB)
+	return &SimilarityDetail{
+		SimilarityPair: *pair,
+		HighlightA:     highlight(pair.SubmissionA.Content, pair.MatchesA),
+		HighlightB:     highlight(pair.SubmissionB.Content, pair.MatchesB),
+	}, errcode.Success
+}
+
+// highlight 按片段把原文切成高亮/不高亮的段（片段有序且不重叠）
+func highlight(content string, regions models.MatchRegions) []HighlightSegment {
+	segments := make([]HighlightSegment, 0, 2*len(regions)+1)
+	pos := 0
+	for _, r := range regions {
+		// 查重之后内容可能变了，越界的片段忽略
+		if r.Start < pos || r.End > len(content) || r.Start >= r.End {
+			continue
+		}
+		if r.Start > pos {
+			segments = append(segments, HighlightSegment{Text: content[pos:r.Start]})
+		}
+		segments = append(segments, HighlightSegment{Text: content[r.Start:r.End], Matched: true})
+		pos = r.End
+	}
+	if pos < len(content) {
+		segments = append(segments, HighlightSegment{Text: content[pos:]})
+	}
+	return segments
+}
+
+// ReviewSimilarityPair 管理员标记可疑提交对为已核查或正常相似
+func ReviewSimilarityPair(pairID, reviewerID int64, status, note string) errcode.ErrCode {
+	// 1. 校验状态
+	switch models.SimilarityStatus(status) {
+	case models.SimilarityReviewed, models.SimilarityAcceptable:
+	default:
+		return errcode.ParamError
+	}
+
+	// 2. 检查是否存在
+	pair, err := dao.GetSimilarityPairByID(pairID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if pair == nil {
+		return errcode.DataNotFound
+	}
+
+	// 3. 保存
+	if err := dao.ReviewSimilarityPair(pairID, reviewerID, models.SimilarityStatus(status), note, time.Now()); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
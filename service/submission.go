@@ -1,6 +1,8 @@
 package service
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/chuji555/homework-system/dao"
@@ -9,35 +11,133 @@ import (
 )
 
 // 提交作业的业务逻辑
-func CreateSubmission(studentID, homeworkID int64, content, fileURL string) errcode.ErrCode {
+func CreateSubmission(studentID, homeworkID int64, content, fileURL string, fileIDs []int64) errcode.ErrCode {
+	return createSubmission(studentID, homeworkID, models.HomeworkNormal, content, fileURL, fileIDs)
+}
+
+// createSubmission 提交作业，kind为作业要求的类型（测验和仓库作业要走各自的接口）
+func createSubmission(studentID, homeworkID int64, kind models.HomeworkType, content, fileURL string, fileIDs []int64) errcode.ErrCode {
+	// 撤回过的提交再次提交时按重新提交处理（占用一次提交次数）
+	existing, errCode := existingSubmission(studentID, homeworkID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	if existing != nil && existing.Withdrawn {
+		return resubmitSubmission(studentID, existing.ID, kind, content, fileURL, fileIDs)
+	}
+
+	homework, submission, errCode := newSubmission(studentID, homeworkID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	if homework.Type != kind {
+		return errcode.ParamError
+	}
+
+	// 关联上传的文件
+	files, errCode := checkSubmissionFiles(studentID, homeworkID, 0, fileIDs)
+	if errCode != errcode.Success {
+		return errCode
+	}
+
+	submission.Content = content
+	submission.FileURL = fileURL
+	submission.FileIDs = files
+	if err := dao.CreateSubmission(submission); err != nil {
+		// 并发重复提交时由唯一索引兜底
+		if dao.IsDuplicateKey(err) {
+			return errcode.AlreadySubmitted
+		}
+		return errcode.DBError
+	}
+	// 作业配了测试用例的话排队自动评测
+	scheduleAutograde(homework, submission.ID)
+	// 后台和同作业的其他提交查重
+	scheduleSimilarityCheck(homework, submission.ID)
+
+	return errcode.Success
+}
+
+// existingSubmission 学生在作业上已有的提交：小组作业按所在小组查（可能是组员交的），否则按本人查，没有时返回nil
+func existingSubmission(studentID, homeworkID int64) (*models.Submission, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	var sub *models.Submission
+	if homework.IsGroup {
+		team, err := dao.GetTeamByStudentAndHomework(studentID, homeworkID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+		if team == nil {
+			return nil, errcode.Success
+		}
+		sub, err = dao.GetSubmissionByTeam(team.ID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+	} else {
+		sub, err = dao.GetSubmissionByStudentAndHomework(studentID, homeworkID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+	}
+	return sub, errcode.Success
+}
+
+// newSubmission 提交前的通用校验，返回作业和填好基础字段的提交记录
+func newSubmission(studentID, homeworkID int64) (*models.Homework, *models.Submission, errcode.ErrCode) {
 	// 1. 检查是否已提交
 	sub, err := dao.GetSubmissionByStudentAndHomework(studentID, homeworkID)
 	if err != nil {
-		return errcode.DBError
+		return nil, nil, errcode.DBError
 	}
 	if sub != nil {
-		return errcode.ParamError // 自定义"已提交过该作业"的错误码也可以
+		return nil, nil, errcode.AlreadySubmitted
 	}
 
-	// 2. 检查作业截止时间（简化版：实际应先查询作业信息）
-	// 这里先跳过作业查询，直接标记是否迟交（后续可以补充）
-	isLate := false // 实际逻辑：对比提交时间和作业deadline
+	// 2. 查询作业，未解锁的作业不能提交
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, nil, errcode.DataNotFound
+	}
+	if errCode := checkUnlocked(studentID, homework); errCode != errcode.Success {
+		return nil, nil, errCode
+	}
 
-	// 3. 创建提交记录
+	// 标记是否迟交（有个人延期时以延期后的时间为准）
+	deadline, err := effectiveDeadline(homework, studentID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	isLate := time.Now().After(deadline)
+
+	// 3. 小组作业：必须已组队且本组还没交过
+	var teamID *int64
+	if homework.IsGroup {
+		team, errCode := teamSubmissionCheck(homework, studentID)
+		if errCode != errcode.Success {
+			return nil, nil, errCode
+		}
+		teamID = &team.ID
+	}
+
+	// 4. 填充提交记录
 	submission := &models.Submission{
 		HomeworkID:  homeworkID,
 		StudentID:   studentID,
-		Content:     content,
-		FileURL:     fileURL,
+		TeamID:      teamID,
 		IsLate:      isLate,
 		SubmittedAt: time.Now(),
 	}
-
-	if err := dao.CreateSubmission(submission); err != nil {
-		return errcode.DBError
-	}
-
-	return errcode.Success
+	return homework, submission, errcode.Success
 }
 
 // 查询我的提交记录
@@ -46,21 +146,62 @@ func ListMySubmission(studentID int64, page, pageSize int) ([]models.Submission,
 	if err != nil {
 		return nil, 0, errcode.DBError
 	}
+	now := time.Now()
+	for i := range list {
+		// 成绩发布前看不到批改结果，小组作业按个人调整后的分数展示
+		studentGradeView(&list[i].Homework, &list[i], studentID, now)
+		list[i].FillScoreDisplay(list[i].Homework.GradingScale)
+	}
 	return list, total, errcode.Success
 }
 
 // 管理员查询作业的所有提交
-func ListSubmissionByHomework(homeworkID int64, page, pageSize int) ([]models.Submission, int64, errcode.ErrCode) {
-	list, total, err := dao.ListSubmissionByHomeworkID(homeworkID, page, pageSize)
+func ListSubmissionByHomework(homeworkID int64, filter dao.SubmissionFilter, page, pageSize int) ([]models.Submission, int64, errcode.ErrCode) {
+	// 1. 校验分页和筛选条件
+	if page <= 0 || pageSize <= 0 || pageSize > 100 {
+		return nil, 0, errcode.ParamError
+	}
+	switch filter.Sort {
+	case "", dao.SubmissionSortSubmittedAt, dao.SubmissionSortScore, dao.SubmissionSortReviewedAt, dao.SubmissionSortUnreviewedFirst:
+	default:
+		return nil, 0, errcode.ParamError
+	}
+	if filter.MinScore != nil && filter.MaxScore != nil && *filter.MinScore > *filter.MaxScore {
+		return nil, 0, errcode.ParamError
+	}
+	homework, err := dao.GetHomeworkByID(homeworkID)
 	if err != nil {
 		return nil, 0, errcode.DBError
 	}
+	if homework == nil {
+		return nil, 0, errcode.DataNotFound
+	}
+	// 匿名批改期间按学生姓名搜索等于查看身份
+	filter.Keyword = strings.TrimSpace(filter.Keyword)
+	if filter.Keyword != "" && blindActive(homework) {
+		return nil, 0, errcode.PermissionDenied
+	}
+
+	// 2. 查询
+	list, total, err := dao.ListSubmissionByHomeworkID(homeworkID, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, errcode.DBError
+	}
+	now := time.Now()
+	for i := range list {
+		list[i].FillScoreDisplay(homework.GradingScale)
+		if list[i].Team != nil {
+			showTeamAdjustments(homework, list[i].Team, 0, true, now)
+		}
+		// 匿名批改时隐藏学生身份
+		anonymizeSubmission(homework, &list[i])
+	}
 	return list, total, errcode.Success
 }
 
-// 批改作业
-func ReviewSubmission(subID int64, score int, comment string, reviewerID int64) errcode.ErrCode {
-	// 1. 查询提交记录
+// 批改作业（作业设置了评分标准时按分项打分，总分由服务端计算；否则按作业评分制校验分数/等级）
+func ReviewSubmission(subID int64, score *int, grade string, criterionScores []CriterionScoreInput, comment string, reviewerID int64, override bool, ifMatch int) errcode.ErrCode {
+	// 1. 查询提交记录和所属作业
 	sub, err := dao.GetSubmissionByID(subID)
 	if err != nil {
 		return errcode.DBError
@@ -68,23 +209,113 @@ func ReviewSubmission(subID int64, score int, comment string, reviewerID int64)
 	if sub == nil {
 		return errcode.DataNotFound
 	}
+	homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+
+	// 已撤回的提交不能批改
+	if sub.Withdrawn {
+		return errcode.ParamError
+	}
+	// If-Match的版本要和当前一致
+	if ifMatch != sub.Version {
+		return errcode.VersionConflict
+	}
+
+	// 别人认领了或已经由别人批改的，除非明确接手，否则不能改
+	if !override {
+		if errCode := checkReviewClaim(sub, reviewerID); errCode != errcode.Success {
+			return errCode
+		}
+	}
+	// 成绩发布后已有的成绩锁定
+	if errCode := checkGradeLocked(homework, sub); errCode != errcode.Success {
+		return errCode
+	}
 
-	// 2. 更新批改信息
+	// 2. 按作业的评分标准或评分制算出分数
+	rubric, err := dao.GetRubricByHomeworkID(sub.HomeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	score, scores, errCode := computeScore(homework, rubric, score, grade, criterionScores)
+	if errCode != errcode.Success {
+		return errCode
+	}
+
+	// 3. 更新批改信息，分数有变动时记录
 	now := time.Now()
-	sub.Score = &score
+	change := scoreChange(sub.Score, *score, models.ScoreSourceReview, reviewerID, comment)
+	sub.Score = score
 	sub.Comment = comment
 	sub.ReviewerID = &reviewerID
 	sub.ReviewedAt = &now
 
-	if err := dao.UpdateSubmission(sub); err != nil {
+	if rubric != nil {
+		err = dao.ReviewSubmissionWithCriteria(sub, scores, change)
+	} else {
+		err = dao.ReviewSubmission(sub, change)
+	}
+	if err != nil {
+		if errors.Is(err, dao.ErrVersionConflict) {
+			return errcode.VersionConflict
+		}
 		return errcode.DBError
 	}
 
 	return errcode.Success
 }
 
-// 标记优秀作业
-func MarkExcellent(subID int64, isExcellent bool) errcode.ErrCode {
+// computeScore 有评分标准时按分项得分求和，否则直接打总分（数值）或给等级（等级制/通过制）
+func computeScore(homework *models.Homework, rubric *models.Rubric, score *int, grade string, criterionScores []CriterionScoreInput) (*int, []models.SubmissionCriterionScore, errcode.ErrCode) {
+	if rubric != nil {
+		total, list, errCode := scoreByRubric(rubric, criterionScores)
+		if errCode != errcode.Success {
+			return nil, nil, errCode
+		}
+		return &total, list, errcode.Success
+	}
+	if len(criterionScores) > 0 {
+		return nil, nil, errcode.ParamError
+	}
+	if grade != "" {
+		value, ok := homework.GradingScale.ParseGrade(grade)
+		if !ok {
+			return nil, nil, errcode.ParamError
+		}
+		score = &value
+	}
+	if score == nil || !homework.GradingScale.ValidScore(*score) {
+		return nil, nil, errcode.ParamError
+	}
+	return score, nil, errcode.Success
+}
+
+// scoreChange 分数有变动（或第一次打分）时生成变动记录，没变返回nil
+func scoreChange(oldScore *int, newScore int, source models.ScoreChangeSource, changedBy int64, reason string) *models.ScoreChange {
+	if oldScore != nil && *oldScore == newScore {
+		return nil
+	}
+	var old *int
+	if oldScore != nil {
+		value := *oldScore
+		old = &value
+	}
+	return &models.ScoreChange{
+		OldScore:  old,
+		NewScore:  newScore,
+		Source:    source,
+		ChangedBy: &changedBy,
+		Reason:    reason,
+	}
+}
+
+// 标记优秀作业（取消标记时推荐理由一起清空）
+func MarkExcellent(subID int64, isExcellent bool, reason string, ifMatch int) errcode.ErrCode {
 	sub, err := dao.GetSubmissionByID(subID)
 	if err != nil {
 		return errcode.DBError
@@ -92,20 +323,268 @@ func MarkExcellent(subID int64, isExcellent bool) errcode.ErrCode {
 	if sub == nil {
 		return errcode.DataNotFound
 	}
+	if ifMatch != sub.Version {
+		return errcode.VersionConflict
+	}
 
-	sub.IsExcellent = isExcellent
-	if err := dao.UpdateSubmission(sub); err != nil {
+	reason = strings.TrimSpace(reason)
+	if !isExcellent {
+		reason = ""
+	}
+	if err := dao.MarkSubmissionExcellent(sub, isExcellent, reason); err != nil {
+		if errors.Is(err, dao.ErrVersionConflict) {
+			return errcode.VersionConflict
+		}
 		return errcode.DBError
 	}
 
 	return errcode.Success
 }
 
-// 查询优秀作业
-func ListExcellentSubmission(page, pageSize int) ([]models.Submission, int64, errcode.ErrCode) {
-	list, total, err := dao.ListExcellentSubmission(page, pageSize)
+// ResubmitSubmission 重新提交（保留所有历史版本，受最多提交次数和截止时间限制）
+func ResubmitSubmission(studentID, subID int64, content, fileURL string, fileIDs []int64) errcode.ErrCode {
+	return resubmitSubmission(studentID, subID, models.HomeworkNormal, content, fileURL, fileIDs)
+}
+
+// resubmitSubmission 重新提交，kind为作业要求的类型
+func resubmitSubmission(studentID, subID int64, kind models.HomeworkType, content, fileURL string, fileIDs []int64) errcode.ErrCode {
+	// 1. 查询提交记录并校验是自己（或自己小组）的
+	sub, homework, errCode := getOwnSubmission(studentID, subID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	// 测验作业不能重新作答，仓库作业要重新拍快照，已批改的也不能再改
+	if homework.Type != kind || sub.Score != nil {
+		return errcode.ParamError
+	}
+
+	// 2. 检查提交次数（0表示不限）
+	if homework.MaxAttempts > 0 && sub.Attempts >= homework.MaxAttempts {
+		return errcode.AttemptsUsedUp
+	}
+
+	// 3. 截止后只有允许迟交的作业才能重新提交（并标记迟交）
+	deadline, err := effectiveDeadline(homework, studentID)
 	if err != nil {
-		return nil, 0, errcode.DBError
+		return errcode.DBError
 	}
-	return list, total, errcode.Success
+	now := time.Now()
+	isLate := now.After(deadline)
+	if isLate && !homework.AllowLate {
+		return errcode.DeadlinePassed
+	}
+
+	// 4. 关联上传的文件（可以沿用之前版本的文件）
+	files, errCode := checkSubmissionFiles(studentID, sub.HomeworkID, sub.ID, fileIDs)
+	if errCode != errcode.Success {
+		return errCode
+	}
+
+	// 5. 追加新版本
+	previous := *sub
+	sub.Content = content
+	sub.FileURL = fileURL
+	sub.FileIDs = files
+	sub.IsLate = isLate
+	sub.SubmittedAt = now
+	sub.Attempts++
+	updated, err := dao.ResubmitSubmission(&previous, sub, studentID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if !updated {
+		// 同时有别的重新提交或批改先完成了
+		return errcode.ParamError
+	}
+	// 新版本重新自动评测
+	scheduleAutograde(homework, sub.ID)
+	scheduleSimilarityCheck(homework, sub.ID)
+	return errcode.Success
+}
+
+// WithdrawSubmission 截止前撤回提交（撤回后可以重新提交）
+func WithdrawSubmission(studentID, subID int64) errcode.ErrCode {
+	// 1. 查询提交记录并校验是自己（或自己小组）的
+	sub, homework, errCode := getOwnSubmission(studentID, subID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	if sub.Withdrawn || sub.Score != nil {
+		return errcode.ParamError
+	}
+
+	// 2. 只能在截止前撤回
+	deadline, err := effectiveDeadline(homework, studentID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if time.Now().After(deadline) {
+		return errcode.DeadlinePassed
+	}
+
+	// 3. 标记撤回
+	withdrawn, err := dao.WithdrawSubmission(subID, time.Now())
+	if err != nil {
+		return errcode.DBError
+	}
+	if !withdrawn {
+		return errcode.ParamError
+	}
+	return errcode.Success
+}
+
+// getOwnSubmission 查询学生自己的提交（小组作业时组员都算本人）
+func getOwnSubmission(studentID, subID int64) (*models.Submission, *models.Homework, errcode.ErrCode) {
+	sub, err := dao.GetSubmissionByID(subID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if sub == nil {
+		return nil, nil, errcode.DataNotFound
+	}
+	if sub.StudentID != studentID {
+		if sub.TeamID == nil {
+			return nil, nil, errcode.PermissionDenied
+		}
+		team, err := dao.GetTeamByStudentAndHomework(studentID, sub.HomeworkID)
+		if err != nil {
+			return nil, nil, errcode.DBError
+		}
+		if team == nil || team.ID != *sub.TeamID {
+			return nil, nil, errcode.PermissionDenied
+		}
+	}
+	homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, nil, errcode.DataNotFound
+	}
+	return sub, homework, errcode.Success
+}
+
+// getVisibleSubmission 管理员可以看所有提交，学生只能看自己（或小组）的
+func getVisibleSubmission(userID int64, isAdmin bool, subID int64) (*models.Submission, errcode.ErrCode) {
+	if !isAdmin {
+		sub, _, errCode := getOwnSubmission(userID, subID)
+		return sub, errCode
+	}
+	sub, err := dao.GetSubmissionByID(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if sub == nil {
+		return nil, errcode.DataNotFound
+	}
+	return sub, errcode.Success
+}
+
+// GetSubmission 查询单个提交（管理员或提交者本人/组员，匿名批改时对管理员隐藏学生身份，成绩发布前对学生隐藏批改结果）
+func GetSubmission(userID int64, isAdmin bool, subID int64) (*models.Submission, errcode.ErrCode) {
+	sub, errCode := getVisibleSubmission(userID, isAdmin, subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	if isAdmin {
+		anonymizeSubmission(homework, sub)
+	} else {
+		// 小组作业要按组员的个人调整换算分数
+		if sub.TeamID != nil {
+			team, err := dao.GetTeamByID(*sub.TeamID)
+			if err != nil {
+				return nil, errcode.DBError
+			}
+			sub.Team = team
+		}
+		studentGradeView(homework, sub, userID, time.Now())
+	}
+	sub.FillScoreDisplay(homework.GradingScale)
+	return sub, errcode.Success
+}
+
+// 两个版本的对比结果
+type SubmissionVersionDiff struct {
+	From        int        `json:"from"`
+	To          int        `json:"to"`
+	ContentDiff []DiffLine `json:"content_diff"` // 提交内容的逐行对比
+	OldFileURL  string     `json:"old_file_url"`
+	NewFileURL  string     `json:"new_file_url"`
+}
+
+// ListSubmissionVersions 查询提交的历史版本（按版本号升序，最后一个是当前版本）
+func ListSubmissionVersions(subID int64) ([]models.SubmissionVersion, errcode.ErrCode) {
+	sub, err := dao.GetSubmissionByID(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if sub == nil {
+		return nil, errcode.DataNotFound
+	}
+	list, err := dao.ListSubmissionVersions(subID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	// 旧数据（和测验提交）没有版本记录，用当前内容作为版本1
+	if len(list) == 0 {
+		list = append(list, models.SubmissionVersion{
+			SubmissionID: sub.ID,
+			Version:      1,
+			SubmitterID:  sub.StudentID,
+			Content:      sub.Content,
+			FileURL:      sub.FileURL,
+			IsLate:       sub.IsLate,
+			SubmittedAt:  sub.SubmittedAt,
+		})
+	}
+	// 匿名批改时不显示每个版本是谁提交的
+	homework, err := dao.GetHomeworkByID(sub.HomeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if blindActive(homework) {
+		for i := range list {
+			list[i].SubmitterID = 0
+		}
+	}
+	return list, errcode.Success
+}
+
+// DiffSubmissionVersions 对比提交的两个版本
+func DiffSubmissionVersions(subID int64, from, to int) (*SubmissionVersionDiff, errcode.ErrCode) {
+	list, errCode := ListSubmissionVersions(subID)
+	if errCode != errcode.Success {
+		return nil, errCode
+	}
+	var fromVer, toVer *models.SubmissionVersion
+	for i := range list {
+		if list[i].Version == from {
+			fromVer = &list[i]
+		}
+		if list[i].Version == to {
+			toVer = &list[i]
+		}
+	}
+	if fromVer == nil || toVer == nil {
+		return nil, errcode.DataNotFound
+	}
+	// 内容太长时不做逐行对比
+	lines, ok := diffLines(fromVer.Content, toVer.Content)
+	if !ok {
+		return nil, errcode.ParamError
+	}
+	return &SubmissionVersionDiff{
+		From:        from,
+		To:          to,
+		ContentDiff: lines,
+		OldFileURL:  fromVer.FileURL,
+		NewFileURL:  toVer.FileURL,
+	}, errcode.Success
 }
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// UpdateGroupSettings 设置作业是否为小组作业及组队规则，ifMatch要和作业当前的版本一致
+func UpdateGroupSettings(homeworkID int64, isGroup bool, minTeamSize, maxTeamSize int, allowStudentTeams bool, ifMatch int) errcode.ErrCode {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	if ifMatch != homework.Version {
+		return errcode.VersionConflict
+	}
+
+	// 1. 校验人数限制
+	if isGroup && (minTeamSize < 1 || maxTeamSize < 2 || minTeamSize > maxTeamSize) {
+		return errcode.ParamError
+	}
+	if !isGroup {
+		minTeamSize, maxTeamSize, allowStudentTeams = 0, 0, false
+	}
+
+	// 2. 已经有人提交/组队后不能切换个人/小组模式
+	if isGroup != homework.IsGroup {
+		submitted, err := dao.CountSubmissionByHomework(homeworkID)
+		if err != nil {
+			return errcode.DBError
+		}
+		grouped, err := dao.CountTeamMembersByHomework(homeworkID)
+		if err != nil {
+			return errcode.DBError
+		}
+		if submitted > 0 || grouped > 0 {
+			return errcode.ParamError
+		}
+	}
+
+	return homeworkUpdateResult(dao.UpdateHomeworkGroupSettings(homeworkID, ifMatch, isGroup, minTeamSize, maxTeamSize, allowStudentTeams))
+}
+
+// CreateTeam 创建小组（管理员指定组员；学生自行组队时创建者即组长，其他人再加入）
+func CreateTeam(homeworkID int64, name string, creatorID int64, isAdmin bool, memberIDs []int64) (*models.Team, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	if !homework.IsGroup {
+		return nil, errcode.ParamError
+	}
+	if !isAdmin {
+		if !homework.AllowStudentTeams {
+			return nil, errcode.PermissionDenied
+		}
+		memberIDs = []int64{creatorID}
+	}
+	if len(memberIDs) == 0 || len(memberIDs) > homework.MaxTeamSize {
+		return nil, errcode.ParamError
+	}
+	if errCode := checkTeamStudents(homework, memberIDs, 0); errCode != errcode.Success {
+		return nil, errCode
+	}
+
+	team := &models.Team{
+		HomeworkID: homeworkID,
+		Name:       name,
+		LeaderID:   memberIDs[0], // 第一个组员为组长
+	}
+	for _, id := range memberIDs {
+		team.Members = append(team.Members, models.TeamMember{HomeworkID: homeworkID, StudentID: id})
+	}
+	if err := dao.CreateTeam(team); err != nil {
+		return nil, errcode.DBError
+	}
+	return team, errcode.Success
+}
+
+// ReplaceTeamMembers 管理员调整小组成员（整体替换）
+func ReplaceTeamMembers(teamID int64, memberIDs []int64) errcode.ErrCode {
+	team, homework, errCode := getEditableTeam(teamID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	if len(memberIDs) == 0 || len(memberIDs) > homework.MaxTeamSize {
+		return errcode.ParamError
+	}
+	if errCode := checkTeamStudents(homework, memberIDs, teamID); errCode != errcode.Success {
+		return errCode
+	}
+
+	if err := dao.ReplaceTeamMembers(team, memberIDs); err != nil {
+		return errcode.DBError
+	}
+	// 组长被移出时由第一个组员接任
+	for _, id := range memberIDs {
+		if id == team.LeaderID {
+			return errcode.Success
+		}
+	}
+	if err := dao.UpdateTeamLeader(teamID, memberIDs[0]); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// JoinTeam 学生加入小组
+func JoinTeam(teamID, studentID int64) errcode.ErrCode {
+	team, homework, errCode := getEditableTeam(teamID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	if !homework.AllowStudentTeams {
+		return errcode.PermissionDenied
+	}
+	if len(team.Members) >= homework.MaxTeamSize {
+		return errcode.ParamError
+	}
+	if errCode := checkTeamStudents(homework, []int64{studentID}, 0); errCode != errcode.Success {
+		return errCode
+	}
+
+	member := &models.TeamMember{TeamID: teamID, HomeworkID: team.HomeworkID, StudentID: studentID}
+	if err := dao.AddTeamMember(member); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// LeaveTeam 学生退出小组（最后一人退出时解散小组）
+func LeaveTeam(teamID, studentID int64) errcode.ErrCode {
+	team, homework, errCode := getEditableTeam(teamID)
+	if errCode != errcode.Success {
+		return errCode
+	}
+	if !homework.AllowStudentTeams {
+		return errcode.PermissionDenied
+	}
+
+	var next int64
+	found := false
+	for _, m := range team.Members {
+		if m.StudentID == studentID {
+			found = true
+		} else if next == 0 {
+			next = m.StudentID
+		}
+	}
+	if !found {
+		return errcode.DataNotFound
+	}
+	if next == 0 {
+		if err := dao.DeleteTeam(teamID); err != nil {
+			return errcode.DBError
+		}
+		return errcode.Success
+	}
+
+	if err := dao.DeleteTeamMember(teamID, studentID); err != nil {
+		return errcode.DBError
+	}
+	if team.LeaderID == studentID {
+		if err := dao.UpdateTeamLeader(teamID, next); err != nil {
+			return errcode.DBError
+		}
+	}
+	return errcode.Success
+}
+
+// DeleteTeam 管理员解散小组
+func DeleteTeam(teamID int64) errcode.ErrCode {
+	if _, _, errCode := getEditableTeam(teamID); errCode != errcode.Success {
+		return errCode
+	}
+	if err := dao.DeleteTeam(teamID); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// SetMemberAdjustment 管理员在小组已批改后设置组员的个人分数调整，调整后的个人得分要在评分制范围内；
+// ifMatch要和小组提交当前的版本一致，返回小组提交的ID（版本冲突时据此返回最新数据）
+func SetMemberAdjustment(teamID, studentID int64, adjustment int, operatorID int64, ifMatch int) (int64, errcode.ErrCode) {
+	// 1. 查询小组、组员和小组的提交
+	team, err := dao.GetTeamByID(teamID)
+	if err != nil {
+		return 0, errcode.DBError
+	}
+	if team == nil {
+		return 0, errcode.DataNotFound
+	}
+	var member *models.TeamMember
+	for i := range team.Members {
+		if team.Members[i].StudentID == studentID {
+			member = &team.Members[i]
+		}
+	}
+	if member == nil {
+		return 0, errcode.DataNotFound
+	}
+	homework, err := dao.GetHomeworkByID(team.HomeworkID)
+	if err != nil {
+		return 0, errcode.DBError
+	}
+	if homework == nil {
+		return 0, errcode.DataNotFound
+	}
+	sub, err := dao.GetSubmissionByTeam(teamID)
+	if err != nil {
+		return 0, errcode.DBError
+	}
+	// 小组得分出来后才能调整个人得分
+	if sub == nil || sub.Score == nil {
+		return 0, errcode.ParamError
+	}
+	if ifMatch != sub.Version {
+		return sub.ID, errcode.VersionConflict
+	}
+
+	// 2. 成绩发布后锁定，调整后的个人得分不能超出评分制范围
+	if errCode := checkGradeLocked(homework, sub); errCode != errcode.Success {
+		return sub.ID, errCode
+	}
+	scale := homework.GradingScale
+	if !scale.ValidScore(*sub.Score + adjustment) {
+		return sub.ID, errcode.ParamError
+	}
+
+	// 3. 保存调整，个人得分有变动时记入分数变动记录
+	oldScore := teamMemberScore(*sub.Score, member.ScoreAdjustment, scale)
+	change := scoreChange(&oldScore, *sub.Score+adjustment, models.ScoreSourceAdjustment, operatorID, "")
+	if change != nil {
+		change.StudentID = &studentID
+	}
+	err = dao.UpdateTeamMemberAdjustment(sub, studentID, adjustment, change)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		return sub.ID, errcode.VersionConflict
+	}
+	if err != nil {
+		return sub.ID, errcode.DBError
+	}
+	return sub.ID, errcode.Success
+}
+
+// ListTeam 管理员查询作业下的全部小组（含组员的个人分数调整）
+func ListTeam(homeworkID int64) ([]models.Team, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	list, err := dao.ListTeamByHomework(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	now := time.Now()
+	for i := range list {
+		showTeamAdjustments(homework, &list[i], 0, true, now)
+	}
+	return list, errcode.Success
+}
+
+// GetMyTeam 查询学生在某个作业下的小组（成绩发布后能看到自己的个人分数调整）
+func GetMyTeam(studentID, homeworkID int64) (*models.Team, errcode.ErrCode) {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, errcode.DataNotFound
+	}
+	team, err := dao.GetTeamByStudentAndHomework(studentID, homeworkID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if team == nil {
+		return nil, errcode.DataNotFound
+	}
+	showTeamAdjustments(homework, team, studentID, false, time.Now())
+	return team, errcode.Success
+}
+
+// teamSubmissionCheck 小组作业提交前的校验，返回提交所属的小组
+func teamSubmissionCheck(homework *models.Homework, studentID int64) (*models.Team, errcode.ErrCode) {
+	team, err := dao.GetTeamByStudentAndHomework(studentID, homework.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	// 没组队或者人数不够都不能交
+	if team == nil || len(team.Members) < homework.MinTeamSize {
+		return nil, errcode.ParamError
+	}
+	sub, err := dao.GetSubmissionByTeam(team.ID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if sub != nil {
+		return nil, errcode.AlreadySubmitted
+	}
+	return team, errcode.Success
+}
+
+// teamMemberScore 小组得分加上组员的个人调整（限制在评分制范围内）
+func teamMemberScore(score, adjustment int, scale models.GradingScale) int {
+	score += adjustment
+	if score < 0 {
+		score = 0
+	}
+	if score > scale.MaxScore() {
+		score = scale.MaxScore()
+	}
+	return score
+}
+
+// applyTeamAdjustment 把小组提交的分数换成某个组员的个人得分（需要关联Team.Members）；
+// 学生查看提交、学习进度和前置作业判断都经过这里，同一个学生在各处看到的成绩一致
+func applyTeamAdjustment(sub *models.Submission, studentID int64, scale models.GradingScale) {
+	if sub.Team == nil || sub.Score == nil {
+		return
+	}
+	for _, m := range sub.Team.Members {
+		if m.StudentID == studentID {
+			score := teamMemberScore(*sub.Score, m.ScoreAdjustment, scale)
+			sub.Score = &score
+			return
+		}
+	}
+}
+
+// studentGradeView 学生看到的成绩：成绩发布前隐藏批改结果，小组作业换成本人的个人得分，组员的调整只展示本人的
+func studentGradeView(homework *models.Homework, sub *models.Submission, studentID int64, now time.Time) {
+	hideUnreleasedGrade(homework, sub, now)
+	applyTeamAdjustment(sub, studentID, homework.GradingScale)
+	if sub.Team != nil {
+		showTeamAdjustments(homework, sub.Team, studentID, false, now)
+	}
+}
+
+// showTeamAdjustments 填充组员个人分数调整的展示：管理员看全部，学生只能在成绩发布后看自己的
+func showTeamAdjustments(homework *models.Homework, team *models.Team, viewerID int64, isAdmin bool, now time.Time) {
+	visible := isAdmin || homework.GradesVisible(now)
+	for i := range team.Members {
+		m := &team.Members[i]
+		m.Adjustment = nil
+		if visible && (isAdmin || m.StudentID == viewerID) {
+			adjustment := m.ScoreAdjustment
+			m.Adjustment = &adjustment
+		}
+	}
+}
+
+// getEditableTeam 查询小组及其作业，小组已提交后不允许再调整成员
+func getEditableTeam(teamID int64) (*models.Team, *models.Homework, errcode.ErrCode) {
+	team, err := dao.GetTeamByID(teamID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if team == nil {
+		return nil, nil, errcode.DataNotFound
+	}
+	homework, err := dao.GetHomeworkByID(team.HomeworkID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if homework == nil {
+		return nil, nil, errcode.DataNotFound
+	}
+	sub, err := dao.GetSubmissionByTeam(teamID)
+	if err != nil {
+		return nil, nil, errcode.DBError
+	}
+	if sub != nil {
+		return nil, nil, errcode.ParamError
+	}
+	return team, homework, errcode.Success
+}
+
+// checkTeamStudents 校验组员：不重复、是学生、没有加入其他小组（小组可以跨部门）
+func checkTeamStudents(homework *models.Homework, studentIDs []int64, teamID int64) errcode.ErrCode {
+	seen := make(map[int64]bool, len(studentIDs))
+	for _, id := range studentIDs {
+		if seen[id] {
+			return errcode.ParamError
+		}
+		seen[id] = true
+
+		student, err := dao.GetUserByID(id)
+		if err != nil {
+			return errcode.DBError
+		}
+		if student == nil || student.Role != models.Student {
+			return errcode.ParamError
+		}
+		team, err := dao.GetTeamByStudentAndHomework(id, homework.ID)
+		if err != nil {
+			return errcode.DBError
+		}
+		if team != nil && team.ID != teamID {
+			return errcode.ParamError
+		}
+	}
+	return errcode.Success
+}
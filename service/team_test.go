@@ -0,0 +1,94 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chuji555/homework-system/models"
+)
+
+func teamSubmission(score int) *models.Submission {
+	return &models.Submission{
+		Score: &score,
+		Team: &models.Team{Members: []models.TeamMember{
+			{StudentID: 1, ScoreAdjustment: -10},
+			{StudentID: 2, ScoreAdjustment: 0},
+			{StudentID: 3, ScoreAdjustment: 15},
+		}},
+	}
+}
+
+func TestApplyTeamAdjustment(t *testing.T) {
+	tests := []struct {
+		name      string
+		score     int
+		scale     models.GradingScale
+		studentID int64
+		want      int
+	}{
+		{"减分", 80, models.ScalePercentage, 1, 70},
+		{"不调整", 80, models.ScalePercentage, 2, 80},
+		{"加分不超过满分", 90, models.ScalePercentage, 3, 100},
+		{"减分不低于0", 5, models.ScaleTenPoint, 1, 0},
+		{"不是组员不变", 80, models.ScalePercentage, 4, 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := teamSubmission(tt.score)
+			applyTeamAdjustment(sub, tt.studentID, tt.scale)
+			if *sub.Score != tt.want {
+				t.Errorf("Score = %d, want %d", *sub.Score, tt.want)
+			}
+		})
+	}
+
+	// 没批改或不是小组提交时不变
+	sub := &models.Submission{Team: teamSubmission(0).Team}
+	applyTeamAdjustment(sub, 1, models.ScalePercentage)
+	if sub.Score != nil {
+		t.Errorf("没批改的提交不应有分数：%d", *sub.Score)
+	}
+}
+
+func TestStudentGradeView(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	released := &models.Homework{GradingScale: models.ScalePercentage}
+	unreleased := &models.Homework{GradingScale: models.ScalePercentage, RequireGradeRelease: true, GradesReleasedAt: &future}
+
+	// 成绩发布后只看到自己的个人得分和调整
+	sub := teamSubmission(80)
+	studentGradeView(released, sub, 1, now)
+	if sub.Score == nil || *sub.Score != 70 {
+		t.Fatalf("Score = %v, want 70", sub.Score)
+	}
+	for _, m := range sub.Team.Members {
+		if m.StudentID == 1 && (m.Adjustment == nil || *m.Adjustment != -10) {
+			t.Errorf("本人的调整 = %v, want -10", m.Adjustment)
+		}
+		if m.StudentID != 1 && m.Adjustment != nil {
+			t.Errorf("看到了组员%d的调整", m.StudentID)
+		}
+	}
+
+	// 发布前分数和调整都看不到
+	sub = teamSubmission(80)
+	studentGradeView(unreleased, sub, 1, now)
+	if sub.Score != nil {
+		t.Errorf("发布前不应看到分数：%d", *sub.Score)
+	}
+	for _, m := range sub.Team.Members {
+		if m.Adjustment != nil {
+			t.Errorf("发布前看到了组员%d的调整", m.StudentID)
+		}
+	}
+
+	// 管理员随时看全部
+	team := teamSubmission(80).Team
+	showTeamAdjustments(unreleased, team, 0, true, now)
+	for _, m := range team.Members {
+		if m.Adjustment == nil || *m.Adjustment != m.ScoreAdjustment {
+			t.Errorf("管理员看到组员%d的调整 = %v", m.StudentID, m.Adjustment)
+		}
+	}
+}
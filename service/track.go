@@ -0,0 +1,401 @@
+package service
+
+import (
+	"time"
+
+	"github.com/chuji555/homework-system/dao"
+	"github.com/chuji555/homework-system/models"
+	"github.com/chuji555/homework-system/pkg/errcode"
+)
+
+// 学习进度中单个作业的状态
+const (
+	ProgressLocked    = "locked"    // 前置作业未完成
+	ProgressAvailable = "available" // 已解锁，未提交
+	ProgressSubmitted = "submitted" // 已提交，待批改
+	ProgressReviewed  = "reviewed"  // 已批改
+)
+
+// 学生在路线中某个作业上的进度
+type TrackItemProgress struct {
+	HomeworkID   int64    `json:"homework_id"`
+	Title        string   `json:"title"`
+	Status       string   `json:"status"`
+	ScorePercent *float64 `json:"score_percent,omitempty"`
+}
+
+// 学生在一条路线上的整体进度
+type StudentTrackProgress struct {
+	StudentID       int64               `json:"student_id"`
+	Nickname        string              `json:"nickname"`
+	CompletedCount  int                 `json:"completed_count"`  // 已批改的作业数
+	CurrentPosition int                 `json:"current_position"` // 当前卡在第几项（从0开始，全部完成时等于作业数）
+	Items           []TrackItemProgress `json:"items"`
+}
+
+// CreateTrack 创建学习路线
+func CreateTrack(name, desc, dept string, creatorID int64, homeworkIDs []int64) (*models.Track, errcode.ErrCode) {
+	if errCode := checkTrackHomeworks(dept, homeworkIDs); errCode != errcode.Success {
+		return nil, errCode
+	}
+	track := &models.Track{
+		Name:        name,
+		Description: desc,
+		Department:  models.Department(dept),
+		CreatorID:   creatorID,
+		Items:       buildTrackItems(homeworkIDs),
+	}
+	if err := dao.CreateTrack(track); err != nil {
+		return nil, errcode.DBError
+	}
+	return track, errcode.Success
+}
+
+// UpdateTrack 修改学习路线（作业列表整体替换）
+func UpdateTrack(trackID int64, name, desc string, homeworkIDs []int64) errcode.ErrCode {
+	track, err := dao.GetTrackByID(trackID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if track == nil {
+		return errcode.DataNotFound
+	}
+	if errCode := checkTrackHomeworks(string(track.Department), homeworkIDs); errCode != errcode.Success {
+		return errCode
+	}
+
+	if name != "" {
+		track.Name = name
+	}
+	if desc != "" {
+		track.Description = desc
+	}
+	track.Items = buildTrackItems(homeworkIDs)
+	if err := dao.UpdateTrack(track); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// DeleteTrack 删除学习路线
+func DeleteTrack(trackID int64) errcode.ErrCode {
+	track, err := dao.GetTrackByID(trackID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if track == nil {
+		return errcode.DataNotFound
+	}
+	if err := dao.DeleteTrack(trackID); err != nil {
+		return errcode.DBError
+	}
+	return errcode.Success
+}
+
+// ListTrack 查询学习路线列表
+func ListTrack(department string) ([]models.Track, errcode.ErrCode) {
+	list, err := dao.ListTrack(department)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	return list, errcode.Success
+}
+
+// GetTrack 查询学习路线详情
+func GetTrack(trackID int64) (*models.Track, errcode.ErrCode) {
+	track, err := dao.GetTrackByID(trackID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if track == nil {
+		return nil, errcode.DataNotFound
+	}
+	return track, errcode.Success
+}
+
+// SetPrerequisite 设置作业的前置作业（prerequisiteID为nil表示取消），ifMatch要和作业当前的版本一致
+func SetPrerequisite(homeworkID int64, prerequisiteID *int64, minPercent float64, ifMatch int) errcode.ErrCode {
+	homework, err := dao.GetHomeworkByID(homeworkID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if homework == nil {
+		return errcode.DataNotFound
+	}
+	if ifMatch != homework.Version {
+		return errcode.VersionConflict
+	}
+	if prerequisiteID == nil {
+		return homeworkUpdateResult(dao.UpdateHomeworkPrerequisite(homeworkID, ifMatch, nil, 0))
+	}
+
+	// 前置作业必须是同部门的其他作业
+	if *prerequisiteID == homeworkID || minPercent < 0 || minPercent > 100 {
+		return errcode.ParamError
+	}
+	prerequisite, err := dao.GetHomeworkByID(*prerequisiteID)
+	if err != nil {
+		return errcode.DBError
+	}
+	if prerequisite == nil {
+		return errcode.DataNotFound
+	}
+	if prerequisite.Department != homework.Department {
+		return errcode.ParamError
+	}
+
+	// 沿着前置链往上找，不能绕回自己（防止互相锁死）
+	for p := prerequisite; p.PrerequisiteID != nil; {
+		if *p.PrerequisiteID == homeworkID {
+			return errcode.ParamError
+		}
+		p, err = dao.GetHomeworkByID(*p.PrerequisiteID)
+		if err != nil {
+			return errcode.DBError
+		}
+		if p == nil {
+			break
+		}
+	}
+
+	return homeworkUpdateResult(dao.UpdateHomeworkPrerequisite(homeworkID, ifMatch, prerequisiteID, minPercent))
+}
+
+// GetTrackProgress 查询路线的学习进度（管理员看全部门学生，学生只看自己）
+func GetTrackProgress(trackID, viewerID int64, isAdmin bool) ([]StudentTrackProgress, errcode.ErrCode) {
+	// 1. 查询路线
+	track, err := dao.GetTrackByID(trackID)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	if track == nil {
+		return nil, errcode.DataNotFound
+	}
+
+	// 2. 确定要统计的学生
+	var students []models.User
+	if isAdmin {
+		students, err = dao.ListStudentsByDepartment(string(track.Department))
+		if err != nil {
+			return nil, errcode.DBError
+		}
+	} else {
+		student, err := dao.GetUserByID(viewerID)
+		if err != nil {
+			return nil, errcode.DBError
+		}
+		if student == nil {
+			return nil, errcode.DataNotFound
+		}
+		// 学生只能看本部门的路线
+		if student.Department != track.Department {
+			return nil, errcode.PermissionDenied
+		}
+		students = []models.User{*student}
+	}
+
+	// 3. 一次查出路线作业及其前置作业的全部提交
+	homeworkIDs := make([]int64, 0, len(track.Items))
+	for _, item := range track.Items {
+		homeworkIDs = append(homeworkIDs, item.HomeworkID)
+		if item.Homework.PrerequisiteID != nil {
+			homeworkIDs = append(homeworkIDs, *item.Homework.PrerequisiteID)
+		}
+	}
+	homeworks, err := dao.ListHomeworkByIDs(homeworkIDs)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	scales := make(map[int64]models.GradingScale, len(homeworks))
+	homeworkMap := make(map[int64]*models.Homework, len(homeworks))
+	for i, h := range homeworks {
+		scales[h.ID] = h.GradingScale
+		homeworkMap[h.ID] = &homeworks[i]
+	}
+	subs, err := dao.ListSubmissionByHomeworkIDs(homeworkIDs)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	// 学生看自己的进度时，成绩还没发布的按待批改算
+	if !isAdmin {
+		now := time.Now()
+		for i := range subs {
+			if h := homeworkMap[subs[i].HomeworkID]; h != nil {
+				hideUnreleasedGrade(h, &subs[i], now)
+			}
+		}
+	}
+	// 小组提交算到每个组员头上
+	teamIDs := make([]int64, 0)
+	for _, s := range subs {
+		if s.TeamID != nil {
+			teamIDs = append(teamIDs, *s.TeamID)
+		}
+	}
+	members, err := dao.ListTeamMembersByTeamIDs(teamIDs)
+	if err != nil {
+		return nil, errcode.DBError
+	}
+	teamMembers := make(map[int64][]models.TeamMember)
+	for _, m := range members {
+		teamMembers[m.TeamID] = append(teamMembers[m.TeamID], m)
+	}
+	// 学生ID -> 作业ID -> 提交（小组提交给每个组员复制一份，换成各自的个人得分）
+	subMap := make(map[int64]map[int64]*models.Submission)
+	addSub := func(studentID int64, s *models.Submission) {
+		if subMap[studentID] == nil {
+			subMap[studentID] = make(map[int64]*models.Submission)
+		}
+		subMap[studentID][s.HomeworkID] = s
+	}
+	for i := range subs {
+		s := &subs[i]
+		if s.TeamID == nil {
+			addSub(s.StudentID, s)
+			continue
+		}
+		s.Team = &models.Team{Members: teamMembers[*s.TeamID]}
+		for _, m := range s.Team.Members {
+			personal := *s
+			applyTeamAdjustment(&personal, m.StudentID, scales[s.HomeworkID])
+			addSub(m.StudentID, &personal)
+		}
+	}
+
+	// 4. 逐个学生计算进度
+	result := make([]StudentTrackProgress, 0, len(students))
+	for _, student := range students {
+		progress := StudentTrackProgress{
+			StudentID:       student.ID,
+			Nickname:        student.Nickname,
+			CurrentPosition: -1,
+			Items:           make([]TrackItemProgress, 0, len(track.Items)),
+		}
+		mySubs := subMap[student.ID]
+		for i, item := range track.Items {
+			ip := TrackItemProgress{HomeworkID: item.HomeworkID, Title: item.Homework.Title}
+			sub := mySubs[item.HomeworkID]
+			switch {
+			case sub != nil && sub.Score != nil:
+				ip.Status = ProgressReviewed
+				percent := scales[item.HomeworkID].Percentage(*sub.Score)
+				ip.ScorePercent = &percent
+				progress.CompletedCount++
+			case sub != nil:
+				ip.Status = ProgressSubmitted
+			case !prerequisitePassed(&item.Homework, mySubs[prerequisiteOf(&item.Homework)], scales):
+				ip.Status = ProgressLocked
+			default:
+				ip.Status = ProgressAvailable
+			}
+			if ip.Status != ProgressReviewed && progress.CurrentPosition < 0 {
+				progress.CurrentPosition = i
+			}
+			progress.Items = append(progress.Items, ip)
+		}
+		if progress.CurrentPosition < 0 {
+			progress.CurrentPosition = len(track.Items)
+		}
+		result = append(result, progress)
+	}
+	return result, errcode.Success
+}
+
+// lockedHomework 计算一批作业对学生是否处于锁定状态
+func lockedHomework(studentID int64, homeworks []models.Homework) (map[int64]bool, error) {
+	locked := make(map[int64]bool)
+	prerequisiteIDs := make([]int64, 0)
+	for _, h := range homeworks {
+		if h.PrerequisiteID != nil {
+			prerequisiteIDs = append(prerequisiteIDs, *h.PrerequisiteID)
+		}
+	}
+	if len(prerequisiteIDs) == 0 {
+		return locked, nil
+	}
+
+	subs, err := dao.ListSubmissionByStudentAndHomeworkIDs(studentID, prerequisiteIDs)
+	if err != nil {
+		return nil, err
+	}
+	subMap := make(map[int64]*models.Submission, len(subs))
+	scales := make(map[int64]models.GradingScale, len(subs))
+	now := time.Now()
+	for i := range subs {
+		// 成绩还没发布的不算完成，小组作业按个人得分判断
+		studentGradeView(&subs[i].Homework, &subs[i], studentID, now)
+		subMap[subs[i].HomeworkID] = &subs[i]
+		scales[subs[i].HomeworkID] = subs[i].Homework.GradingScale
+	}
+	for i := range homeworks {
+		h := &homeworks[i]
+		if h.PrerequisiteID != nil && !prerequisitePassed(h, subMap[*h.PrerequisiteID], scales) {
+			locked[h.ID] = true
+		}
+	}
+	return locked, nil
+}
+
+// checkUnlocked 校验作业对学生是否已解锁（提交作业前调用）
+func checkUnlocked(studentID int64, homework *models.Homework) errcode.ErrCode {
+	locked, err := lockedHomework(studentID, []models.Homework{*homework})
+	if err != nil {
+		return errcode.DBError
+	}
+	if locked[homework.ID] {
+		return errcode.HomeworkLocked
+	}
+	return errcode.Success
+}
+
+// prerequisitePassed 前置作业是否已完成（已批改且得分达标）
+func prerequisitePassed(homework *models.Homework, sub *models.Submission, scales map[int64]models.GradingScale) bool {
+	if homework.PrerequisiteID == nil {
+		return true
+	}
+	if sub == nil || sub.Score == nil {
+		return false
+	}
+	return scales[sub.HomeworkID].Percentage(*sub.Score) >= homework.PrerequisiteMinPercent
+}
+
+// prerequisiteOf 作业的前置作业ID（没有时返回0）
+func prerequisiteOf(homework *models.Homework) int64 {
+	if homework.PrerequisiteID == nil {
+		return 0
+	}
+	return *homework.PrerequisiteID
+}
+
+// checkTrackHomeworks 校验路线中的作业：不重复、都存在且属于路线所在部门
+func checkTrackHomeworks(dept string, homeworkIDs []int64) errcode.ErrCode {
+	seen := make(map[int64]bool, len(homeworkIDs))
+	for _, id := range homeworkIDs {
+		if seen[id] {
+			return errcode.ParamError
+		}
+		seen[id] = true
+	}
+	homeworks, err := dao.ListHomeworkByIDs(homeworkIDs)
+	if err != nil {
+		return errcode.DBError
+	}
+	if len(homeworks) != len(homeworkIDs) {
+		return errcode.DataNotFound
+	}
+	for _, h := range homeworks {
+		if string(h.Department) != dept {
+			return errcode.ParamError
+		}
+	}
+	return errcode.Success
+}
+
+// buildTrackItems 按顺序生成路线中的作业项
+func buildTrackItems(homeworkIDs []int64) []models.TrackItem {
+	items := make([]models.TrackItem, 0, len(homeworkIDs))
+	for i, id := range homeworkIDs {
+		items = append(items, models.TrackItem{HomeworkID: id, Position: i})
+	}
+	return items
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"time"
+
 	"github.com/chuji555/homework-system/dao"
 	"github.com/chuji555/homework-system/models"
 	"github.com/chuji555/homework-system/pkg/errcode"
@@ -59,6 +61,12 @@ func Login(username, password string) (accessToken, refreshToken string, user *m
 	if err != nil {
 		return "", "", nil, errcode.DBError
 	}
+	// 4. 记录登录时间（管理员查未交名单时参考）
+	now := time.Now()
+	if err := dao.UpdateUserLastLogin(user.ID, now); err != nil {
+		return "", "", nil, errcode.DBError
+	}
+	user.LastLoginAt = &now
 	return accessToken, refreshToken, user, errcode.Success
 }
 